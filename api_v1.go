@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// v2 successors of v1 routes keyed on method and route template
+var v1Successors = map[string]string{
+	"GET /v1/cages":                                      "/v2/cages",
+	"POST /v1/cage/{diet}/add":                           "/v2/cages",
+	"GET /v1/cage/{cageid:[0-9]+}":                       "/v2/cages/{cageid}",
+	"GET /v1/cage/{cageid}/list_dinosaurs":               "/v2/cages/{cageid}/dinosaurs",
+	"GET /v1/dino/list":                                  "/v2/dinosaurs",
+	"POST /v1/dino/add":                                  "/v2/dinosaurs",
+	"GET /v1/dino/{dinoid:[0-9]+}":                       "/v2/dinosaurs/{dinoid}",
+	"POST /v1/dino/{dinoid:[0-9]+}/move/{cageid:[0-9]+}": "/v2/dinosaurs/{dinoid}/cage",
+}
+
+// mark responses from v1 routes that have a v2 successor as deprecated
+// linking to the successor with the path variables filled in
+func DeprecateV1(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			tmpl, _ := route.GetPathTemplate()
+			if successor, ok := v1Successors[r.Method+" "+tmpl]; ok {
+				for k, v := range mux.Vars(r) {
+					successor = strings.ReplaceAll(successor, "{"+k+"}", v)
+				}
+				w.Header().Set("Deprecation", "true")
+				w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// register the v1 routes on a router already prefixed with /v1
+func RegisterV1(r *mux.Router, appHandlers *AppHandlers) {
+	r.HandleFunc("/healthcheck", appHandlers.healthcheck).Methods("GET")
+	r.HandleFunc("/cache/stats", appHandlers.CacheStats).Methods("GET")
+	r.HandleFunc("/openapi.json", appHandlers.OpenAPI).Methods("GET")
+	r.HandleFunc("/docs", appHandlers.Docs).Methods("GET")
+	r.HandleFunc("/events", appHandlers.Events).Methods("GET")
+	r.HandleFunc("/dino/add", appHandlers.AddDinosaur).Methods("POST")
+	r.HandleFunc("/dino/list", appHandlers.GetDinosaurs).Methods("GET")
+	r.HandleFunc("/dino/{dinoid:[0-9]+}", appHandlers.GetDinosaur).Methods("GET")
+	r.HandleFunc("/dino/{dinoid:[0-9]+}", appHandlers.PatchDinosaur).Methods("PATCH")
+	r.HandleFunc("/dino/{dinoid:[0-9]+}", appHandlers.DeleteDinosaur).Methods("DELETE")
+	r.HandleFunc("/dino/{dinoid:[0-9]+}/move/{cageid:[0-9]+}", appHandlers.MoveDinosaur).Methods("POST")
+	r.HandleFunc("/cages", appHandlers.GetCages).Methods("GET")
+	r.HandleFunc("/cage/{diet}/add", appHandlers.AddCage).Methods("POST")
+	r.HandleFunc("/cage/{cageid:[0-9]+}", appHandlers.GetCage).Methods("GET")
+	r.HandleFunc("/cage/{cageid:[0-9]+}", appHandlers.PatchCage).Methods("PATCH")
+	r.HandleFunc("/cage/{cageid:[0-9]+}", appHandlers.DeleteCage).Methods("DELETE")
+	r.HandleFunc("/cage/{cageid}/list_dinosaurs", appHandlers.GetCageDinosaurs).Methods("GET")
+	r.HandleFunc("/cage/{cageid}/status/{status}", appHandlers.SetCageStatus).Methods("POST")
+	r.HandleFunc("/cage/{cageid}/status_history", appHandlers.GetCageStatusHistory).Methods("GET")
+	r.HandleFunc("/cage/{cageid}/evacuate", appHandlers.EvacuateCage).Methods("POST")
+	r.HandleFunc("/cage/{cageid}/add_dino", appHandlers.AddDinoToCage).Methods("POST")
+	r.HandleFunc("/plans/rebalance", appHandlers.RebalanceCages).Methods("GET", "POST")
+	r.HandleFunc("/webhooks", appHandlers.AddWebhook).Methods("POST")
+	r.HandleFunc("/webhooks", appHandlers.ListWebhooks).Methods("GET")
+	r.HandleFunc("/webhooks/dead_letters", appHandlers.ListDeadLetters).Methods("GET")
+	r.HandleFunc("/webhooks/{webhookid:[0-9]+}", appHandlers.DeleteWebhook).Methods("DELETE")
+	r.HandleFunc("/webhooks/{webhookid:[0-9]+}/deliveries", appHandlers.ListDeliveries).Methods("GET")
+	r.HandleFunc("/import", appHandlers.Import).Methods("POST")
+	r.HandleFunc("/export", appHandlers.Export).Methods("GET")
+	r.HandleFunc("/restore", appHandlers.Restore).Methods("POST")
+	r.HandleFunc("/species/add", appHandlers.AddSpecies).Methods("POST")
+	r.HandleFunc("/species/list", appHandlers.ListSpecies).Methods("GET")
+	r.HandleFunc("/species/{name}", appHandlers.UpdateSpecies).Methods("PUT")
+	r.HandleFunc("/species/{name}", appHandlers.DeleteSpecies).Methods("DELETE")
+	r.Use(DeprecateV1)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dinocage/das"
+
+	"github.com/gorilla/mux"
+)
+
+// payload creating a cage
+// a zero capacity takes the default cage capacity
+type NewCageRequest struct {
+	Kind     string `json:"kind"`
+	Capacity int    `json:"capacity"`
+}
+
+// payload moving a dinosaur to a cage
+type CagePlacement struct {
+	Cage int `json:"cage"`
+}
+
+// register the v2 routes on a router already prefixed with /v2
+// resources are addressed by collection with the handlers shared with v1
+// wherever the request and response are the same
+func RegisterV2(r *mux.Router, appHandlers *AppHandlers) {
+	r.HandleFunc("/cages", appHandlers.GetCages).Methods("GET")
+	r.HandleFunc("/cages", appHandlers.CreateCage).Methods("POST")
+	r.HandleFunc("/cages/{cageid:[0-9]+}", appHandlers.GetCage).Methods("GET")
+	r.HandleFunc("/cages/{cageid:[0-9]+}/dinosaurs", appHandlers.GetCageDinosaurs).Methods("GET")
+	r.HandleFunc("/dinosaurs", appHandlers.GetDinosaurs).Methods("GET")
+	r.HandleFunc("/dinosaurs", appHandlers.CreateDinosaur).Methods("POST")
+	r.HandleFunc("/dinosaurs/{dinoid:[0-9]+}", appHandlers.GetDinosaur).Methods("GET")
+	r.HandleFunc("/dinosaurs/{dinoid:[0-9]+}/cage", appHandlers.PutDinosaurCage).Methods("PUT")
+}
+
+// create a cage from a json payload returning it with its location
+func (ah AppHandlers) CreateCage(w http.ResponseWriter, r *http.Request) {
+	var req NewCageRequest
+	defer r.Body.Close()
+	err := DecodeJSON(w, r, &req)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Capacity == 0 {
+		req.Capacity = das.CageCapacity
+	}
+	cage, err := ah.park.AddCage(r.Context(), req.Kind, req.Capacity)
+	if err != nil {
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	b, err := json.Marshal(cage)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/v2/cages/%d", cage.ID))
+	w.Header().Set("ETag", ETag(cage.Version))
+	WriteMsg(w, http.StatusCreated, string(b))
+}
+
+// add a dinosaur leaving its cage to the placement strategy
+// returning it with its location
+func (ah AppHandlers) CreateDinosaur(w http.ResponseWriter, r *http.Request) {
+	dino, ok := ah.addDinosaur(w, r)
+	if !ok {
+		return
+	}
+	b, err := json.Marshal(dino)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/v2/dinosaurs/%d", dino.ID))
+	w.Header().Set("ETag", ETag(dino.Version))
+	WriteMsg(w, http.StatusCreated, string(b))
+}
+
+// move a dinosaur to the cage in the payload requiring a matching etag
+func (ah AppHandlers) PutDinosaurCage(w http.ResponseWriter, r *http.Request) {
+	dinoID, err := strconv.Atoi(mux.Vars(r)["dinoid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "dinoid is not a valid value")
+		return
+	}
+	var placement CagePlacement
+	defer r.Body.Close()
+	err = DecodeJSON(w, r, &placement)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	ah.moveDinosaur(w, r, dinoID, placement.Cage)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dinocage/das"
+	"dinocage/mocks"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func serveRequest(r http.Handler, method, url, ifMatch, body string) *httptest.ResponseRecorder {
+	var rd io.Reader
+	if len(body) != 0 {
+		rd = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, url, rd)
+	if len(body) != 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(ifMatch) != 0 {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestV1AndV2Consistent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, das.Species{Name: "velociraptor", Diet: das.CarnivoreCode})
+	r, err := NewRouter(NewAppHandlers(mockDap, das.DefaultPlacement()))
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+
+	cage := das.Cage{ID: 3, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode, Version: 2}
+	dino := das.Dinosaur{ID: 1, Species: "velociraptor", Name: "blue", Diet: das.CarnivoreCode, Cage: 3, Version: 4}
+	moved := das.Dinosaur{ID: 1, Species: "velociraptor", Name: "blue", Diet: das.CarnivoreCode, Cage: 5, Version: 5}
+	mockDap.EXPECT().GetCages(gomock.Any()).Return([]das.Cage{cage}, nil).Times(2)
+	mockDap.EXPECT().GetCage(gomock.Any(), 3).Return(cage, nil).Times(2)
+	mockDap.EXPECT().GetDinosaursForCage(gomock.Any(), 3).Return([]das.Dinosaur{dino}, nil).Times(2)
+	mockDap.EXPECT().GetDinosaurs(gomock.Any(), "velociraptor").Return([]das.Dinosaur{dino}, nil).Times(2)
+	mockDap.EXPECT().GetDinosaur(gomock.Any(), 1).Return(dino, nil).Times(2)
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetDinosaur(gomock.Any(), 1).Return(dino, nil).Times(2)
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(cage, nil).Times(2)
+	tx.EXPECT().GetCage(gomock.Any(), 5).Return(das.Cage{ID: 5, Status: das.StatusActive, Capacity: 4, Kind: das.CarnivoreCode, Version: 1}, nil).Times(2)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(das.Cage{}, nil).Times(4)
+	tx.EXPECT().SaveDinosaur(gomock.Any(), gomock.Any()).Return(moved, nil).Times(2)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoPlaced, 5, gomock.Any()).Return(nil).Times(2)
+
+	tests := []struct {
+		v1Method, v1URL, v2Method, v2URL, v2Body, successor string
+	}{
+		{"GET", "/v1/cages", "GET", "/v2/cages", "", "/v2/cages"},
+		{"GET", "/v1/cage/3", "GET", "/v2/cages/3", "", "/v2/cages/3"},
+		{"GET", "/v1/cage/3/list_dinosaurs", "GET", "/v2/cages/3/dinosaurs", "", "/v2/cages/3/dinosaurs"},
+		{"GET", "/v1/dino/list?species=velociraptor", "GET", "/v2/dinosaurs?species=velociraptor", "", "/v2/dinosaurs"},
+		{"GET", "/v1/dino/1", "GET", "/v2/dinosaurs/1", "", "/v2/dinosaurs/1"},
+		{"POST", "/v1/dino/1/move/5", "PUT", "/v2/dinosaurs/1/cage", `{"cage":5}`, "/v2/dinosaurs/1/cage"},
+	}
+	for _, tc := range tests {
+		w1 := serveRequest(r, tc.v1Method, tc.v1URL, `"4"`, "")
+		w2 := serveRequest(r, tc.v2Method, tc.v2URL, `"4"`, tc.v2Body)
+		if w1.Code != http.StatusOK || w1.Code != w2.Code || w1.Body.String() != w2.Body.String() {
+			t.Errorf("%s and %s differ %d %s and %d %s", tc.v1URL, tc.v2URL, w1.Code, w1.Body.String(), w2.Code, w2.Body.String())
+		}
+		if w1.Header().Get("ETag") != w2.Header().Get("ETag") {
+			t.Errorf("%s and %s etags differ", tc.v1URL, tc.v2URL)
+		}
+		link := "<" + tc.successor + `>; rel="successor-version"`
+		if w1.Header().Get("Deprecation") != "true" || w1.Header().Get("Link") != link {
+			t.Errorf("%s expected deprecation linking %s got %v", tc.v1URL, tc.successor, w1.Header())
+		}
+		if len(w2.Header().Get("Deprecation")) != 0 {
+			t.Errorf("%s should not be deprecated", tc.v2URL)
+		}
+	}
+
+	// routes without a successor stay current
+	w := serveRequest(r, "GET", "/v1/healthcheck", "", "")
+	if w.Code != http.StatusOK || len(w.Header().Get("Deprecation")) != 0 {
+		t.Errorf("healthcheck should not be deprecated %d %v", w.Code, w.Header())
+	}
+}
+
+func TestV1SuccessorsAreRouted(t *testing.T) {
+	r, err := NewRouter(&AppHandlers{})
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+	routed := make(map[string]bool)
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		for _, m := range methods {
+			routed[m+" "+tmpl] = true
+			routed[muxVarPattern.ReplaceAllString(tmpl, "{$1}")] = true
+		}
+		return nil
+	})
+	for route, successor := range v1Successors {
+		if !routed[route] {
+			t.Errorf("deprecated route %s does not exist", route)
+		}
+		if !routed[successor] {
+			t.Errorf("successor %s of %s does not exist", successor, route)
+		}
+	}
+}
+
+func TestV2Create(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, das.Species{Name: "velociraptor", Diet: das.CarnivoreCode})
+	r, err := NewRouter(NewAppHandlers(mockDap, das.DefaultPlacement()))
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+
+	tx := mockWork(ctrl, mockDap)
+	created := das.Cage{ID: 7, Status: das.StatusActive, Kind: das.HerbivoreCode, Capacity: das.CageCapacity, Version: 1}
+	tx.EXPECT().InsertCage(gomock.Any(), gomock.Any()).Return(created, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageCreated, 7, created).Return(nil)
+	w := serveRequest(r, "POST", "/v2/cages", "", `{"kind":"H"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/v2/cages/7" || w.Header().Get("ETag") != `"1"` {
+		t.Errorf("create cage returned %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+
+	// worst fit picks the emptiest cage
+	cages := []das.Cage{
+		{ID: 3, Status: das.StatusActive, Capacity: 4, Count: 3, Kind: das.CarnivoreCode, Version: 2},
+		{ID: 4, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode, Version: 2},
+	}
+	blue := das.Dinosaur{Species: "velociraptor", Name: "blue", Diet: das.CarnivoreCode, Cage: 4}
+	tx.EXPECT().FindCages(gomock.Any(), das.StatusActive, das.CarnivoreCode).Return(cages, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(cages[1], nil)
+	stored := blue
+	stored.ID, stored.Version = 12, 1
+	tx.EXPECT().InsertDinosaur(gomock.Any(), blue).Return(stored, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoAdded, 4, stored).Return(nil)
+	w = serveRequest(r, "POST", "/v2/dinosaurs?strategy=worst-fit", "", `{"species":"velociraptor","name":"blue"}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/v2/dinosaurs/12" || w.Header().Get("ETag") != `"1"` {
+		t.Errorf("create dinosaur returned %d %v %s", w.Code, w.Header(), w.Body.String())
+	}
+	var got das.Dinosaur
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got != stored {
+		t.Errorf("create dinosaur returned %s", w.Body.String())
+	}
+
+	tests := []struct {
+		method, url, ifMatch, body string
+		status                     int
+	}{
+		{"POST", "/v2/cages", "", `{"kind":"V"}`, http.StatusBadRequest},
+		{"POST", "/v2/dinosaurs", "", `{"species":"dodo","name":"dee"}`, http.StatusBadRequest},
+		{"PUT", "/v2/dinosaurs/1/cage", "", `{"cage":5}`, http.StatusPreconditionRequired},
+		{"PUT", "/v2/dinosaurs/1/cage", `"4"`, `{"cage":0}`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		w := serveRequest(r, tc.method, tc.url, tc.ifMatch, tc.body)
+		if w.Code != tc.status {
+			t.Errorf("%s %s %s expected %d got %d %s", tc.method, tc.url, tc.body, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
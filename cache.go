@@ -0,0 +1,311 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"dinocage/das"
+)
+
+// generic concurrent cache bounded in size and age
+// the least recently used entry is evicted when full and entries expire after ttl
+
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	order   *list.List
+	size    int
+	ttl     time.Duration
+	// moves on every invalidation so a load that raced one is not stored
+	gen   uint64
+	stats CacheStats
+	now   func() time.Time
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// counts since the cache was created
+type CacheStats struct {
+	Entries       int    `json:"entries"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Expirations   uint64 `json:"expirations"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+func NewCache[K comparable, V any](size int, ttl time.Duration) *Cache[K, V] {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache[K, V]{entries: make(map[K]*list.Element), order: list.New(), size: size, ttl: ttl, now: time.Now}
+}
+
+func (c *Cache[K, V]) Load(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var val V
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return val, false
+	}
+	entry := el.Value.(*cacheEntry[K, V])
+	if !c.now().Before(entry.expires) {
+		c.remove(el)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return val, false
+	}
+	c.order.MoveToFront(el)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+func (c *Cache[K, V]) Store(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(key, value)
+}
+
+// return the cached value or the result of load, which is cached unless
+// the cache was invalidated while it ran
+func (c *Cache[K, V]) LoadOrCompute(key K, load func() (V, error)) (V, error) {
+	if val, ok := c.Load(key); ok {
+		return val, nil
+	}
+	c.mu.Lock()
+	gen := c.gen
+	c.mu.Unlock()
+	val, err := load()
+	if err != nil {
+		return val, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.store(key, val)
+	}
+	return val, nil
+}
+
+// drop a single entry
+func (c *Cache[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.stats.Invalidations++
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+// drop every entry
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.stats.Invalidations++
+	c.entries = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+func (c *Cache[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+func (c *Cache[K, V]) store(key K, value V) {
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry[K, V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry[K, V]).key)
+}
+
+const (
+	DefaultCacheSize     = 1024
+	DefaultCacheTTL      = 10 * time.Second
+	DefaultCacheInterval = time.Second
+)
+
+// cage and species reads of a data access provider served from caches
+// every change made through the provider clears the cached cages and species
+// and changes made by other instances are picked up by following the event clock
+// entries are loaded from the primary so a lagging replica cannot refill them
+// reads within a unit of work are never cached as they lock the rows they read
+type CachedProvider struct {
+	das.DataAccessProvider
+	cages    *Cache[int, das.Cage]
+	listings *Cache[string, []das.Cage]
+	species  *Cache[string, das.Species]
+	known    *Cache[string, []das.Species]
+	Interval time.Duration
+}
+
+func NewCachedProvider(dap das.DataAccessProvider, size int, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		DataAccessProvider: dap,
+		cages:              NewCache[int, das.Cage](size, ttl),
+		listings:           NewCache[string, []das.Cage](size, ttl),
+		species:            NewCache[string, das.Species](size, ttl),
+		known:              NewCache[string, []das.Species](1, ttl),
+		Interval:           DefaultCacheInterval,
+	}
+}
+
+// statistics of each cache keyed on what it holds
+func (cp *CachedProvider) Stats() map[string]CacheStats {
+	return map[string]CacheStats{
+		"cages":           cp.cages.Stats(),
+		"cage_listings":   cp.listings.Stats(),
+		"species":         cp.species.Stats(),
+		"species_listing": cp.known.Stats(),
+	}
+}
+
+// a placement changes the counts of two cages and every listing holding them
+// and a unit of work may change any species so any change clears every cache
+func (cp *CachedProvider) invalidate() {
+	cp.cages.Clear()
+	cp.listings.Clear()
+	cp.species.Clear()
+	cp.known.Clear()
+}
+
+func (cp *CachedProvider) GetCage(ctx context.Context, cageID int) (das.Cage, error) {
+	return cp.cages.LoadOrCompute(cageID, func() (das.Cage, error) {
+		return cp.DataAccessProvider.GetCage(das.OnPrimary(ctx), cageID)
+	})
+}
+
+func (cp *CachedProvider) GetCages(ctx context.Context, optStatus ...string) ([]das.Cage, error) {
+	var status string
+	if len(optStatus) != 0 {
+		status = optStatus[0]
+	}
+	cages, err := cp.listings.LoadOrCompute(status, func() ([]das.Cage, error) {
+		return cp.DataAccessProvider.GetCages(das.OnPrimary(ctx), optStatus...)
+	})
+	// callers may modify the slice they are given
+	return slices.Clone(cages), err
+}
+
+// unknown species are not cached so one added elsewhere is found at once
+func (cp *CachedProvider) GetSpecies(ctx context.Context, name string) (das.Species, error) {
+	return cp.species.LoadOrCompute(name, func() (das.Species, error) {
+		return cp.DataAccessProvider.GetSpecies(das.OnPrimary(ctx), name)
+	})
+}
+
+func (cp *CachedProvider) ListSpecies(ctx context.Context) ([]das.Species, error) {
+	species, err := cp.known.LoadOrCompute("", func() ([]das.Species, error) {
+		return cp.DataAccessProvider.ListSpecies(das.OnPrimary(ctx))
+	})
+	return slices.Clone(species), err
+}
+
+func (cp *CachedProvider) SaveSpecies(ctx context.Context, s das.Species) error {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.SaveSpecies(ctx, s)
+}
+
+func (cp *CachedProvider) RemoveSpecies(ctx context.Context, name string) error {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.RemoveSpecies(ctx, name)
+}
+
+func (cp *CachedProvider) InsertCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.InsertCage(ctx, cage)
+}
+
+func (cp *CachedProvider) SaveCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.SaveCage(ctx, cage)
+}
+
+func (cp *CachedProvider) RemoveCage(ctx context.Context, cageID int) error {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.RemoveCage(ctx, cageID)
+}
+
+func (cp *CachedProvider) InsertDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.InsertDinosaur(ctx, d)
+}
+
+func (cp *CachedProvider) SaveDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.SaveDinosaur(ctx, d)
+}
+
+func (cp *CachedProvider) RemoveDinosaur(ctx context.Context, dinoID int) error {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.RemoveDinosaur(ctx, dinoID)
+}
+
+// cleared once the unit of work has ended, a read racing the commit is
+// not stored as the clear moves the cache generation
+func (cp *CachedProvider) Atomic(ctx context.Context, fn func(tx das.Tx) error) error {
+	defer cp.invalidate()
+	return cp.DataAccessProvider.Atomic(ctx, fn)
+}
+
+// clear the caches whenever any instance commits an event until the context is done
+// every change to species, cages or dinosaurs records an event, and the event clock
+// only moves in commit order so no change is passed over
+func (cp *CachedProvider) Follow(ctx context.Context) {
+	last, err := cp.EventClock(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("cache unable to read the event clock : %v", err)
+	}
+	ticker := time.NewTicker(cp.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		last, err = cp.follow(ctx, last)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("cache unable to follow events : %v", err)
+		}
+	}
+}
+
+// clear the caches if the event clock has moved from last returning its new value
+func (cp *CachedProvider) follow(ctx context.Context, last uint64) (uint64, error) {
+	tick, err := cp.EventClock(ctx)
+	if err != nil {
+		return last, err
+	}
+	if tick != last {
+		cp.invalidate()
+	}
+	return tick, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dinocage/das"
+	"dinocage/mocks"
+	"dinocage/service"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// a cache whose clock only moves when the test moves it
+func testCache(size int, ttl time.Duration) (*Cache[int, string], *time.Time) {
+	now := time.Now()
+	c := NewCache[int, string](size, ttl)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCacheExpiryAndEviction(t *testing.T) {
+	c, now := testCache(2, time.Minute)
+	c.Store(1, "one")
+	c.Store(2, "two")
+	if v, ok := c.Load(1); !ok || v != "one" {
+		t.Errorf("expected a hit got %v %v", v, ok)
+	}
+	// 2 is now the least recently used
+	c.Store(3, "three")
+	if _, ok := c.Load(2); ok {
+		t.Errorf("least recently used entry should have been evicted")
+	}
+	*now = now.Add(time.Minute)
+	if _, ok := c.Load(1); ok {
+		t.Errorf("entry should have expired")
+	}
+	want := CacheStats{Entries: 1, Hits: 1, Misses: 2, Evictions: 1, Expirations: 1}
+	if stats := c.Stats(); stats != want {
+		t.Errorf("expected %+v got %+v", want, stats)
+	}
+}
+
+func TestCacheLoadRacingInvalidation(t *testing.T) {
+	c, _ := testCache(10, time.Minute)
+	v, err := c.LoadOrCompute(1, func() (string, error) {
+		// a write lands while the value is being read
+		c.Invalidate(1)
+		return "stale", nil
+	})
+	if err != nil || v != "stale" {
+		t.Errorf("expected the loaded value got %v %v", v, err)
+	}
+	if _, ok := c.Load(1); ok {
+		t.Errorf("a load racing an invalidation should not be cached")
+	}
+	v, _ = c.LoadOrCompute(1, func() (string, error) { return "fresh", nil })
+	if cached, ok := c.Load(1); !ok || cached != "fresh" || v != "fresh" {
+		t.Errorf("expected the fresh value to be cached got %v %v", cached, ok)
+	}
+}
+
+func TestCacheConcurrentUse(t *testing.T) {
+	c := NewCache[int, int](8, time.Minute)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				switch i % 4 {
+				case 0:
+					c.Store(i%16, g)
+				case 1:
+					c.Load(i % 16)
+				case 2:
+					c.LoadOrCompute(i%16, func() (int, error) { return g, nil })
+				default:
+					if g == 0 {
+						c.Clear()
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := c.Stats().Entries; n > 8 {
+		t.Errorf("cache grew past its size to %d entries", n)
+	}
+}
+
+func TestCachedProviderInvalidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	cp := NewCachedProvider(mockDap, 10, time.Minute)
+	mockDap.EXPECT().GetCage(gomock.Any(), 1).Return(das.Cage{ID: 1, Count: 1}, nil)
+	mockDap.EXPECT().GetCages(gomock.Any()).Return([]das.Cage{{ID: 1, Count: 1}}, nil)
+	for i := 0; i < 3; i++ {
+		cp.GetCage(ctx, 1)
+		cp.GetCages(ctx)
+	}
+
+	// a placement clears the cached counts
+	mockDap.EXPECT().Atomic(gomock.Any(), gomock.Any()).Return(nil)
+	cp.Atomic(ctx, func(tx das.Tx) error { return nil })
+	mockDap.EXPECT().GetCage(gomock.Any(), 1).Return(das.Cage{ID: 1, Count: 2}, nil)
+	if cage, _ := cp.GetCage(ctx, 1); cage.Count != 2 {
+		t.Errorf("expected the count after the placement got %+v", cage)
+	}
+
+	// as does an event committed by another instance
+	mockDap.EXPECT().EventClock(gomock.Any()).Return(uint64(5), nil)
+	last, err := cp.follow(ctx, 4)
+	if err != nil || last != 5 {
+		t.Errorf("follow returned %d %v", last, err)
+	}
+	mockDap.EXPECT().GetCage(gomock.Any(), 1).Return(das.Cage{ID: 1, Count: 3}, nil)
+	if cage, _ := cp.GetCage(ctx, 1); cage.Count != 3 {
+		t.Errorf("expected the count after the event got %+v", cage)
+	}
+	mockDap.EXPECT().EventClock(gomock.Any()).Return(uint64(5), nil)
+	cp.follow(ctx, 5)
+	cp.GetCage(ctx, 1)
+
+	stats := cp.Stats()["cages"]
+	if stats.Hits != 3 || stats.Misses != 3 {
+		t.Errorf("unexpected cage cache stats %+v", stats)
+	}
+}
+
+func TestCachedProviderSpecies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	cp := NewCachedProvider(mockDap, 10, time.Minute)
+	dodo := das.Species{Name: "dodo", Diet: das.HerbivoreCode}
+	mockDap.EXPECT().GetSpecies(gomock.Any(), "dodo").Return(dodo, nil)
+	mockDap.EXPECT().ListSpecies(gomock.Any()).Return([]das.Species{dodo}, nil)
+	for i := 0; i < 3; i++ {
+		cp.GetSpecies(ctx, "dodo")
+		cp.ListSpecies(ctx)
+	}
+
+	// unknown species are looked up every time
+	mockDap.EXPECT().GetSpecies(gomock.Any(), "moa").Return(das.Species{}, das.ErrSpeciesNotFound).Times(2)
+	for i := 0; i < 2; i++ {
+		if _, err := cp.GetSpecies(ctx, "moa"); !errors.Is(err, das.ErrSpeciesNotFound) {
+			t.Errorf("expected an unknown species got %v", err)
+		}
+	}
+
+	// saving a species, applying a species file and a peer's event each clear them
+	changes := []func(){
+		func() {
+			mockDap.EXPECT().SaveSpecies(gomock.Any(), gomock.Any()).Return(nil)
+			cp.SaveSpecies(ctx, dodo)
+		},
+		func() {
+			mockDap.EXPECT().Atomic(gomock.Any(), gomock.Any()).Return(nil)
+			cp.Atomic(ctx, func(tx das.Tx) error { return nil })
+		},
+		func() {
+			mockDap.EXPECT().EventClock(gomock.Any()).Return(uint64(2), nil)
+			cp.follow(ctx, 1)
+		},
+	}
+	for i, change := range changes {
+		change()
+		weight := float64(i + 1)
+		mockDap.EXPECT().GetSpecies(gomock.Any(), "dodo").Return(das.Species{Name: "dodo", Diet: das.HerbivoreCode, WeightKg: weight}, nil)
+		mockDap.EXPECT().ListSpecies(gomock.Any()).Return([]das.Species{{Name: "dodo", Diet: das.HerbivoreCode, WeightKg: weight}}, nil)
+		if s, _ := cp.GetSpecies(ctx, "dodo"); s.WeightKg != weight {
+			t.Errorf("change %d expected the new species got %+v", i, s)
+		}
+		if list, _ := cp.ListSpecies(ctx); len(list) != 1 || list[0].WeightKg != weight {
+			t.Errorf("change %d expected the new listing got %+v", i, list)
+		}
+	}
+
+	stats := cp.Stats()["species"]
+	if stats.Hits != 2 || stats.Misses != 6 {
+		t.Errorf("unexpected species cache stats %+v", stats)
+	}
+}
+
+func TestCacheStatsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+	ah.cache = NewCachedProvider(mockDap, 10, time.Minute)
+	mockDap.EXPECT().GetCages(gomock.Any()).Return([]das.Cage{{ID: 1}}, nil)
+	ah.cache.GetCages(context.Background())
+	ah.cache.GetCages(context.Background())
+
+	w := httptest.NewRecorder()
+	ah.CacheStats(w, httptest.NewRequest("GET", "/v1/cache/stats", nil))
+	var stats map[string]CacheStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unable to read stats %v", err)
+	}
+	if len(stats) != 4 || stats["cage_listings"].Hits != 1 || stats["cage_listings"].Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+// every change made by another instance clears the cached cages once followed
+func TestCacheFollowsPeerChanges(t *testing.T) {
+	ctx := context.Background()
+	mine, theirs := sqlitePeers(t)
+	cp := NewCachedProvider(mine, 10, time.Minute)
+	park := service.NewParkService(theirs, theirs, das.DefaultPlacement())
+	if _, err := park.AddSpecies(ctx, das.Species{Name: "triceratops", Diet: das.HerbivoreCode}); err != nil {
+		t.Fatalf("add species failed %v", err)
+	}
+	cage, err := park.AddCage(ctx, das.HerbivoreCode, 4)
+	if err != nil {
+		t.Fatalf("add cage failed %v", err)
+	}
+	dino, err := park.PlaceDinosaur(ctx, cage.ID, das.Dinosaur{Species: "triceratops", Name: "cera"})
+	if err != nil {
+		t.Fatalf("place failed %v", err)
+	}
+	last, err := cp.follow(ctx, 0)
+	if err != nil {
+		t.Fatalf("follow failed %v", err)
+	}
+	cached, _ := cp.GetCage(ctx, cage.ID)
+
+	resized, err := park.ResizeCage(ctx, cage.ID, cached.Version, 6)
+	if err != nil {
+		t.Fatalf("resize failed %v", err)
+	}
+	last, _ = cp.follow(ctx, last)
+	if got, _ := cp.GetCage(ctx, cage.ID); got.Capacity != 6 {
+		t.Errorf("expected the resized capacity got %+v", got)
+	}
+
+	if err := park.DeleteDinosaur(ctx, int(dino.ID), dino.Version); err != nil {
+		t.Fatalf("delete dinosaur failed %v", err)
+	}
+	last, _ = cp.follow(ctx, last)
+	emptied, _ := cp.GetCage(ctx, cage.ID)
+	if emptied.Count != 0 {
+		t.Errorf("expected the dinosaur gone from the count got %+v", emptied)
+	}
+
+	if err := park.DeleteCage(ctx, cage.ID, emptied.Version); err != nil {
+		t.Fatalf("delete cage failed %v", err)
+	}
+	cp.follow(ctx, last)
+	if _, err := cp.GetCage(ctx, cage.ID); !errors.Is(err, das.ErrCageNotFound) {
+		t.Errorf("expected the deleted cage gone got %v", err)
+	}
+	if resized.Version >= emptied.Version {
+		t.Errorf("expected the cage version to move past %d got %d", resized.Version, emptied.Version)
+	}
+}
@@ -0,0 +1,455 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dinocage/das"
+)
+
+// optional placement overrides for a new dinosaur
+// an empty strategy or nil auto create uses the server default
+type PlacementOptions struct {
+	Strategy   string
+	AutoCreate *bool
+}
+
+func (po PlacementOptions) query() url.Values {
+	q := url.Values{}
+	if len(po.Strategy) != 0 {
+		q.Set("strategy", po.Strategy)
+	}
+	if po.AutoCreate != nil {
+		q.Set("auto_create", strconv.FormatBool(*po.AutoCreate))
+	}
+	return q
+}
+
+// report returned from a bulk import
+type ImportReport struct {
+	Mode     string             `json:"mode"`
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+	Results  []das.ImportResult `json:"results"`
+}
+
+// a park change received from the event stream
+type Event struct {
+	ID   uint64          `json:"id"`
+	Type string          `json:"type"`
+	Cage int             `json:"cage,omitempty"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type idResponse struct {
+	ID int `json:"id"`
+}
+
+// the payloads below carry only the fields the server accepts
+// as it refuses unknown fields
+
+type dinosaurPayload struct {
+	Species string `json:"species"`
+	Name    string `json:"name"`
+	Diet    string `json:"diet,omitempty"`
+}
+
+func newDinosaurPayload(d das.Dinosaur) dinosaurPayload {
+	return dinosaurPayload{Species: d.Species, Name: d.Name, Diet: d.Diet}
+}
+
+type statusChangePayload struct {
+	Reason string `json:"reason,omitempty"`
+	Actor  string `json:"actor,omitempty"`
+}
+
+type webhookPayload struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+	Secret string   `json:"secret"`
+}
+
+func ifMatch(version int) http.Header {
+	return http.Header{"If-Match": []string{fmt.Sprintf(`"%d"`, version)}}
+}
+
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/v1/healthcheck"}, nil)
+}
+
+// species
+
+func (c *Client) ListSpecies(ctx context.Context) ([]das.Species, error) {
+	var species []das.Species
+	err := c.do(ctx, request{method: http.MethodGet, path: "/v1/species/list"}, &species)
+	return species, err
+}
+
+func (c *Client) AddSpecies(ctx context.Context, species das.Species) error {
+	req, err := jsonRequest(http.MethodPost, "/v1/species/add", species)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, req, nil)
+}
+
+// replace the details of a species returning them as stored
+func (c *Client) UpdateSpecies(ctx context.Context, species das.Species) (das.Species, error) {
+	var updated das.Species
+	req, err := jsonRequest(http.MethodPut, "/v1/species/"+url.PathEscape(species.Name), species)
+	if err != nil {
+		return updated, err
+	}
+	err = c.do(ctx, req, &updated)
+	return updated, err
+}
+
+func (c *Client) DeleteSpecies(ctx context.Context, name string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/v1/species/" + url.PathEscape(name)}, nil)
+}
+
+// cages
+
+// list cages with an optional status filter
+func (c *Client) ListCages(ctx context.Context, status string) ([]das.Cage, error) {
+	req := request{method: http.MethodGet, path: "/v1/cages"}
+	if len(status) != 0 {
+		req.query = url.Values{"status": {status}}
+	}
+	var cages []das.Cage
+	err := c.do(ctx, req, &cages)
+	return cages, err
+}
+
+// create a cage for a diet code returning its id
+// a capacity of zero uses the server default
+func (c *Client) AddCage(ctx context.Context, diet string, capacity int) (int, error) {
+	req := request{method: http.MethodPost, path: "/v1/cage/" + url.PathEscape(diet) + "/add"}
+	if capacity != 0 {
+		req.query = url.Values{"cap": {strconv.Itoa(capacity)}}
+	}
+	var res idResponse
+	err := c.do(ctx, req, &res)
+	return res.ID, err
+}
+
+func (c *Client) GetCage(ctx context.Context, cageID int) (das.Cage, error) {
+	var cage das.Cage
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/v1/cage/%d", cageID)}, &cage)
+	return cage, err
+}
+
+// change the capacity of a cage at the given version
+func (c *Client) ResizeCage(ctx context.Context, cageID, version, capacity int) (das.Cage, error) {
+	var cage das.Cage
+	req, err := jsonRequest(http.MethodPatch, fmt.Sprintf("/v1/cage/%d", cageID), map[string]int{"capacity": capacity})
+	if err != nil {
+		return cage, err
+	}
+	req.header = ifMatch(version)
+	err = c.do(ctx, req, &cage)
+	return cage, err
+}
+
+// remove an empty cage at the given version
+func (c *Client) DeleteCage(ctx context.Context, cageID, version int) error {
+	req := request{method: http.MethodDelete, path: fmt.Sprintf("/v1/cage/%d", cageID), header: ifMatch(version)}
+	return c.do(ctx, req, nil)
+}
+
+func (c *Client) ListCageDinosaurs(ctx context.Context, cageID int) ([]das.Dinosaur, error) {
+	var dinos []das.Dinosaur
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/v1/cage/%d/list_dinosaurs", cageID)}, &dinos)
+	return dinos, err
+}
+
+// move a cage to a new status at the version given in the change
+func (c *Client) SetCageStatus(ctx context.Context, cageID int, change das.StatusChange) error {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/v1/cage/%d/status/%s", cageID, url.PathEscape(change.Status)), statusChangePayload{Reason: change.Reason, Actor: change.Actor})
+	if err != nil {
+		return err
+	}
+	req.header = ifMatch(change.Version)
+	return c.do(ctx, req, nil)
+}
+
+func (c *Client) CageStatusHistory(ctx context.Context, cageID int) ([]das.StatusTransition, error) {
+	var history []das.StatusTransition
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/v1/cage/%d/status_history", cageID)}, &history)
+	return history, err
+}
+
+func (c *Client) EvacuateCage(ctx context.Context, cageID int, opts das.EvacuateOptions) (das.EvacuationPlan, error) {
+	q := url.Values{}
+	q.Set("dry_run", strconv.FormatBool(opts.DryRun))
+	q.Set("create_cages", strconv.FormatBool(opts.CreateCages))
+	q.Set("power_down", strconv.FormatBool(opts.PowerDown))
+	if len(opts.Reason) != 0 {
+		q.Set("reason", opts.Reason)
+	}
+	if len(opts.Actor) != 0 {
+		q.Set("actor", opts.Actor)
+	}
+	var plan das.EvacuationPlan
+	err := c.do(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/v1/cage/%d/evacuate", cageID), query: q}, &plan)
+	return plan, err
+}
+
+// plan a rebalance applying it unless opts.DryRun is set
+func (c *Client) RebalanceCages(ctx context.Context, opts das.RebalanceOptions) (das.RebalancePlan, error) {
+	req := request{method: http.MethodPost, path: "/v1/plans/rebalance", query: url.Values{}}
+	if opts.DryRun {
+		req.method = http.MethodGet
+	}
+	if len(opts.Mode) != 0 {
+		req.query.Set("mode", opts.Mode)
+	}
+	if opts.Target != 0 {
+		req.query.Set("target", strconv.FormatFloat(opts.Target, 'f', -1, 64))
+	}
+	var plan das.RebalancePlan
+	err := c.do(ctx, req, &plan)
+	return plan, err
+}
+
+// dinosaurs
+
+// list dinosaurs with an optional species filter
+func (c *Client) ListDinosaurs(ctx context.Context, species string) ([]das.Dinosaur, error) {
+	req := request{method: http.MethodGet, path: "/v1/dino/list"}
+	if len(species) != 0 {
+		req.query = url.Values{"species": {species}}
+	}
+	var dinos []das.Dinosaur
+	err := c.do(ctx, req, &dinos)
+	return dinos, err
+}
+
+// add a dinosaur leaving the server to choose its cage
+func (c *Client) AddDinosaur(ctx context.Context, dino das.Dinosaur, opts PlacementOptions) error {
+	req, err := jsonRequest(http.MethodPost, "/v1/dino/add", newDinosaurPayload(dino))
+	if err != nil {
+		return err
+	}
+	req.query = opts.query()
+	return c.do(ctx, req, nil)
+}
+
+// add a dinosaur to the given cage
+func (c *Client) PlaceDinosaur(ctx context.Context, cageID int, dino das.Dinosaur) error {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/v1/cage/%d/add_dino", cageID), newDinosaurPayload(dino))
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, req, nil)
+}
+
+func (c *Client) GetDinosaur(ctx context.Context, dinoID int) (das.Dinosaur, error) {
+	var dino das.Dinosaur
+	err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/v1/dino/%d", dinoID)}, &dino)
+	return dino, err
+}
+
+// rename a dinosaur at the given version
+func (c *Client) RenameDinosaur(ctx context.Context, dinoID, version int, name string) (das.Dinosaur, error) {
+	var dino das.Dinosaur
+	req, err := jsonRequest(http.MethodPatch, fmt.Sprintf("/v1/dino/%d", dinoID), map[string]string{"name": name})
+	if err != nil {
+		return dino, err
+	}
+	req.header = ifMatch(version)
+	err = c.do(ctx, req, &dino)
+	return dino, err
+}
+
+// move a dinosaur at the given version to another cage
+func (c *Client) MoveDinosaur(ctx context.Context, dinoID, version, cageID int) (das.Dinosaur, error) {
+	var dino das.Dinosaur
+	req := request{method: http.MethodPost, path: fmt.Sprintf("/v1/dino/%d/move/%d", dinoID, cageID), header: ifMatch(version)}
+	err := c.do(ctx, req, &dino)
+	return dino, err
+}
+
+// remove a dinosaur at the given version
+func (c *Client) DeleteDinosaur(ctx context.Context, dinoID, version int) error {
+	req := request{method: http.MethodDelete, path: fmt.Sprintf("/v1/dino/%d", dinoID), header: ifMatch(version)}
+	return c.do(ctx, req, nil)
+}
+
+// webhooks
+
+// subscribe to events returning the subscription id
+func (c *Client) AddWebhook(ctx context.Context, hook das.Webhook) (int, error) {
+	req, err := jsonRequest(http.MethodPost, "/v1/webhooks", webhookPayload{URL: hook.URL, Events: hook.Events, Secret: hook.Secret})
+	if err != nil {
+		return 0, err
+	}
+	var res idResponse
+	err = c.do(ctx, req, &res)
+	return res.ID, err
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]das.Webhook, error) {
+	var hooks []das.Webhook
+	err := c.do(ctx, request{method: http.MethodGet, path: "/v1/webhooks"}, &hooks)
+	return hooks, err
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/v1/webhooks/%d", webhookID)}, nil)
+}
+
+// delivery log of a webhook with an optional status filter
+func (c *Client) ListDeliveries(ctx context.Context, webhookID int, status string) ([]das.Delivery, error) {
+	req := request{method: http.MethodGet, path: fmt.Sprintf("/v1/webhooks/%d/deliveries", webhookID)}
+	if len(status) != 0 {
+		req.query = url.Values{"status": {status}}
+	}
+	var deliveries []das.Delivery
+	err := c.do(ctx, req, &deliveries)
+	return deliveries, err
+}
+
+func (c *Client) ListDeadLetters(ctx context.Context) ([]das.Delivery, error) {
+	var deliveries []das.Delivery
+	err := c.do(ctx, request{method: http.MethodGet, path: "/v1/webhooks/dead_letters"}, &deliveries)
+	return deliveries, err
+}
+
+// snapshots
+
+var importContentTypes = map[string]string{
+	"csv":   "text/csv",
+	"jsonl": "application/x-ndjson",
+	"json":  "application/json",
+}
+
+// bulk import csv or jsonl data in atomic or best_effort mode
+// a rejected import returns the report along with an ErrUnprocessable error
+func (c *Client) Import(ctx context.Context, data []byte, format, mode string, opts PlacementOptions) (ImportReport, error) {
+	var report ImportReport
+	req := request{method: http.MethodPost, path: "/v1/import", query: opts.query(), body: data, contentType: importContentTypes[format]}
+	req.query.Set("format", format)
+	if len(mode) != 0 {
+		req.query.Set("mode", mode)
+	}
+	err := c.do(ctx, req, &report)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		json.Unmarshal(apiErr.Body, &report)
+	}
+	return report, err
+}
+
+// export a snapshot of the park in json, jsonl or csv
+func (c *Client) Export(ctx context.Context, format string) ([]byte, error) {
+	req := request{method: http.MethodGet, path: "/v1/export", query: url.Values{"format": {format}}}
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// restore a snapshot into an empty park
+func (c *Client) Restore(ctx context.Context, data []byte, format string) error {
+	req := request{method: http.MethodPost, path: "/v1/restore", query: url.Values{"format": {format}}, body: data, contentType: importContentTypes[format]}
+	return c.do(ctx, req, nil)
+}
+
+// graphql
+
+// run a graphql query decoding its data into out
+// errors reported by the query are returned as a single error
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) error {
+	req, err := jsonRequest(http.MethodPost, "/graphql", map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	err = c.do(ctx, req, &res)
+	if err != nil {
+		return err
+	}
+	if len(res.Errors) != 0 {
+		msgs := make([]string, 0, len(res.Errors))
+		for _, e := range res.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("graphql : %s", strings.Join(msgs, "; "))
+	}
+	if out == nil || len(res.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res.Data, out)
+}
+
+// events
+
+// stream park changes after lastID calling fn for each event until the
+// context is done, the server ends the stream or fn returns an error
+// types and cage optionally filter the events
+func (c *Client) Events(ctx context.Context, lastID uint64, types []string, cage int, fn func(Event) error) error {
+	req := request{method: http.MethodGet, path: "/v1/events", query: url.Values{}}
+	if len(types) != 0 {
+		req.query.Set("types", strings.Join(types, ","))
+	}
+	if cage != 0 {
+		req.query.Set("cage", strconv.Itoa(cage))
+	}
+	if lastID != 0 {
+		req.header = http.Header{"Last-Event-ID": {strconv.FormatUint(lastID, 10)}}
+	}
+	// streams stay open so the overall client timeout does not apply
+	hc := *c.HTTPClient
+	hc.Timeout = 0
+	r, err := c.build(ctx, req, "")
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(r)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxErrorBody)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e Event
+		err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+		if err != nil {
+			return fmt.Errorf("bad event : %w", err)
+		}
+		err = fn(e)
+		if err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
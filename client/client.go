@@ -0,0 +1,231 @@
+// Package client is a typed Go client for the dinocage rest api.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 200 * time.Millisecond
+	DefaultTimeout    = 30 * time.Second
+	maxErrorBody      = 1 << 20
+)
+
+var (
+	ErrBadRequest           = errors.New("bad request")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrVersionMismatch      = errors.New("version mismatch")
+	ErrPreconditionRequired = errors.New("precondition required")
+	ErrUnprocessable        = errors.New("unprocessable")
+	ErrServer               = errors.New("server error")
+)
+
+// a problem with one field of a request payload
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// error for any response outside 2xx carrying the message from the server
+// it matches the sentinel for its status with errors.Is
+// a payload the server found invalid lists every problem in Fields
+type APIError struct {
+	StatusCode int
+	Message    string
+	Body       []byte
+	Fields     []FieldError
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s : %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrVersionMismatch:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrPreconditionRequired:
+		return e.StatusCode == http.StatusPreconditionRequired
+	case ErrUnprocessable:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// client for a dinocage server
+// requests failing with a 5xx or a network error are retried with the delay
+// doubling from Backoff - mutating requests carry an Idempotency-Key so a
+// retry is never applied twice
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	MaxRetries int
+	Backoff    time.Duration
+	Header     http.Header
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    DefaultBackoff,
+		Header:     make(http.Header),
+	}
+}
+
+// a request to send with its body held so it can be retried
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        []byte
+	contentType string
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+func jsonRequest(method, path string, v any) (request, error) {
+	req := request{method: method, path: path}
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return req, err
+		}
+		req.body = b
+		req.contentType = "application/json"
+	}
+	return req, nil
+}
+
+func (c *Client) build(ctx context.Context, req request, idemKey string) (*http.Request, error) {
+	u := c.BaseURL + req.path
+	if len(req.query) != 0 {
+		u += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	r, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		r.Header[k] = v
+	}
+	for k, v := range req.header {
+		r.Header[k] = v
+	}
+	if len(req.contentType) != 0 {
+		r.Header.Set("Content-Type", req.contentType)
+	}
+	if len(idemKey) != 0 {
+		r.Header.Set("Idempotency-Key", idemKey)
+	}
+	return r, nil
+}
+
+// send a request retrying server errors returning the successful response
+// the caller must close the response body
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var idemKey string
+	if req.method != http.MethodGet && req.method != http.MethodHead {
+		idemKey = newIdempotencyKey()
+	}
+	delay := c.Backoff
+	for attempt := 0; ; attempt++ {
+		r, err := c.build(ctx, req, idemKey)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTPClient.Do(r)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err == nil {
+			err = decodeError(resp)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var apiErr *APIError
+		if (errors.As(err, &apiErr) && apiErr.StatusCode < 500) || attempt >= c.MaxRetries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// read the error message the server wrote for a failed response
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(b)), Body: b}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var v struct {
+			Error  string       `json:"error"`
+			Fields []FieldError `json:"fields"`
+		}
+		if json.Unmarshal(b, &v) == nil && len(v.Fields) != 0 {
+			apiErr.Fields = v.Fields
+			var parts []string
+			for _, f := range v.Fields {
+				parts = append(parts, f.Field+" "+f.Message)
+			}
+			apiErr.Message = v.Error + " : " + strings.Join(parts, ", ")
+		}
+	}
+	return apiErr
+}
+
+// send a request decoding a json response into out when given
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("unable to decode response : %w", err)
+	}
+	return nil
+}
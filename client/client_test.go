@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dinocage/das"
+)
+
+func TestRetryServerErrors(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("busy"))
+			return
+		}
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer svr.Close()
+
+	c := New(svr.URL)
+	c.Backoff = time.Millisecond
+	id, err := c.AddCage(context.Background(), das.CarnivoreCode, 4)
+	if err != nil || id != 7 {
+		t.Fatalf("expected cage 7 got %d %v", id, err)
+	}
+	if len(keys) != 3 || len(keys[0]) == 0 || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Errorf("retries must reuse one idempotency key got %q", keys)
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	calls := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("database down"))
+	}))
+	defer svr.Close()
+
+	c := New(svr.URL)
+	c.Backoff = time.Millisecond
+	c.MaxRetries = 2
+	_, err := c.ListCages(context.Background(), "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrServer) || apiErr.Message != "database down" {
+		t.Errorf("expected server error got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts got %d", calls)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusPreconditionFailed, ErrVersionMismatch},
+		{http.StatusPreconditionRequired, ErrPreconditionRequired},
+		{http.StatusUnprocessableEntity, ErrUnprocessable},
+	}
+	for _, tc := range tests {
+		calls := 0
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tc.status)
+		}))
+		_, err := New(svr.URL).GetCage(context.Background(), 1)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("status %d expected %v got %v", tc.status, tc.want, err)
+		}
+		if calls != 1 {
+			t.Errorf("status %d should not be retried got %d calls", tc.status, calls)
+		}
+		svr.Close()
+	}
+}
+
+func TestClientFieldErrors(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid request","fields":[{"field":"name","message":"is required"},{"field":"diet","message":"must be H (herbivore) or C (carnivore) not \"V\""}]}`))
+	}))
+	defer svr.Close()
+
+	err := New(svr.URL).AddSpecies(context.Background(), das.Species{Diet: "V"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("expected a bad request got %v", err)
+	}
+	if len(apiErr.Fields) != 2 || apiErr.Fields[0].Field != "name" {
+		t.Errorf("expected both field problems got %+v", apiErr.Fields)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer svr.Close()
+
+	c := New(svr.URL)
+	c.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Health(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded got %v", err)
+	}
+}
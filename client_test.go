@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dinocage/client"
+	"dinocage/das"
+	"dinocage/mocks"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// serve the full router for the given handlers returning a client for it
+func testClient(t *testing.T, ah *AppHandlers) *client.Client {
+	r, err := NewRouter(ah)
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+	svr := httptest.NewServer(r)
+	t.Cleanup(svr.Close)
+	c := client.New(svr.URL)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestClientCages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+	ah.idempotent = NewIdempotencyStore(time.Minute)
+	c := testClient(t, ah)
+	ctx := context.Background()
+
+	tx := mockWork(ctrl, mockDap)
+	created := das.Cage{ID: 3, Status: das.StatusActive, Capacity: 6, Kind: das.HerbivoreCode, Version: 1}
+	tx.EXPECT().InsertCage(gomock.Any(), das.Cage{Status: das.StatusActive, Capacity: 6, Kind: das.HerbivoreCode}).Return(created, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageCreated, 3, created).Return(nil)
+	id, err := c.AddCage(ctx, das.HerbivoreCode, 6)
+	if err != nil || id != 3 {
+		t.Errorf("add cage returned %d %v", id, err)
+	}
+
+	mockDap.EXPECT().GetCages(gomock.Any(), das.StatusActive).Return([]das.Cage{{ID: 3, Status: das.StatusActive, Capacity: 6, Kind: das.HerbivoreCode, Version: 1}}, nil)
+	cages, err := c.ListCages(ctx, das.StatusActive)
+	if err != nil || len(cages) != 1 || cages[0].ID != 3 || cages[0].Version != 1 {
+		t.Errorf("list cages returned %+v %v", cages, err)
+	}
+
+	mockDap.EXPECT().GetCage(gomock.Any(), 9).Return(das.Cage{}, das.ErrCageNotFound)
+	_, err = c.GetCage(ctx, 9)
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("expected not found got %v", err)
+	}
+
+	change := das.StatusChange{Status: das.StatusLockdown, Reason: "storm", Actor: "ops", Version: 4}
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(das.Cage{ID: 3, Status: das.StatusActive, Capacity: 6, Kind: das.HerbivoreCode, Version: 5}, nil)
+	err = c.SetCageStatus(ctx, 3, change)
+	if !errors.Is(err, client.ErrVersionMismatch) {
+		t.Errorf("expected version mismatch got %v", err)
+	}
+
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(created, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), das.Cage{ID: 3, Status: das.StatusActive, Capacity: 8, Kind: das.HerbivoreCode, Version: 1}).Return(das.Cage{ID: 3, Capacity: 8, Version: 2}, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageResized, 3, das.Cage{ID: 3, Capacity: 8, Version: 2}).Return(nil)
+	cage, err := c.ResizeCage(ctx, 3, 1, 8)
+	if err != nil || cage.Capacity != 8 || cage.Version != 2 {
+		t.Errorf("resize cage returned %+v %v", cage, err)
+	}
+
+	// rejected by request validation before reaching the handler
+	_, err = c.AddCage(ctx, "V", 0)
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("expected bad request got %v", err)
+	}
+}
+
+func TestClientDinosaurs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, das.Species{Name: "velociraptor", Diet: das.CarnivoreCode})
+	c := testClient(t, NewAppHandlers(mockDap, das.DefaultPlacement()))
+	ctx := context.Background()
+
+	tx := mockWork(ctrl, mockDap)
+	full := das.Cage{ID: 2, Status: das.StatusActive, Capacity: 1, Count: 1, Kind: das.CarnivoreCode, Version: 7}
+	dino := das.Dinosaur{Species: "Velociraptor", Name: "blue", Diet: das.CarnivoreCode}
+	autoCreate := false
+	tx.EXPECT().FindCages(gomock.Any(), das.StatusActive, das.CarnivoreCode).Return([]das.Cage{full}, nil)
+	err := c.AddDinosaur(ctx, dino, client.PlacementOptions{Strategy: das.StrategyBestFit, AutoCreate: &autoCreate})
+	if !errors.Is(err, client.ErrConflict) {
+		t.Errorf("expected conflict got %v", err)
+	}
+
+	mockDap.EXPECT().GetDinosaurs(gomock.Any(), "velociraptor").Return([]das.Dinosaur{{ID: 1, Species: "velociraptor", Name: "blue", Cage: 2, Version: 3}}, nil)
+	dinos, err := c.ListDinosaurs(ctx, "velociraptor")
+	if err != nil || len(dinos) != 1 || dinos[0].Cage != 2 {
+		t.Errorf("list dinosaurs returned %+v %v", dinos, err)
+	}
+
+	blue := das.Dinosaur{ID: 1, Species: "velociraptor", Name: "blue", Diet: das.CarnivoreCode, Cage: 2, Version: 3}
+	tx.EXPECT().GetDinosaur(gomock.Any(), 1).Return(blue, nil)
+	tx.EXPECT().SaveDinosaur(gomock.Any(), gomock.Any()).Return(das.Dinosaur{ID: 1, Name: "charlie", Version: 4}, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoRenamed, 0, gomock.Any()).Return(nil)
+	renamed, err := c.RenameDinosaur(ctx, 1, 3, "charlie")
+	if err != nil || renamed.Name != "charlie" || renamed.Version != 4 {
+		t.Errorf("rename returned %+v %v", renamed, err)
+	}
+
+	blue.Version = 4
+	tx.EXPECT().GetDinosaur(gomock.Any(), 1).Return(blue, nil).Times(2)
+	tx.EXPECT().GetCage(gomock.Any(), 2).Return(full, nil).Times(2)
+	tx.EXPECT().GetCage(gomock.Any(), 6).Return(das.Cage{ID: 6, Status: das.StatusActive, Capacity: 4, Kind: das.CarnivoreCode, Version: 1}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(das.Cage{}, nil).Times(2)
+	tx.EXPECT().SaveDinosaur(gomock.Any(), gomock.Any()).Return(das.Dinosaur{ID: 1, Cage: 6, Version: 5}, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoPlaced, 6, gomock.Any()).Return(nil)
+	moved, err := c.MoveDinosaur(ctx, 1, 4, 6)
+	if err != nil || moved.Cage != 6 || moved.Version != 5 {
+		t.Errorf("move returned %+v %v", moved, err)
+	}
+
+	tx.EXPECT().GetCage(gomock.Any(), 7).Return(das.Cage{ID: 7, Status: das.StatusActive, Capacity: 1, Count: 1, Kind: das.CarnivoreCode, Version: 2}, nil)
+	_, err = c.MoveDinosaur(ctx, 1, 4, 7)
+	if !errors.Is(err, client.ErrConflict) {
+		t.Errorf("expected conflict got %v", err)
+	}
+
+	species, err := c.ListSpecies(ctx)
+	if err != nil || len(species) != 1 || species[0].Name != "velociraptor" {
+		t.Errorf("list species returned %+v %v", species, err)
+	}
+}
+
+func TestClientImportReport(t *testing.T) {
+	c := testClient(t, NewAppHandlers(sqliteProvider(t), das.DefaultPlacement()))
+
+	data := []byte(`{"type":"cage","kind":"H","capacity":4}` + "\n" + `{"type":"dinosaur","species":"dodo","name":"dee"}` + "\n")
+	report, err := c.Import(context.Background(), data, "jsonl", "atomic", client.PlacementOptions{})
+	if !errors.Is(err, client.ErrUnprocessable) {
+		t.Errorf("expected rejected import got %v", err)
+	}
+	if report.Failed != 2 || len(report.Results) != 2 || report.Results[0].Error != "not attempted" || len(report.Results[1].Error) == 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	hub := NewEventHub(DefaultEventBuffer)
+	deliver(hub, 1, das.EventCageCreated, 1, map[string]int{"id": 1})
+	deliver(hub, 2, das.EventDinoPlaced, 2, nil)
+	deliver(hub, 3, das.EventCageCreated, 3, nil)
+	c := testClient(t, &AppHandlers{events: hub})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var ids []uint64
+	done := errors.New("done")
+	err := c.Events(ctx, 0, []string{das.EventCageCreated}, 0, func(e client.Event) error {
+		ids = append(ids, e.ID)
+		if len(ids) == 2 {
+			return done
+		}
+		return nil
+	})
+	if err != done || len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("unexpected events %v %v", ids, err)
+	}
+}
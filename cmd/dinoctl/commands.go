@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"dinocage/client"
+	"dinocage/das"
+)
+
+func usageError(format string, a ...any) error {
+	return fmt.Errorf("%w : %s", errUsage, fmt.Sprintf(format, a...))
+}
+
+// parse flags that may appear before, after or between positional arguments
+// and check the number of positional arguments
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError("%s %v", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+	if len(pos) != want {
+		return nil, usageError("%s expects %d arguments got %d", fs.Name(), want, len(pos))
+	}
+	return pos, nil
+}
+
+func parseID(name, s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 1 {
+		return 0, usageError("invalid %s %s", name, s)
+	}
+	return id, nil
+}
+
+// report a successful change that has nothing to show
+// only the table format prints so json and csv output stays parseable
+func (e *env) done(msg string) error {
+	if e.format == FormatTable {
+		_, err := fmt.Fprintln(e.out, msg)
+		return err
+	}
+	return nil
+}
+
+type action func(ctx context.Context, e *env, args []string) error
+
+var commands = map[string]map[string]action{
+	"species": {
+		"list": speciesList,
+		"add":  speciesAdd,
+	},
+	"cage": {
+		"list":   cageList,
+		"add":    cageAdd,
+		"show":   cageShow,
+		"status": cageStatus,
+	},
+	"dino": {
+		"list":  dinoList,
+		"add":   dinoAdd,
+		"place": dinoPlace,
+		"move":  dinoMove,
+	},
+}
+
+func dispatch(ctx context.Context, e *env, args []string) error {
+	if len(args) < 2 {
+		return usageError("a command and action are required")
+	}
+	actions, ok := commands[args[0]]
+	if !ok {
+		return usageError("unknown command %s", args[0])
+	}
+	fn, ok := actions[args[1]]
+	if !ok {
+		return usageError("unknown action %s %s", args[0], args[1])
+	}
+	return fn(ctx, e, args[2:])
+}
+
+// species
+
+func speciesList(ctx context.Context, e *env, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("species list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	species, err := e.client.ListSpecies(ctx)
+	if err != nil {
+		return err
+	}
+	return writeSpecies(e.out, e.format, species)
+}
+
+func speciesAdd(ctx context.Context, e *env, args []string) error {
+	pos, err := parseArgs(flag.NewFlagSet("species add", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	species := das.Species{Name: pos[0], Diet: strings.ToUpper(pos[1])}
+	if err := e.client.AddSpecies(ctx, species); err != nil {
+		return err
+	}
+	return e.done("species " + species.Name + " added")
+}
+
+// cages
+
+func cageList(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("cage list", flag.ContinueOnError)
+	status := fs.String("status", "", "only list cages with this status")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	cages, err := e.client.ListCages(ctx, strings.ToUpper(*status))
+	if err != nil {
+		return err
+	}
+	return writeCages(e.out, e.format, cages)
+}
+
+func cageAdd(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("cage add", flag.ContinueOnError)
+	capacity := fs.Int("capacity", 0, "number of dinosaurs the cage holds (default server setting)")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := e.client.AddCage(ctx, strings.ToUpper(pos[0]), *capacity)
+	if err != nil {
+		return err
+	}
+	cage, err := e.client.GetCage(ctx, id)
+	if err != nil {
+		return err
+	}
+	return writeCages(e.out, e.format, []das.Cage{cage})
+}
+
+// show a cage along with the dinosaurs in it
+func cageShow(ctx context.Context, e *env, args []string) error {
+	pos, err := parseArgs(flag.NewFlagSet("cage show", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	cageID, err := parseID("cage id", pos[0])
+	if err != nil {
+		return err
+	}
+	cage, err := e.client.GetCage(ctx, cageID)
+	if err != nil {
+		return err
+	}
+	dinos, err := e.client.ListCageDinosaurs(ctx, cageID)
+	if err != nil {
+		return err
+	}
+	if e.format == FormatJSON {
+		return write(e.out, e.format, struct {
+			das.Cage
+			Dinosaurs []das.Dinosaur `json:"dinosaurs"`
+		}{cage, dinos}, nil, nil)
+	}
+	if err := writeCages(e.out, e.format, []das.Cage{cage}); err != nil {
+		return err
+	}
+	fmt.Fprintln(e.out)
+	return writeDinosaurs(e.out, e.format, dinos)
+}
+
+// change the status of a cage
+// the current version is fetched unless one is given
+func cageStatus(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("cage status", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the status is changing")
+	actor := fs.String("actor", "", "who is changing the status")
+	version := fs.Int("version", 0, "expected cage version (default current version)")
+	pos, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	cageID, err := parseID("cage id", pos[0])
+	if err != nil {
+		return err
+	}
+	change := das.StatusChange{Status: strings.ToUpper(pos[1]), Reason: *reason, Actor: *actor, Version: *version}
+	if change.Version == 0 {
+		cage, err := e.client.GetCage(ctx, cageID)
+		if err != nil {
+			return err
+		}
+		change.Version = cage.Version
+	}
+	if err := e.client.SetCageStatus(ctx, cageID, change); err != nil {
+		return err
+	}
+	cage, err := e.client.GetCage(ctx, cageID)
+	if err != nil {
+		return err
+	}
+	return writeCages(e.out, e.format, []das.Cage{cage})
+}
+
+// dinosaurs
+
+func dinoList(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("dino list", flag.ContinueOnError)
+	species := fs.String("species", "", "only list dinosaurs of this species")
+	cageID := fs.Int("cage", 0, "only list dinosaurs in this cage")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	var dinos []das.Dinosaur
+	var err error
+	if *cageID != 0 {
+		dinos, err = e.client.ListCageDinosaurs(ctx, *cageID)
+	} else {
+		dinos, err = e.client.ListDinosaurs(ctx, *species)
+	}
+	if err != nil {
+		return err
+	}
+	if *cageID != 0 && len(*species) != 0 {
+		filtered := dinos[:0]
+		for _, d := range dinos {
+			if strings.EqualFold(d.Species, *species) {
+				filtered = append(filtered, d)
+			}
+		}
+		dinos = filtered
+	}
+	return writeDinosaurs(e.out, e.format, dinos)
+}
+
+// add a dinosaur leaving the server to choose its cage
+func dinoAdd(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("dino add", flag.ContinueOnError)
+	strategy := fs.String("strategy", "", "placement strategy first-fit|best-fit|worst-fit|round-robin")
+	var opts client.PlacementOptions
+	fs.Func("auto-create", "create a cage when none have room true|false", func(s string) error {
+		v, err := strconv.ParseBool(s)
+		opts.AutoCreate = &v
+		return err
+	})
+	pos, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	opts.Strategy = *strategy
+	if err := e.client.AddDinosaur(ctx, das.Dinosaur{Species: pos[0], Name: pos[1]}, opts); err != nil {
+		return err
+	}
+	return e.done("dinosaur " + pos[1] + " added")
+}
+
+func dinoPlace(ctx context.Context, e *env, args []string) error {
+	pos, err := parseArgs(flag.NewFlagSet("dino place", flag.ContinueOnError), args, 3)
+	if err != nil {
+		return err
+	}
+	cageID, err := parseID("cage id", pos[0])
+	if err != nil {
+		return err
+	}
+	if err := e.client.PlaceDinosaur(ctx, cageID, das.Dinosaur{Species: pos[1], Name: pos[2]}); err != nil {
+		return err
+	}
+	return e.done(fmt.Sprintf("dinosaur %s placed in cage %d", pos[2], cageID))
+}
+
+// move a dinosaur to another cage
+// the current version is fetched unless one is given
+func dinoMove(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("dino move", flag.ContinueOnError)
+	version := fs.Int("version", 0, "expected dinosaur version (default current version)")
+	pos, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	dinoID, err := parseID("dinosaur id", pos[0])
+	if err != nil {
+		return err
+	}
+	cageID, err := parseID("cage id", pos[1])
+	if err != nil {
+		return err
+	}
+	if *version == 0 {
+		dino, err := e.client.GetDinosaur(ctx, dinoID)
+		if err != nil {
+			return err
+		}
+		*version = dino.Version
+	}
+	dino, err := e.client.MoveDinosaur(ctx, dinoID, *version, cageID)
+	if err != nil {
+		return err
+	}
+	return writeDinosaurs(e.out, e.format, []das.Dinosaur{dino})
+}
@@ -0,0 +1,180 @@
+// Command dinoctl manages species, cages and dinosaurs on a dinocage server.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"dinocage/client"
+)
+
+const (
+	DefaultServer = "http://localhost:8000"
+	EnvConfig     = "DINOCTL_CONFIG"
+	EnvServer     = "DINOCTL_SERVER"
+	EnvToken      = "DINOCTL_TOKEN"
+)
+
+// exit codes
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+	ExitConflict = 4
+)
+
+var errUsage = errors.New("usage")
+
+// settings read from the config file
+// anything given on the command line or in the environment takes precedence
+type Config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// location of the config file when none is named
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dinoctl", "config.json")
+}
+
+// read the config file at path
+// a missing file is only an error when it was asked for explicitly
+func LoadConfig(path string, explicit bool) (Config, error) {
+	var cfg Config
+	if len(path) == 0 {
+		return cfg, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("unable to read config : %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("unable to parse config %s : %w", path, err)
+	}
+	return cfg, nil
+}
+
+// the client and output settings shared by every command
+type env struct {
+	client *client.Client
+	out    io.Writer
+	format string
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: dinoctl [flags] <command> <action> [args]
+
+commands:
+  species list
+  species add <name> <H|C>
+  cage list [-status S]
+  cage add <H|C> [-capacity N]
+  cage show <cage id>
+  cage status <cage id> <status> [-reason R] [-actor A] [-version V]
+  dino list [-species S] [-cage N]
+  dino add <species> <name> [-strategy S] [-auto-create=true|false]
+  dino place <cage id> <species> <name>
+  dino move <dino id> <cage id> [-version V]
+
+flags:
+`)
+}
+
+// map an error to the exit code reported to the shell
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errUsage):
+		return ExitUsage
+	case errors.Is(err, client.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, client.ErrConflict), errors.Is(err, client.ErrVersionMismatch),
+		errors.Is(err, client.ErrPreconditionRequired), errors.Is(err, client.ErrUnprocessable):
+		return ExitConflict
+	}
+	return ExitError
+}
+
+// parse the global flags and run the command returning the exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dinoctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		usage(stderr)
+		fs.PrintDefaults()
+	}
+	server := fs.String("server", "", "server base url (default "+DefaultServer+")")
+	output := fs.String("output", "table", "output format table|json|csv")
+	configPath := fs.String("config", "", "config file holding server and token")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if !ValidFormat(*output) {
+		fmt.Fprintf(stderr, "unknown output format %s\n", *output)
+		return ExitUsage
+	}
+
+	path, explicit := *configPath, len(*configPath) != 0
+	if !explicit {
+		path, explicit = os.Getenv(EnvConfig), len(os.Getenv(EnvConfig)) != 0
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	cfg, err := LoadConfig(path, explicit)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+	for _, s := range []string{*server, os.Getenv(EnvServer), cfg.Server, DefaultServer} {
+		if len(s) != 0 {
+			cfg.Server = s
+			break
+		}
+	}
+	if t := os.Getenv(EnvToken); len(t) != 0 {
+		cfg.Token = t
+	}
+
+	c := client.New(cfg.Server)
+	if len(cfg.Token) != 0 {
+		c.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	e := &env{client: c, out: stdout, format: *output}
+
+	err = dispatch(ctx, e, fs.Args())
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(stderr, strings.TrimPrefix(err.Error(), errUsage.Error()+" : "))
+		fs.Usage()
+	} else if err != nil {
+		fmt.Fprintln(stderr, err)
+	}
+	return exitCode(err)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
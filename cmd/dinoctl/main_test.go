@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serve canned responses for the paths the tests use
+func testServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/cages", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":1,"status":"ACTIVE","capacity":4,"count":1,"kind":"C","version":2}]`))
+	})
+	mux.HandleFunc("GET /v1/cage/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "1" {
+			http.Error(w, "cage not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "missing token", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"id":1,"status":"ACTIVE","capacity":4,"count":1,"kind":"C","version":2}`))
+	})
+	mux.HandleFunc("GET /v1/cage/{id}/list_dinosaurs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":5,"species":"velociraptor","name":"blue","diet":"C","cage":1,"version":3}]`))
+	})
+	mux.HandleFunc("GET /v1/dino/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":5,"species":"velociraptor","name":"blue","diet":"C","cage":1,"version":3}`))
+	})
+	mux.HandleFunc("POST /v1/dino/{id}/move/{cage}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") != `"3"` {
+			http.Error(w, "version mismatch", http.StatusPreconditionFailed)
+			return
+		}
+		w.Write([]byte(`{"id":5,"species":"velociraptor","name":"blue","diet":"C","cage":2,"version":4}`))
+	})
+	svr := httptest.NewServer(mux)
+	t.Cleanup(svr.Close)
+	return svr
+}
+
+func runArgs(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestOutputFormats(t *testing.T) {
+	svr := testServer(t)
+	t.Setenv(EnvConfig, "")
+
+	code, out, _ := runArgs("--server", svr.URL, "cage", "list")
+	if code != ExitOK || !strings.HasPrefix(out, "ID  KIND  STATUS") || !strings.Contains(out, "1   C     ACTIVE") {
+		t.Errorf("unexpected table output %d %q", code, out)
+	}
+	code, out, _ = runArgs("--server", svr.URL, "--output", "csv", "cage", "list")
+	if code != ExitOK || out != "ID,KIND,STATUS,COUNT,CAPACITY,VERSION\n1,C,ACTIVE,1,4,2\n" {
+		t.Errorf("unexpected csv output %d %q", code, out)
+	}
+	code, out, _ = runArgs("--server", svr.URL, "--output", "json", "cage", "list")
+	if code != ExitOK || !strings.Contains(out, `"status": "ACTIVE"`) {
+		t.Errorf("unexpected json output %d %q", code, out)
+	}
+}
+
+func TestConfigAndExitCodes(t *testing.T) {
+	svr := testServer(t)
+	config := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(config, []byte(`{"server":"`+svr.URL+`","token":"secret"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvConfig, config)
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"cage", "show", "1"}, ExitOK},
+		{[]string{"cage", "show", "9"}, ExitNotFound},
+		{[]string{"dino", "move", "5", "2"}, ExitOK},
+		{[]string{"dino", "move", "5", "2", "-version", "1"}, ExitConflict},
+		{[]string{"dino", "move", "5"}, ExitUsage},
+		{[]string{"cage", "show", "x"}, ExitUsage},
+		{[]string{"cage", "fly"}, ExitUsage},
+		{[]string{"--output", "yaml", "cage", "list"}, ExitUsage},
+		{[]string{"--config", filepath.Join(t.TempDir(), "missing.json"), "cage", "list"}, ExitError},
+	}
+	for _, tc := range tests {
+		code, _, stderr := runArgs(tc.args...)
+		if code != tc.code {
+			t.Errorf("%v expected exit %d got %d %s", tc.args, tc.code, code, stderr)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"dinocage/das"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+)
+
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatTable, FormatJSON, FormatCSV:
+		return true
+	}
+	return false
+}
+
+// write a value in the requested format
+// json writes v as is while table and csv write the header and rows
+func write(w io.Writer, format string, v any, header []string, rows [][]string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeSpecies(w io.Writer, format string, species []das.Species) error {
+	rows := make([][]string, 0, len(species))
+	for _, s := range species {
+		rows = append(rows, []string{s.Name, s.Diet})
+	}
+	return write(w, format, species, []string{"NAME", "DIET"}, rows)
+}
+
+func writeCages(w io.Writer, format string, cages []das.Cage) error {
+	rows := make([][]string, 0, len(cages))
+	for _, c := range cages {
+		rows = append(rows, []string{
+			strconv.Itoa(c.ID), c.Kind, c.Status,
+			strconv.Itoa(c.Count), strconv.Itoa(c.Capacity), strconv.Itoa(c.Version),
+		})
+	}
+	return write(w, format, cages, []string{"ID", "KIND", "STATUS", "COUNT", "CAPACITY", "VERSION"}, rows)
+}
+
+func writeDinosaurs(w io.Writer, format string, dinos []das.Dinosaur) error {
+	rows := make([][]string, 0, len(dinos))
+	for _, d := range dinos {
+		rows = append(rows, []string{
+			strconv.Itoa(int(d.ID)), d.Name, d.Species, d.Diet,
+			strconv.Itoa(int(d.Cage)), strconv.Itoa(d.Version),
+		})
+	}
+	return write(w, format, dinos, []string{"ID", "NAME", "SPECIES", "DIET", "CAGE", "VERSION"}, rows)
+}
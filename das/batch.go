@@ -0,0 +1,37 @@
+package das
+
+import (
+	"context"
+)
+
+// batched reads used to resolve nested queries without a query per parent
+
+// return the cages with the given ids in id order
+func (repo *sqlRepo) GetCagesByID(ctx context.Context, cageIDs []int) ([]Cage, error) {
+	sqlStmt := `SELECT id, status, capacity, count, kind, version FROM cages WHERE ` + repo.d.anyOf("id", "$1") + ` ORDER BY id`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, repo.d.list(cageIDs))
+	if err != nil {
+		return nil, err
+	}
+	return scanCages(rows)
+}
+
+// return the dinosaurs held in any of the given cages
+func (repo *sqlRepo) GetDinosaursForCages(ctx context.Context, cageIDs []int) ([]Dinosaur, error) {
+	sqlStmt := `SELECT id, species, name, diet, cage, version FROM dinosaurs WHERE ` + repo.d.anyOf("cage", "$1") + ` ORDER BY id`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, repo.d.list(cageIDs))
+	if err != nil {
+		return nil, err
+	}
+	return scanDinosaurs(rows)
+}
+
+// return the dinosaurs of any of the given species
+func (repo *sqlRepo) GetDinosaursForSpecies(ctx context.Context, species []string) ([]Dinosaur, error) {
+	sqlStmt := `SELECT id, species, name, diet, cage, version FROM dinosaurs WHERE ` + repo.d.anyOf("lower(species)", "$1") + ` ORDER BY id`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, repo.d.list(species))
+	if err != nil {
+		return nil, err
+	}
+	return scanDinosaurs(rows)
+}
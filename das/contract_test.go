@@ -0,0 +1,465 @@
+package das
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// contract every data access provider must honour
+// sqlite always runs, postgres runs against the database named by
+// ENV_TEST_DB_NAME using the ENV_DB_* connection settings, whose tables are emptied first
+
+func readSchema(t *testing.T) string {
+	b, err := os.ReadFile(filepath.Join("..", "dataset", "init.sql"))
+	if err != nil {
+		t.Fatalf("unable to read schema %v", err)
+	}
+	return string(b)
+}
+
+func newSQLiteProvider(t *testing.T) DataAccessProvider {
+	dap, err := ConnectSQLite(filepath.Join(t.TempDir(), "dinocage.db"), readSchema(t))
+	if err != nil {
+		t.Fatalf("unable to open sqlite %v", err)
+	}
+	t.Cleanup(dap.Close)
+	return dap
+}
+
+func newPostgresProvider(t *testing.T) DataAccessProvider {
+	name := os.Getenv("ENV_TEST_DB_NAME")
+	if len(name) == 0 {
+		t.Skip("ENV_TEST_DB_NAME is not set")
+	}
+	dap, err := Connect(os.Getenv("ENV_DB_HOST"), os.Getenv("ENV_DB_PORT"), os.Getenv("ENV_DB_USR"), os.Getenv("ENV_DB_PWD"), name)
+	if err != nil {
+		t.Fatalf("unable to connect to postgres %v", err)
+	}
+	t.Cleanup(dap.Close)
+	_, err = dap.(*SQLDataProvider).db.Exec(`TRUNCATE species, cages, dinosaurs, cage_status_log, webhooks, webhook_deliveries, outbox RESTART IDENTITY`)
+	if err != nil {
+		t.Fatalf("unable to empty postgres %v", err)
+	}
+	_, err = dap.(*SQLDataProvider).db.Exec(`UPDATE outbox_clock SET tick = 0`)
+	if err != nil {
+		t.Fatalf("unable to reset the event clock %v", err)
+	}
+	return dap
+}
+
+var providers = map[string]func(t *testing.T) DataAccessProvider{
+	"sqlite":   newSQLiteProvider,
+	"postgres": newPostgresProvider,
+}
+
+func forEachProvider(t *testing.T, test func(t *testing.T, dap DataAccessProvider)) {
+	for name, open := range providers {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+func TestContractRepositories(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		h, err := dap.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 2, Kind: HerbivoreCode})
+		if err != nil || h.ID == 0 || h.Version != 1 {
+			t.Fatalf("insert cage returned %+v %v", h, err)
+		}
+		c, err := dap.InsertCage(ctx, Cage{Status: StatusDown, Capacity: 4, Kind: CarnivoreCode})
+		if err != nil {
+			t.Fatalf("insert cage failed %v", err)
+		}
+
+		h.Count = 1
+		h, err = dap.SaveCage(ctx, h)
+		if err != nil || h.Count != 1 || h.Version != 2 {
+			t.Errorf("save cage returned %+v %v", h, err)
+		}
+		d, err := dap.InsertDinosaur(ctx, Dinosaur{Species: "stegosaurus", Name: "steggy", Diet: HerbivoreCode, Cage: uint(h.ID)})
+		if err != nil || d.ID == 0 {
+			t.Fatalf("insert dinosaur returned %+v %v", d, err)
+		}
+		d.Name = "stella"
+		d, err = dap.SaveDinosaur(ctx, d)
+		if err != nil || d.Name != "stella" || d.Version != 2 {
+			t.Errorf("save dinosaur returned %+v %v", d, err)
+		}
+
+		// a row saved at a version it has moved on from is left unchanged
+		stale := h
+		stale.Version = 1
+		if _, err := dap.SaveCage(ctx, stale); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("expected a stale cage to be refused got %v", err)
+		}
+		if _, err := dap.SaveCage(ctx, Cage{ID: h.ID + c.ID, Status: StatusActive, Capacity: 1, Version: 1}); !errors.Is(err, ErrCageNotFound) {
+			t.Errorf("expected an unknown cage got %v", err)
+		}
+		staleDino := d
+		staleDino.Version, staleDino.Name = 1, "steggy"
+		if _, err := dap.SaveDinosaur(ctx, staleDino); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("expected a stale dinosaur to be refused got %v", err)
+		}
+		if got, _ := dap.GetDinosaur(ctx, int(d.ID)); got != d {
+			t.Errorf("expected the dinosaur unchanged got %+v", got)
+		}
+
+		active, err := dap.GetCages(ctx, StatusActive)
+		if err != nil || len(active) != 1 || active[0].ID != h.ID {
+			t.Errorf("active cages returned %+v %v", active, err)
+		}
+		found, err := dap.FindCages(ctx, StatusDown, CarnivoreCode)
+		if err != nil || len(found) != 1 || found[0].ID != c.ID {
+			t.Errorf("find cages returned %+v %v", found, err)
+		}
+		byID, err := dap.GetCagesByID(ctx, []int{c.ID, h.ID})
+		if err != nil || len(byID) != 2 || byID[0].ID != h.ID {
+			t.Errorf("cages by id returned %+v %v", byID, err)
+		}
+		dinos, err := dap.GetDinosaursForCages(ctx, []int{h.ID, c.ID})
+		if err != nil || len(dinos) != 1 || dinos[0] != d {
+			t.Errorf("dinosaurs for cages returned %+v %v", dinos, err)
+		}
+		dinos, err = dap.GetDinosaursForSpecies(ctx, []string{"stegosaurus"})
+		if err != nil || len(dinos) != 1 {
+			t.Errorf("dinosaurs for species returned %+v %v", dinos, err)
+		}
+		dinos, err = dap.GetDinosaurs(ctx, "velociraptor")
+		if err != nil || len(dinos) != 0 {
+			t.Errorf("dinosaurs of another species returned %+v %v", dinos, err)
+		}
+
+		err = dap.AddStatusTransition(ctx, StatusTransition{Cage: c.ID, From: StatusActive, To: StatusDown, Reason: "repairs", Actor: "ops"})
+		if err != nil {
+			t.Fatalf("add status transition failed %v", err)
+		}
+		history, err := dap.GetCageStatusHistory(ctx, c.ID)
+		if err != nil || len(history) != 1 || history[0].Reason != "repairs" || history[0].ChangedAt.IsZero() {
+			t.Errorf("status history returned %+v %v", history, err)
+		}
+
+		if err := dap.RemoveDinosaur(ctx, int(d.ID)); err != nil {
+			t.Errorf("remove dinosaur failed %v", err)
+		}
+		if _, err := dap.GetDinosaur(ctx, int(d.ID)); !errors.Is(err, ErrDinosaurNotFound) {
+			t.Errorf("expected removed dinosaur to be missing got %v", err)
+		}
+		if err := dap.RemoveCage(ctx, c.ID); err != nil {
+			t.Errorf("remove cage failed %v", err)
+		}
+		if err := dap.RemoveCage(ctx, c.ID); !errors.Is(err, ErrCageNotFound) {
+			t.Errorf("expected removed cage to be missing got %v", err)
+		}
+	})
+}
+
+func TestContractAtomic(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		failed := errors.New("failed")
+		err := dap.Atomic(ctx, func(tx Tx) error {
+			cage, err := tx.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 1, Kind: HerbivoreCode})
+			if err != nil {
+				return err
+			}
+			if err := tx.RecordEvent(ctx, EventCageCreated, cage.ID, cage); err != nil {
+				return err
+			}
+			return failed
+		})
+		if err != failed {
+			t.Errorf("expected the unit of work error got %v", err)
+		}
+		cages, _ := dap.GetCages(ctx)
+		events, _ := dap.PendingEvents(ctx, 10)
+		if len(cages) != 0 || len(events) != 0 {
+			t.Errorf("expected a rollback got %+v %+v", cages, events)
+		}
+
+		err = dap.Atomic(ctx, func(tx Tx) error {
+			cage, err := tx.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 1, Kind: HerbivoreCode})
+			if err != nil {
+				return err
+			}
+			return tx.RecordEvent(ctx, EventCageCreated, cage.ID, cage)
+		})
+		if err != nil {
+			t.Fatalf("unit of work failed %v", err)
+		}
+		events, err = dap.PendingEvents(ctx, 10)
+		if err != nil || len(events) != 1 || events[0].Type != EventCageCreated || events[0].CreatedAt.IsZero() {
+			t.Fatalf("pending events returned %+v %v", events, err)
+		}
+		if err := dap.MarkDispatched(ctx, events[0].ID); err != nil {
+			t.Errorf("mark dispatched failed %v", err)
+		}
+		if events, _ := dap.PendingEvents(ctx, 10); len(events) != 0 {
+			t.Errorf("expected no pending events got %+v", events)
+		}
+
+		// the clock counts dispatched events too so other instances see every change
+		tick, err := dap.EventClock(ctx)
+		if err != nil || tick != 1 {
+			t.Errorf("event clock returned %d %v", tick, err)
+		}
+		if err := dap.RecordEvent(ctx, EventSpeciesAdded, 0, Species{Name: "t-rex", Diet: CarnivoreCode}); err != nil {
+			t.Fatalf("record event failed %v", err)
+		}
+		// a unit of work that fails leaves the clock alone
+		dap.Atomic(ctx, func(tx Tx) error {
+			if err := tx.RecordEvent(ctx, EventSpeciesRemoved, 0, Species{Name: "t-rex"}); err != nil {
+				return err
+			}
+			return ErrNotEmpty
+		})
+		if tick, err := dap.EventClock(ctx); err != nil || tick != 2 {
+			t.Errorf("event clock returned %d %v expected 2", tick, err)
+		}
+
+		// committed events are followed in commit order by their tick
+		committed, err := dap.CommittedEvents(ctx, EventCursor{}, 10)
+		if err != nil || len(committed) != 2 || committed[0].Tick != 1 || committed[1].Tick != 2 || committed[1].Type != EventSpeciesAdded {
+			t.Fatalf("committed events returned %+v %v", committed, err)
+		}
+		committed, err = dap.CommittedEvents(ctx, committed[0].Cursor(), 10)
+		if err != nil || len(committed) != 1 || committed[0].Type != EventSpeciesAdded {
+			t.Errorf("committed events after the first returned %+v %v", committed, err)
+		}
+
+		// only events dispatched before the retention are pruned
+		if num, err := dap.PruneDispatched(ctx, time.Hour); err != nil || num != 0 {
+			t.Errorf("prune of recent events returned %d %v", num, err)
+		}
+		_, err = dap.(*SQLDataProvider).db.Exec(`UPDATE outbox SET dispatched_at = '2000-01-01 00:00:00' WHERE dispatched_at IS NOT NULL`)
+		if err != nil {
+			t.Fatalf("unable to age events %v", err)
+		}
+		if num, err := dap.PruneDispatched(ctx, time.Hour); err != nil || num != 1 {
+			t.Errorf("prune of old events returned %d %v", num, err)
+		}
+		if events, _ := dap.PendingEvents(ctx, 10); len(events) != 1 || events[0].Type != EventSpeciesAdded {
+			t.Errorf("expected the undispatched event kept got %+v", events)
+		}
+	})
+}
+
+// concurrent units of work filling a cage must never overfill it
+func TestContractConcurrentPlacement(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		cage, err := dap.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 5, Kind: CarnivoreCode})
+		if err != nil {
+			t.Fatalf("insert cage failed %v", err)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- dap.Atomic(ctx, func(tx Tx) error {
+					c, err := tx.GetCage(ctx, cage.ID)
+					if err != nil {
+						return err
+					}
+					if c.Count >= c.Capacity {
+						return ErrNoCapacity
+					}
+					c.Count++
+					if _, err := tx.SaveCage(ctx, c); err != nil {
+						return err
+					}
+					_, err = tx.InsertDinosaur(ctx, Dinosaur{Species: "velociraptor", Name: fmt.Sprintf("raptor%d", i), Diet: CarnivoreCode, Cage: uint(c.ID)})
+					return err
+				})
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		placed := 0
+		for err := range errs {
+			switch {
+			case err == nil:
+				placed++
+			case !errors.Is(err, ErrNoCapacity):
+				t.Errorf("unexpected placement error %v", err)
+			}
+		}
+		cage, _ = dap.GetCage(ctx, cage.ID)
+		dinos, _ := dap.GetDinosaursForCage(ctx, cage.ID)
+		if placed != 5 || cage.Count != 5 || len(dinos) != 5 {
+			t.Errorf("placed %d with count %d and %d dinosaurs", placed, cage.Count, len(dinos))
+		}
+	})
+}
+
+func TestContractSavepoint(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		boom := errors.New("boom")
+		err := dap.Atomic(ctx, func(tx Tx) error {
+			err := tx.Savepoint(ctx, func() error {
+				_, err := tx.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 2, Kind: CarnivoreCode})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			// only the failed savepoint is undone
+			err = tx.Savepoint(ctx, func() error {
+				if _, err := tx.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 3, Kind: CarnivoreCode}); err != nil {
+					return err
+				}
+				return boom
+			})
+			if !errors.Is(err, boom) {
+				return fmt.Errorf("savepoint returned %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unit of work failed %v", err)
+		}
+		cages, err := dap.GetCages(ctx)
+		if err != nil || len(cages) != 1 || cages[0].Capacity != 2 {
+			t.Errorf("expected only the first cage to be kept got %+v %v", cages, err)
+		}
+	})
+}
+
+func TestContractSpecies(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		raptor := Species{Name: "velociraptor", Diet: CarnivoreCode, Period: "cretaceous", WeightKg: 15.5, DangerLevel: 4, MaxPerCage: 2, Habitat: "scrub"}
+		if err := dap.SaveSpecies(ctx, raptor); err != nil {
+			t.Fatalf("save species failed %v", err)
+		}
+		for _, name := range []string{"stegosaurus", "ankylosaurus"} {
+			if err := dap.SaveSpecies(ctx, Species{Name: name, Diet: HerbivoreCode}); err != nil {
+				t.Fatalf("save species failed %v", err)
+			}
+		}
+		// saving again replaces the details
+		raptor.MaxPerCage = 3
+		if err := dap.SaveSpecies(ctx, raptor); err != nil {
+			t.Fatalf("save species failed %v", err)
+		}
+		err := dap.Atomic(ctx, func(tx Tx) error {
+			s, err := tx.GetSpecies(ctx, "velociraptor")
+			if err != nil || s != raptor {
+				t.Errorf("get species returned %+v %v", s, err)
+			}
+			return tx.RemoveSpecies(ctx, "stegosaurus")
+		})
+		if err != nil {
+			t.Fatalf("remove species failed %v", err)
+		}
+		species, err := dap.ListSpecies(ctx)
+		if err != nil || len(species) != 2 || species[0].Name != "ankylosaurus" || species[1] != raptor {
+			t.Errorf("list species returned %+v %v", species, err)
+		}
+		if _, err := dap.GetSpecies(ctx, "stegosaurus"); !errors.Is(err, ErrSpeciesNotFound) {
+			t.Errorf("expected a removed species to be missing got %v", err)
+		}
+		if err := dap.RemoveSpecies(ctx, "stegosaurus"); !errors.Is(err, ErrSpeciesNotFound) {
+			t.Errorf("expected removing a missing species to fail got %v", err)
+		}
+	})
+}
+
+func TestContractRestore(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		snap := Snapshot{
+			Species:   []Species{{Name: "stegosaurus", Diet: HerbivoreCode}},
+			Cages:     []Cage{{ID: 7, Status: StatusActive, Capacity: 3, Count: 1, Kind: HerbivoreCode}},
+			Dinosaurs: []Dinosaur{{ID: 4, Species: "stegosaurus", Name: "Steggy", Diet: HerbivoreCode, Cage: 7, Version: 2}},
+		}
+		load := func(snap Snapshot) error {
+			return dap.Atomic(ctx, func(tx Tx) error {
+				return tx.LoadSnapshot(ctx, snap)
+			})
+		}
+		if err := load(snap); err != nil {
+			t.Fatalf("restore failed %v", err)
+		}
+		cage, err := dap.GetCage(ctx, 7)
+		if err != nil || cage.Count != 1 || cage.Version != 1 {
+			t.Errorf("restored cage is %+v %v", cage, err)
+		}
+		dino, err := dap.GetDinosaur(ctx, 4)
+		if err != nil || dino.Version != 2 || dino.Name != "Steggy" {
+			t.Errorf("restored dinosaur is %+v %v", dino, err)
+		}
+		// new rows follow the restored ids
+		next, err := dap.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 1, Kind: HerbivoreCode})
+		if err != nil || next.ID <= 7 {
+			t.Errorf("cage inserted after restore is %+v %v", next, err)
+		}
+
+		taken, err := dap.Snapshot(ctx)
+		if err != nil || len(taken.Species) != 1 || len(taken.Cages) != 2 || len(taken.Dinosaurs) != 1 {
+			t.Fatalf("snapshot returned %+v %v", taken, err)
+		}
+		if err := load(taken); !errors.Is(err, ErrNotEmpty) {
+			t.Errorf("expected restore into a used database to fail got %v", err)
+		}
+	})
+}
+
+func TestContractWebhooks(t *testing.T) {
+	forEachProvider(t, func(t *testing.T, dap DataAccessProvider) {
+		ctx := context.Background()
+		id, err := dap.AddWebhook(ctx, Webhook{URL: "http://example.com/hook", Events: []string{EventCageCreated}, Secret: "s"})
+		if err != nil {
+			t.Fatalf("add webhook failed %v", err)
+		}
+		hooks, err := dap.ListWebhooks(ctx)
+		if err != nil || len(hooks) != 1 || !hooks[0].Wants(EventCageCreated) || hooks[0].CreatedAt.IsZero() {
+			t.Errorf("list webhooks returned %+v %v", hooks, err)
+		}
+		did, err := dap.AddDelivery(ctx, Delivery{Webhook: id, EventID: 1, EventType: EventCageCreated, Payload: "{}", Status: DeliveryPending})
+		if err != nil {
+			t.Fatalf("add delivery failed %v", err)
+		}
+		// one claim at a time until the attempt is recorded
+		claimed, ok, err := dap.ClaimDelivery(ctx, did, time.Minute)
+		if err != nil || !ok || claimed.ID != did || claimed.Payload != "{}" {
+			t.Errorf("claim delivery returned %+v %v %v", claimed, ok, err)
+		}
+		if _, ok, err := dap.ClaimDelivery(ctx, did, time.Minute); err != nil || ok {
+			t.Errorf("expected a claimed delivery to be refused got %v %v", ok, err)
+		}
+		err = dap.UpdateDelivery(ctx, Delivery{ID: did, Status: DeliveryPending, Attempts: 1, ResponseCode: 500, LastError: "boom"})
+		if err != nil {
+			t.Errorf("update delivery failed %v", err)
+		}
+		if claimed, ok, err := dap.ClaimDelivery(ctx, did, time.Minute); err != nil || !ok || claimed.Attempts != 1 {
+			t.Errorf("expected a released delivery to be claimed again got %+v %v %v", claimed, ok, err)
+		}
+		err = dap.UpdateDelivery(ctx, Delivery{ID: did, Status: DeliveryDead, Attempts: 2, ResponseCode: 500, LastError: "boom"})
+		if err != nil {
+			t.Errorf("update delivery failed %v", err)
+		}
+		if _, ok, err := dap.ClaimDelivery(ctx, did, 0); err != nil || ok {
+			t.Errorf("expected a dead delivery to be refused got %v %v", ok, err)
+		}
+		deliveries, err := dap.ListDeliveries(ctx, id, DeliveryDead)
+		if err != nil || len(deliveries) != 1 || deliveries[0].ResponseCode != 500 || deliveries[0].UpdatedAt.IsZero() {
+			t.Errorf("list deliveries returned %+v %v", deliveries, err)
+		}
+		if err := dap.DeleteWebhook(ctx, id); err != nil {
+			t.Errorf("delete webhook failed %v", err)
+		}
+		if deliveries, _ := dap.ListDeliveries(ctx, id, ""); len(deliveries) != 0 {
+			t.Errorf("expected deliveries to be removed with the webhook got %+v", deliveries)
+		}
+	})
+}
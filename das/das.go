@@ -2,238 +2,214 @@ package das
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"database/sql"
 	_ "github.com/lib/pq"
 )
 
-// TODO: MAP DB LEVEL ERRORS TO APP LEVEL ERRORS
-// poorly organised monolithic data access interface
-// should make more modular
-type DataAccessProvider interface {
-	NewCage(ctx context.Context, cap int, kind string) (int, error)
-	AddDinosaur(ctx context.Context, d Dinosaur) error
-	PlaceDinosaurInCage(ctx context.Context, cageID int, d Dinosaur) error
+// app level errors that handlers can map to status codes
+var (
+	ErrCageNotFound      = errors.New("cage not found")
+	ErrIllegalTransition = errors.New("illegal cage status transition")
+	ErrCageNotEmpty      = errors.New("cage is not empty")
+	ErrDinosaurNotFound  = errors.New("dinosaur not found")
+	ErrVersionMismatch   = errors.New("version has changed")
+)
+
+// persistence for webhook subscriptions and their delivery log
+type WebhookStore interface {
+	AddWebhook(ctx context.Context, wh Webhook) (int, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	AddDelivery(ctx context.Context, d Delivery) (int, error)
+	ClaimDelivery(ctx context.Context, id int, lease time.Duration) (Delivery, bool, error)
+	UpdateDelivery(ctx context.Context, d Delivery) error
+	ListDeliveries(ctx context.Context, webhookID int, status string) ([]Delivery, error)
+}
+
+// transactional outbox of events awaiting dispatch
+type OutboxStore interface {
+	RecordEvent(ctx context.Context, kind string, cage int, data any) error
+	PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+	MarkDispatched(ctx context.Context, id uint64) error
+	PruneDispatched(ctx context.Context, age time.Duration) (int64, error)
+	CommittedEvents(ctx context.Context, after EventCursor, limit int) ([]OutboxEvent, error)
+	EventClock(ctx context.Context) (uint64, error)
+}
+
+// cage persistence without any park rules
+// inside a unit of work the single cage reads lock the rows they return
+type CageRepo interface {
+	InsertCage(ctx context.Context, cage Cage) (Cage, error)
+	GetCage(ctx context.Context, cageID int) (Cage, error)
 	GetCages(ctx context.Context, optStatus ...string) ([]Cage, error)
-	GetDinosaursForCage(ctx context.Context, cageID int) ([]Dinosaur, error)
+	GetCagesByID(ctx context.Context, cageIDs []int) ([]Cage, error)
+	FindCages(ctx context.Context, status, kind string) ([]Cage, error)
+	SaveCage(ctx context.Context, cage Cage) (Cage, error)
+	RemoveCage(ctx context.Context, cageID int) error
+	AddStatusTransition(ctx context.Context, st StatusTransition) error
+	GetCageStatusHistory(ctx context.Context, cageID int) ([]StatusTransition, error)
+}
+
+// dinosaur persistence without any park rules
+// inside a unit of work the single dinosaur reads lock the rows they return
+type DinoRepo interface {
+	InsertDinosaur(ctx context.Context, d Dinosaur) (Dinosaur, error)
+	GetDinosaur(ctx context.Context, dinoID int) (Dinosaur, error)
 	GetDinosaurs(ctx context.Context, opts ...string) ([]Dinosaur, error)
-	SetCageStatus(ctx context.Context, cageID int, status string) error
-	Close()
+	GetDinosaursForCage(ctx context.Context, cageID int) ([]Dinosaur, error)
+	GetDinosaursForCages(ctx context.Context, cageIDs []int) ([]Dinosaur, error)
+	GetDinosaursForSpecies(ctx context.Context, species []string) ([]Dinosaur, error)
+	SaveDinosaur(ctx context.Context, d Dinosaur) (Dinosaur, error)
+	RemoveDinosaur(ctx context.Context, dinoID int) error
 }
 
-type PsqlDataProvider struct {
-	db *sql.DB
+// species persistence keyed on lower case name
+// inside a unit of work GetSpecies locks the row it returns so a species
+// cannot be removed or change its diet while dinosaurs of it are added
+type SpeciesRepo interface {
+	GetSpecies(ctx context.Context, name string) (Species, error)
+	ListSpecies(ctx context.Context) ([]Species, error)
+	SaveSpecies(ctx context.Context, s Species) error
+	RemoveSpecies(ctx context.Context, name string) error
 }
 
-// connect to database and return a data access object
-func Connect(host, port, user, pwd, dbname string) (DataAccessProvider, error) {
-	psqlConnStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, pwd, dbname)
-	db, err := sql.Open("postgres", psqlConnStr)
-	if err != nil {
-		return nil, err
-	}
-	err = db.Ping()
-	if err != nil {
-		return nil, err
-	}
-	return &PsqlDataProvider{db: db}, nil
+// repositories bound to a single transaction along with its outbox
+type Tx interface {
+	CageRepo
+	DinoRepo
+	SpeciesRepo
+	RecordEvent(ctx context.Context, kind string, cage int, data any) error
+	// run fn in a savepoint undoing only its changes if it fails
+	// fn's error is returned unless the savepoint itself fails
+	Savepoint(ctx context.Context, fn func() error) error
+	// fail with ErrNotEmpty if there are any cages or dinosaurs
+	CheckEmpty(ctx context.Context) error
+	// insert the rows of a snapshot keeping their ids, counts and versions
+	// and saving its species, failing with ErrNotEmpty unless there are no
+	// cages or dinosaurs
+	LoadSnapshot(ctx context.Context, snap Snapshot) error
 }
 
-func (pdb *PsqlDataProvider) Close() {
-	pdb.db.Close()
+// runs fn in a transaction committing only if it returns nil
+type UnitOfWork interface {
+	Atomic(ctx context.Context, fn func(tx Tx) error) error
 }
 
-// place dinosaur in a given cage
-// this does not enforce constraints correctly and my be subject to race condition
-// probably requires row level locking and transactional integrity
-func (pdb *PsqlDataProvider) PlaceDinosaurInCage(ctx context.Context, cageID int, d Dinosaur) error {
-	err := pdb.CheckCage(ctx, cageID, d.Diet)
-	if err != nil {
-		return err
-	}
-	sqlStmt := `INSERT INTO dinosaurs (species, name, diet, cage) VALUES ($1, $2, $3, $4)`
-	_, err = pdb.db.Exec(sqlStmt, strings.ToLower(d.Species), strings.ToLower(d.Name), d.Diet, cageID)
-	return err
+// consistent reads spanning every row
+type BulkStore interface {
+	Snapshot(ctx context.Context) (Snapshot, error)
 }
 
-// check if cage has capacity and meets dietary requirements
-func (pdb *PsqlDataProvider) CheckCage(ctx context.Context, cageID int, diet string) error {
-	sqlStmt := `UPDATE cages SET  count=count+1 WHERE id = $1 AND kind = $2 AND count < capacity`
-	res, err := pdb.db.ExecContext(ctx, sqlStmt, cageID, diet)
-	if err != nil {
-		return err
-	}
-	num, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if num == 1 {
-		return nil
-	}
-	return fmt.Errorf("unable to place dino in cage %d", cageID)
+// TODO: MAP DB LEVEL ERRORS TO APP LEVEL ERRORS
+// every store backed by the one database
+type DataAccessProvider interface {
+	CageRepo
+	DinoRepo
+	SpeciesRepo
+	UnitOfWork
+	BulkStore
+	WebhookStore
+	OutboxStore
+	Close()
 }
 
-func (pdb *PsqlDataProvider) AddDinosaur(ctx context.Context, d Dinosaur) error {
-	// this could be a code const but is probably ok for the demo
-	id, err := pdb.GetFreeCage(ctx, d.Diet)
-	if err != nil {
-		return err
-	}
-	// this is a replica of above - consolidate
-	sqlStmt := `INSERT INTO dinosaurs (species, name, diet, cage) VALUES ($1, $2, $3, $4)`
-	_, err = pdb.db.Exec(sqlStmt, strings.ToLower(d.Species), strings.ToLower(d.Name), d.Diet, id)
-	return err
+// statements may run on the database or within a transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-// create a new cage of given capacity and diet
-func (pdb *PsqlDataProvider) NewCage(ctx context.Context, cap int, kind string) (int, error) {
-	if cap < 1 {
-		return -1, fmt.Errorf("cage capacity < 1 not permitted")
-	}
-	sqlStmt := `INSERT INTO cages (status, capacity, count, kind) VALUES ($1, $2, $3, $4) RETURNING id`
-	var id int
-	err := pdb.db.QueryRow(sqlStmt, StatusActive, cap, 0, kind).Scan(&id)
-	return id, err
-}
-
-// get a free cage of the required type
-// if none is available then create a new one
-// this suffers from referential integrity problems
-// requires row level locking and transaction integrity being enforced
-func (pdb *PsqlDataProvider) GetFreeCage(ctx context.Context, diet string) (int, error) {
-	sqlStmt := `SELECT id FROM cages WHERE ( count < capacity ) AND status = "ACTIVE" AND kind = $1 FOR UPDATE`
-	rows, err := pdb.db.QueryContext(ctx, sqlStmt, diet)
+// data access over postgres or sqlite
+// both share the same statements with the differences held in a dialect
+// repository reads outside a unit of work may be served by a replica,
+// transactions and the other stores always use the primary db
+type SQLDataProvider struct {
+	sqlRepo
+	db *sql.DB
+	rt *router
+}
+
+func newSQLDataProvider(d *dialect, db *sql.DB, replicas ...*sql.DB) *SQLDataProvider {
+	rt := newRouter(db, replicas...)
+	return &SQLDataProvider{sqlRepo: sqlRepo{q: rt, d: d}, db: db, rt: rt}
+}
+
+// connect to database and return a data access object
+// replicas are host:port addresses of read only copies sharing the credentials
+func Connect(host, port, user, pwd, dbname string, replicas ...string) (DataAccessProvider, error) {
+	db, err := openPostgres(host, port, user, pwd, dbname)
 	if err != nil {
-		log.Printf("GetFreeCage() : %v", err)
-		return 0, err
-	}
-	var id int
-	count := 0
-	for rows.Next() {
-		err := rows.Scan(&id)
-		if err != nil {
-			return 0, err
+		if db != nil {
+			db.Close()
 		}
-		count++
+		return nil, err
 	}
-	if count == 0 {
-		id, err = pdb.NewCage(ctx, CageCapacity, diet)
+	var replicaDBs []*sql.DB
+	for _, addr := range replicas {
+		rhost, rport, found := strings.Cut(addr, ":")
+		if !found {
+			rport = port
+		}
+		rdb, err := openPostgres(rhost, rport, user, pwd, dbname)
+		if rdb == nil {
+			newSQLDataProvider(&postgres, db, replicaDBs...).Close()
+			return nil, err
+		}
 		if err != nil {
-			return 0, err
+			// an unreachable replica is skipped until it answers
+			log.Printf("replica %s unavailable : %v", addr, err)
 		}
+		replicaDBs = append(replicaDBs, rdb)
 	}
-	err = pdb.PlaceInCage(ctx, id)
-	return id, nil
-}
-
-// return persisted cages
-func (pdb *PsqlDataProvider) GetCages(ctx context.Context, optStatus ...string) ([]Cage, error) {
-	var cages []Cage
-	var opts []interface{}
-	sqlStmt := `SELECT id, status, capacity, count, kind FROM cages`
-	if len(optStatus) != 0 {
-		sqlStmt = sqlStmt + ` WHERE status = $1`
-		opts = append(opts, &optStatus[0])
-	}
-	rows, err := pdb.db.QueryContext(ctx, sqlStmt, opts...)
+	return newSQLDataProvider(&postgres, db, replicaDBs...), nil
+}
+
+func openPostgres(host, port, user, pwd, dbname string) (*sql.DB, error) {
+	psqlConnStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, pwd, dbname)
+	db, err := sql.Open("postgres", psqlConnStr)
 	if err != nil {
-		return cages, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		cage := Cage{}
-		err := rows.Scan(&cage.ID, &cage.Status, &cage.Capacity, &cage.Count, &cage.Kind)
-		if err != nil {
-			return cages, err
-		}
-		cages = append(cages, cage)
+		return nil, err
 	}
-	return cages, nil
+	return db, db.Ping()
 }
 
-func (pdb *PsqlDataProvider) PlaceInCage(ctx context.Context, id int) error {
-	sqlStmt := `UPDATE cages SET count = count + 1 WHERE id = $1`
-	_, err := pdb.db.ExecContext(ctx, sqlStmt, id)
-	return err
+func (pdb *SQLDataProvider) Close() {
+	pdb.rt.Close()
 }
 
-func (pdb *PsqlDataProvider) GetDinosaursForCage(ctx context.Context, cageID int) ([]Dinosaur, error) {
-	var dinos []Dinosaur
-	sqlStmt := `SELECT id, species, name, diet, cage FROM dinosaurs WHERE cage=$1`
-	rows, err := pdb.db.QueryContext(ctx, sqlStmt, cageID)
-	if err != nil {
-		return dinos, err
+// begin a transaction on the primary
+// a read write transaction pins later reads of the session to the primary
+func (pdb *SQLDataProvider) begin(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if opts == nil || !opts.ReadOnly {
+		markWritten(ctx)
 	}
-	defer rows.Close()
-	for rows.Next() {
-		dino := Dinosaur{}
-		err := rows.Scan(&dino.ID, &dino.Name, &dino.Diet, &dino.Cage)
-		if err != nil {
-			return dinos, err
-		}
-		dinos = append(dinos, dino)
-	}
-	return dinos, nil
+	return pdb.db.BeginTx(ctx, opts)
 }
 
-func (pdb *PsqlDataProvider) GetDinosaurs(ctx context.Context, species ...string) ([]Dinosaur, error) {
-	var dinos []Dinosaur
-	var opts []interface{}
-	sqlStmt := `SELECT id, species, name, diet, cage FROM dinosaurs`
-	if len(species) != 0 {
-		sqlStmt = sqlStmt + ` WHERE species = $1`
-		opts = append(opts, &species[0])
-	}
-	log.Printf("sql : %v", sqlStmt)
-	rows, err := pdb.db.QueryContext(ctx, sqlStmt, opts...)
+// run fn against repositories sharing one transaction
+func (pdb *SQLDataProvider) Atomic(ctx context.Context, fn func(tx Tx) error) error {
+	tx, err := pdb.begin(ctx, nil)
 	if err != nil {
-		log.Printf("err : %v", err)
-		return dinos, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		dino := Dinosaur{}
-		err := rows.Scan(&dino.ID, &dino.Species, &dino.Name, &dino.Diet, &dino.Cage)
-		if err != nil {
-			return dinos, err
-		}
-		dinos = append(dinos, dino)
-	}
-	return dinos, nil
-}
-
-func (pdb *PsqlDataProvider) CheckCageDiet(ctx context.Context, cageID int, diet string) (bool, error) {
-	sqlStmt := `SELECT count(*) FROM cage WHERE id = $1 AND diet=$2`
-	var count int
-	err := pdb.db.QueryRowContext(ctx, sqlStmt, cageID, diet).Scan(&count)
-	switch {
-	case err == sql.ErrNoRows:
-		return false, nil
-	case err != nil:
-		return false, err
+		return err
 	}
-	return true, nil
-}
-
-func (pdb *PsqlDataProvider) SetCageStatus(ctx context.Context, cageID int, status string) error {
-	sqlStmt := `UPDATE cages SET status = $1 WHERE id = $2`
-	if status == StatusDown {
-		// ensure that if we power down a cage then it must not be empty
-		sqlStmt = sqlStmt + ` AND count = 0`
+	defer tx.Rollback()
+	stx := &sqlTx{sqlRepo: sqlRepo{q: tx, d: pdb.d, lock: pdb.d.lock}}
+	err = fn(stx)
+	if err != nil {
+		return err
 	}
-	log.Printf("sql : %s", sqlStmt)
-	resp, err := pdb.db.ExecContext(ctx, sqlStmt, status, cageID)
-	if resp != nil {
-		num, err := resp.RowsAffected()
+	if len(stx.events) != 0 {
+		err = tickClockTx(ctx, tx, pdb.d, stx.events)
 		if err != nil {
 			return err
 		}
-		if num == 0 {
-			return fmt.Errorf("cage status unchanged")
-		}
 	}
-	return err
+	return tx.Commit()
 }
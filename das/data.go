@@ -1,22 +1,50 @@
 package das
 
+import (
+	"strings"
+	"time"
+)
+
 const (
 	Herbivore     = "herbivore"
 	HerbivoreCode = "H"
 	Carnivore     = "carnivore"
 	CarnivoreCode = "C"
-	StatusDown    = "DOWN"
-	StatusActive  = "ACTIVE"
+
+	StatusDown           = "DOWN"
+	StatusActive         = "ACTIVE"
+	StatusMaintenance    = "MAINTENANCE"
+	StatusLockdown       = "LOCKDOWN"
+	StatusDecommissioned = "DECOMMISSIONED"
 
 	CageCapacity = 20
+
+	PeriodTriassic   = "triassic"
+	PeriodJurassic   = "jurassic"
+	PeriodCretaceous = "cretaceous"
+
+	MaxDangerLevel = 5
 )
 
+// a species and the details the park rules and reports use
+// zero values are unknown, a zero max per cage has no limit beyond the capacity
+type Species struct {
+	Name        string  `json:"name"`
+	Diet        string  `json:"diet"`
+	Period      string  `json:"period,omitempty"`
+	WeightKg    float64 `json:"weight_kg,omitempty"`
+	DangerLevel int     `json:"danger_level,omitempty"`
+	MaxPerCage  int     `json:"max_per_cage,omitempty"`
+	Habitat     string  `json:"habitat,omitempty"`
+}
+
 type Dinosaur struct {
 	ID      uint   `json:"id"`
 	Species string `json:"species"`
 	Name    string `json:"name"`
 	Diet    string `json:"diet"`
 	Cage    uint   `json:"cage"`
+	Version int    `json:"version"`
 }
 
 type Cage struct {
@@ -25,14 +53,80 @@ type Cage struct {
 	Capacity int    `json:"capacity"`
 	Count    int    `json:"count"`
 	Kind     string `json:"kind"`
+	Version  int    `json:"version"`
+}
+
+// requested change of cage status along with who asked for it and why
+// a non zero Version must match the cage version for the change to apply
+type StatusChange struct {
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Actor   string `json:"actor"`
+	Version int    `json:"-"`
+}
+
+// a recorded cage status transition
+type StatusTransition struct {
+	Cage      int       `json:"cage"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// permitted cage status transitions keyed on the current status
+// DECOMMISSIONED is terminal
+var statusTransitions = map[string][]string{
+	StatusActive:         {StatusDown, StatusMaintenance, StatusLockdown},
+	StatusDown:           {StatusActive, StatusMaintenance, StatusDecommissioned},
+	StatusMaintenance:    {StatusActive, StatusDown, StatusDecommissioned},
+	StatusLockdown:       {StatusActive, StatusMaintenance},
+	StatusDecommissioned: {},
 }
 
 func ValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// check if a cage may move from one status to another
+func ValidTransition(from, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// check a diet code is herbivore or carnivore
+func ValidDiet(diet string) bool {
+	diet = strings.ToUpper(diet)
+	return diet == HerbivoreCode || diet == CarnivoreCode
+}
+
+// check a period is one of the mesozoic periods
+func ValidPeriod(period string) bool {
+	switch strings.ToLower(period) {
+	case PeriodTriassic, PeriodJurassic, PeriodCretaceous:
+		return true
+	default:
+		return false
+	}
+}
+
+// only cages that are powered and not otherwise restricted accept new dinosaurs
+func AdmitsPlacement(status string) bool {
+	return status == StatusActive
+}
+
+// a cage must be emptied before it can enter these states
+func RequiresEmpty(status string) bool {
 	switch status {
-	case StatusDown, StatusActive:
+	case StatusDown, StatusDecommissioned:
 		return true
 	default:
 		return false
 	}
-	return false
 }
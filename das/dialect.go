@@ -0,0 +1,80 @@
+package das
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/lib/pq"
+)
+
+// the parts of a statement that differ between the supported databases
+type dialect struct {
+	// appended to reads that hold their rows until the transaction ends
+	lock string
+	// the current time
+	now string
+	// the time a number of seconds given by a parameter before now
+	ago func(param string) string
+	// the larger of two values
+	greatest string
+	// match a column against a list parameter
+	anyOf func(col, param string) string
+	// bind a slice as a list parameter
+	list func(v any) any
+	// move the id sequence of a table past rows inserted with explicit ids
+	resequence func(table string) string
+}
+
+var postgres = dialect{
+	lock:     " FOR UPDATE",
+	now:      "now()",
+	greatest: "GREATEST",
+	ago: func(param string) string {
+		return "now() - make_interval(secs => " + param + ")"
+	},
+	anyOf: func(col, param string) string {
+		return col + " = ANY(" + param + ")"
+	},
+	list: func(v any) any {
+		return pq.Array(v)
+	},
+	resequence: func(table string) string {
+		return fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %s`, table, table)
+	},
+}
+
+// sqlite has no row locks, every unit of work instead takes the database
+// write lock when it begins so units of work run one at a time
+// autoincrement ids already follow the largest id inserted
+var sqlite = dialect{
+	lock:     "",
+	now:      "CURRENT_TIMESTAMP",
+	greatest: "MAX",
+	ago: func(param string) string {
+		return "datetime('now', '-' || " + param + " || ' seconds')"
+	},
+	anyOf: func(col, param string) string {
+		return col + " IN (SELECT value FROM json_each(" + param + "))"
+	},
+	list: func(v any) any {
+		b, _ := json.Marshal(v)
+		return string(b)
+	},
+	resequence: func(table string) string {
+		return ""
+	},
+}
+
+var (
+	serialColumn = regexp.MustCompile(`(?i)\b(big)?serial( PRIMARY KEY)?`)
+	timestampTZ  = regexp.MustCompile(`(?i)\bTIMESTAMPTZ\b`)
+	nowCall      = regexp.MustCompile(`(?i)\bnow\(\)`)
+)
+
+// translate the postgres schema to sqlite so both share one set of migrations
+func sqliteSchema(ddl string) string {
+	ddl = serialColumn.ReplaceAllString(ddl, "INTEGER PRIMARY KEY AUTOINCREMENT")
+	ddl = timestampTZ.ReplaceAllString(ddl, "TIMESTAMP")
+	return nowCall.ReplaceAllString(ddl, "CURRENT_TIMESTAMP")
+}
@@ -0,0 +1,36 @@
+package das
+
+import (
+	"errors"
+)
+
+var ErrNoCapacity = errors.New("no cage capacity available")
+
+// options controlling a cage evacuation
+type EvacuateOptions struct {
+	DryRun      bool
+	CreateCages bool
+	PowerDown   bool
+	Reason      string
+	Actor       string
+}
+
+// a single planned or applied move of a dinosaur between cages
+// NewCage is set when the destination is a cage created for the move
+type Relocation struct {
+	Dinosaur uint   `json:"dinosaur"`
+	Name     string `json:"name"`
+	Species  string `json:"species"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	NewCage  bool   `json:"new_cage"`
+}
+
+// result of an evacuation request
+type EvacuationPlan struct {
+	Cage        int          `json:"cage"`
+	DryRun      bool         `json:"dry_run"`
+	Relocations []Relocation `json:"relocations"`
+	NewCages    int          `json:"new_cages"`
+	PoweredDown bool         `json:"powered_down"`
+}
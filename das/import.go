@@ -0,0 +1,39 @@
+package das
+
+const (
+	ImportSpecies  = "species"
+	ImportCage     = "cage"
+	ImportDinosaur = "dinosaur"
+)
+
+// a single row of a bulk import or export
+// the fields used depend on Type and ID is only used when restoring a snapshot
+type ImportRecord struct {
+	Type     string `json:"type"`
+	ID       int    `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Species  string `json:"species,omitempty"`
+	Diet     string `json:"diet,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Capacity int    `json:"capacity,omitempty"`
+	Cage     int    `json:"cage,omitempty"`
+}
+
+// options controlling a bulk import
+// in best effort mode failed rows are skipped rather than aborting the import
+// and a validate only import checks every row then rolls all of them back
+type ImportOptions struct {
+	BestEffort   bool
+	ValidateOnly bool
+	Placement    PlacementOptions
+}
+
+// outcome of an individual import row
+type ImportResult struct {
+	Row   int    `json:"row"`
+	Type  string `json:"type"`
+	OK    bool   `json:"ok"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,173 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventDinoAdded         = "dino.added"
+	EventDinoPlaced        = "dino.placed"
+	EventDinoRenamed       = "dino.renamed"
+	EventDinoRemoved       = "dino.removed"
+	EventCageCreated       = "cage.created"
+	EventCageStatusChanged = "cage.status_changed"
+	EventCageFull          = "cage.full"
+	EventCageResized       = "cage.resized"
+	EventCageDeleted       = "cage.deleted"
+	EventSpeciesAdded      = "species.added"
+	EventSpeciesUpdated    = "species.updated"
+	EventSpeciesRemoved    = "species.removed"
+)
+
+// all published event types
+var EventTypes = []string{
+	EventDinoAdded, EventDinoPlaced, EventDinoRenamed, EventDinoRemoved,
+	EventCageCreated, EventCageStatusChanged, EventCageFull, EventCageResized, EventCageDeleted,
+	EventSpeciesAdded, EventSpeciesUpdated, EventSpeciesRemoved,
+}
+
+func ValidEventType(kind string) bool {
+	for _, t := range EventTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// an event recorded in the outbox awaiting dispatch
+// tick is the event clock of the transaction that committed it
+type OutboxEvent struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	Cage      int             `json:"cage,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	Tick      uint64          `json:"tick"`
+}
+
+// a position in the outbox in commit order
+type EventCursor struct {
+	Tick uint64
+	ID   uint64
+}
+
+// the position of an event in commit order
+func (e OutboxEvent) Cursor() EventCursor {
+	return EventCursor{Tick: e.Tick, ID: e.ID}
+}
+
+// record an event in the outbox as part of a mutation transaction returning its id
+func writeOutboxTx(ctx context.Context, tx queryer, kind string, cage int, data any) (uint64, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	var id uint64
+	sqlStmt := `INSERT INTO outbox (event_type, cage, payload) VALUES ($1, $2, $3) RETURNING id`
+	err = tx.QueryRowContext(ctx, sqlStmt, kind, cage, string(payload)).Scan(&id)
+	return id, err
+}
+
+// move the event clock just before a transaction that recorded events commits
+// and stamp its events with the new tick
+// the clock row stays locked until the commit so the next writer only reads
+// the tick once this one is visible, and taking it last keeps it from being
+// held while the transaction waits on other rows
+func tickClockTx(ctx context.Context, tx queryer, d *dialect, events []uint64) error {
+	var tick uint64
+	sqlStmt := `UPDATE outbox_clock SET tick = tick + 1 WHERE id = 1 RETURNING tick`
+	err := tx.QueryRowContext(ctx, sqlStmt).Scan(&tick)
+	if err != nil {
+		return err
+	}
+	sqlStmt = `UPDATE outbox SET tick = $1 WHERE ` + d.anyOf("id", "$2")
+	_, err = tx.ExecContext(ctx, sqlStmt, tick, d.list(events))
+	return err
+}
+
+// record an event for a change that is not held in the database
+func (pdb *SQLDataProvider) RecordEvent(ctx context.Context, kind string, cage int, data any) error {
+	tx, err := pdb.begin(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	id, err := writeOutboxTx(ctx, tx, kind, cage, data)
+	if err != nil {
+		return err
+	}
+	err = tickClockTx(ctx, tx, pdb.d, []uint64{id})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// return up to limit undispatched events oldest first
+func (pdb *SQLDataProvider) PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	sqlStmt := `SELECT id, event_type, cage, payload, created_at, COALESCE(tick, 0) FROM outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT $1`
+	rows, err := pdb.db.QueryContext(ctx, sqlStmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutbox(rows)
+}
+
+// return up to limit events committed after the cursor in commit order
+// whether dispatched or not so every instance can follow every change
+func (pdb *SQLDataProvider) CommittedEvents(ctx context.Context, after EventCursor, limit int) ([]OutboxEvent, error) {
+	sqlStmt := `SELECT id, event_type, cage, payload, created_at, tick FROM outbox WHERE tick > $1 OR (tick = $1 AND id > $2) ORDER BY tick, id LIMIT $3`
+	rows, err := pdb.db.QueryContext(ctx, sqlStmt, after.Tick, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutbox(rows)
+}
+
+// remove events dispatched longer than age ago returning how many were removed
+func (pdb *SQLDataProvider) PruneDispatched(ctx context.Context, age time.Duration) (int64, error) {
+	sqlStmt := `DELETE FROM outbox WHERE dispatched_at IS NOT NULL AND dispatched_at < ` + pdb.d.ago("$1")
+	res, err := pdb.db.ExecContext(ctx, sqlStmt, int64(age/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// a count of the committed transactions that recorded events, it only moves in
+// commit order unlike event ids which are taken before commit and may become
+// visible out of order, so every instance can tell when another made a change
+func (pdb *SQLDataProvider) EventClock(ctx context.Context) (uint64, error) {
+	var tick uint64
+	sqlStmt := `SELECT tick FROM outbox_clock WHERE id = 1`
+	err := pdb.db.QueryRowContext(ctx, sqlStmt).Scan(&tick)
+	return tick, err
+}
+
+// read all outbox rows closing the result set
+func scanOutbox(rows *sql.Rows) ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	defer rows.Close()
+	for rows.Next() {
+		e := OutboxEvent{}
+		var payload string
+		err := rows.Scan(&e.ID, &e.Type, &e.Cage, &payload, &e.CreatedAt, &e.Tick)
+		if err != nil {
+			return events, err
+		}
+		e.Payload = json.RawMessage(payload)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// mark an event as delivered to every sink
+func (pdb *SQLDataProvider) MarkDispatched(ctx context.Context, id uint64) error {
+	sqlStmt := `UPDATE outbox SET dispatched_at = ` + pdb.d.now + ` WHERE id = $1`
+	_, err := pdb.db.ExecContext(ctx, sqlStmt, id)
+	return err
+}
@@ -0,0 +1,85 @@
+package das
+
+import (
+	"sync"
+)
+
+const (
+	StrategyFirstFit   = "first-fit"
+	StrategyBestFit    = "best-fit"
+	StrategyWorstFit   = "worst-fit"
+	StrategyRoundRobin = "round-robin"
+
+	DefaultStrategy = StrategyFirstFit
+)
+
+// options controlling automatic placement of a dinosaur
+type PlacementOptions struct {
+	Strategy   string
+	AutoCreate bool
+}
+
+func DefaultPlacement() PlacementOptions {
+	return PlacementOptions{Strategy: DefaultStrategy, AutoCreate: true}
+}
+
+func ValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyFirstFit, StrategyBestFit, StrategyWorstFit, StrategyRoundRobin:
+		return true
+	default:
+		return false
+	}
+}
+
+// pick a cage from candidates ordered by id
+// last is the previously chosen cage and is only used for round robin
+func SelectCage(strategy string, candidates []Cage, last int) (Cage, bool) {
+	if len(candidates) == 0 {
+		return Cage{}, false
+	}
+	pick := 0
+	switch strategy {
+	case StrategyBestFit:
+		for i, c := range candidates {
+			if c.Capacity-c.Count < candidates[pick].Capacity-candidates[pick].Count {
+				pick = i
+			}
+		}
+	case StrategyWorstFit:
+		for i, c := range candidates {
+			if c.Capacity-c.Count > candidates[pick].Capacity-candidates[pick].Count {
+				pick = i
+			}
+		}
+	case StrategyRoundRobin:
+		for i, c := range candidates {
+			if c.ID > last {
+				pick = i
+				break
+			}
+		}
+	}
+	return candidates[pick], true
+}
+
+// tracks the last cage chosen per diet for round robin placement
+type RoundRobin struct {
+	mu   sync.Mutex
+	last map[string]int
+}
+
+func (rr *RoundRobin) Get(diet string) int {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.last[diet]
+}
+
+func (rr *RoundRobin) Set(diet string, id int) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if rr.last == nil {
+		rr.last = make(map[string]int)
+	}
+	rr.last[diet] = id
+}
@@ -0,0 +1,33 @@
+package das
+
+import (
+	"testing"
+)
+
+func TestSelectCage(t *testing.T) {
+	candidates := []Cage{
+		{ID: 2, Capacity: 10, Count: 5},
+		{ID: 4, Capacity: 10, Count: 9},
+		{ID: 6, Capacity: 10, Count: 1},
+	}
+	tests := []struct {
+		strategy string
+		last     int
+		want     int
+	}{
+		{StrategyFirstFit, 0, 2},
+		{StrategyBestFit, 0, 4},
+		{StrategyWorstFit, 0, 6},
+		{StrategyRoundRobin, 2, 4},
+		{StrategyRoundRobin, 6, 2},
+	}
+	for _, tc := range tests {
+		cage, ok := SelectCage(tc.strategy, candidates, tc.last)
+		if !ok || cage.ID != tc.want {
+			t.Errorf("%s after %d picked %d expected %d", tc.strategy, tc.last, cage.ID, tc.want)
+		}
+	}
+	if _, ok := SelectCage(StrategyFirstFit, nil, 0); ok {
+		t.Errorf("expected no cage from an empty candidate list")
+	}
+}
@@ -0,0 +1,27 @@
+package das
+
+const (
+	RebalanceConsolidate = "consolidate"
+	RebalanceSpread      = "spread"
+)
+
+// options controlling an occupancy rebalance
+// Target is the fraction of capacity each cage should be filled to when spreading
+type RebalanceOptions struct {
+	Mode   string
+	Target float64
+	DryRun bool
+}
+
+// result of a rebalance request
+type RebalancePlan struct {
+	Mode         string       `json:"mode"`
+	Target       float64      `json:"target,omitempty"`
+	DryRun       bool         `json:"dry_run"`
+	Relocations  []Relocation `json:"relocations"`
+	CagesEmptied []int        `json:"cages_emptied,omitempty"`
+}
+
+func ValidRebalanceMode(mode string) bool {
+	return mode == RebalanceConsolidate || mode == RebalanceSpread
+}
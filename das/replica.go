@@ -0,0 +1,129 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// how long a failed replica is left out before reads are tried on it again
+var ReplicaRetry = 5 * time.Second
+
+// a read only copy of the primary that may lag behind it
+type replica struct {
+	db *sql.DB
+	// unix nanos before which the replica is not used, zero while healthy
+	downUntil atomic.Int64
+}
+
+func (rep *replica) healthy(now time.Time) bool {
+	return now.UnixNano() >= rep.downUntil.Load()
+}
+
+// routes reads to the replicas in turn and everything else to the primary
+// reads are retried on the primary when every healthy replica fails
+type router struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+func newRouter(primary *sql.DB, replicas ...*sql.DB) *router {
+	r := &router{primary: primary}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, &replica{db: db})
+	}
+	return r
+}
+
+// a plain select can be answered by a replica, anything returning rows
+// from a write has to run on the primary
+func isRead(query string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT")
+}
+
+func (r *router) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	markWritten(ctx)
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+// single row queries are only used to insert so always go to the primary
+func (r *router) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if !isRead(query) {
+		markWritten(ctx)
+	}
+	return r.primary.QueryRowContext(ctx, query, args...)
+}
+
+func (r *router) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if !isRead(query) {
+		markWritten(ctx)
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+	if len(r.replicas) == 0 || pinned(ctx) {
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+	now := time.Now()
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		idx := (int(start) + i) % len(r.replicas)
+		rep := r.replicas[idx]
+		if !rep.healthy(now) {
+			continue
+		}
+		rows, err := rep.db.QueryContext(ctx, query, args...)
+		if err == nil {
+			if rep.downUntil.Swap(0) != 0 {
+				log.Printf("replica %d is back in use", idx)
+			}
+			return rows, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("replica %d failed, reading from primary for %v : %v", idx, ReplicaRetry, err)
+		rep.downUntil.Store(now.Add(ReplicaRetry).UnixNano())
+	}
+	return r.primary.QueryContext(ctx, query, args...)
+}
+
+func (r *router) Close() {
+	for _, rep := range r.replicas {
+		rep.db.Close()
+	}
+	r.primary.Close()
+}
+
+// tracks whether the request behind a context has written to the primary
+type session struct {
+	wrote atomic.Bool
+}
+
+type sessionKey struct{}
+
+// start a session in which reads go to the primary once anything is written
+// so a request always reads its own writes
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// send every read made with the returned context to the primary
+func OnPrimary(ctx context.Context) context.Context {
+	s := &session{}
+	s.wrote.Store(true)
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+func markWritten(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+}
+
+func pinned(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && s.wrote.Load()
+}
@@ -0,0 +1,144 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// a sqlite primary with one replica file that is only changed through the returned handle
+// so the replica lags the primary until a test copies rows across
+func newReplicatedProvider(t *testing.T) (*SQLDataProvider, *sql.DB) {
+	schema := readSchema(t)
+	dir := t.TempDir()
+	replicaPath := filepath.Join(dir, "replica.db")
+	rdap, err := ConnectSQLite(replicaPath, schema)
+	if err != nil {
+		t.Fatalf("unable to create replica %v", err)
+	}
+	replica := rdap.(*SQLDataProvider).db
+	t.Cleanup(rdap.Close)
+
+	dap, err := ConnectSQLite(filepath.Join(dir, "primary.db"), schema, replicaPath)
+	if err != nil {
+		t.Fatalf("unable to open primary %v", err)
+	}
+	t.Cleanup(dap.Close)
+	return dap.(*SQLDataProvider), replica
+}
+
+func countCages(t *testing.T, ctx context.Context, dap DataAccessProvider) int {
+	cages, err := dap.GetCages(ctx)
+	if err != nil {
+		t.Fatalf("get cages failed %v", err)
+	}
+	return len(cages)
+}
+
+func TestIsRead(t *testing.T) {
+	cases := map[string]bool{
+		`SELECT id FROM cages`:                         true,
+		"\n\tselect id FROM cages":                     true,
+		`INSERT INTO cages (status) VALUES ($1)`:       false,
+		`UPDATE cages SET count = 1 RETURNING id`:      false,
+		`DELETE FROM dinosaurs WHERE id = $1`:          false,
+		`SELECT id FROM cages WHERE id = 1 FOR UPDATE`: true,
+	}
+	for query, want := range cases {
+		if isRead(query) != want {
+			t.Errorf("isRead(%q) should be %v", query, want)
+		}
+	}
+}
+
+func TestReplicaServesReads(t *testing.T) {
+	dap, replica := newReplicatedProvider(t)
+	ctx := context.Background()
+	_, err := dap.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 2, Kind: HerbivoreCode})
+	if err != nil {
+		t.Fatalf("insert cage failed %v", err)
+	}
+	if n := countCages(t, ctx, dap); n != 0 {
+		t.Errorf("read should come from the lagging replica, got %d cages", n)
+	}
+	if n := countCages(t, OnPrimary(ctx), dap); n != 1 {
+		t.Errorf("read pinned to the primary should see the new cage, got %d", n)
+	}
+	_, err = replica.Exec(`INSERT INTO cages (status, capacity, count, kind) VALUES ('ACTIVE', 2, 0, 'H'), ('ACTIVE', 2, 0, 'H')`)
+	if err != nil {
+		t.Fatalf("unable to seed replica %v", err)
+	}
+	if n := countCages(t, ctx, dap); n != 2 {
+		t.Errorf("read should come from the replica, got %d cages", n)
+	}
+}
+
+func TestSessionReadsOwnWrites(t *testing.T) {
+	dap, _ := newReplicatedProvider(t)
+	ctx := WithSession(context.Background())
+	if n := countCages(t, ctx, dap); n != 0 {
+		t.Fatalf("expected an empty replica, got %d cages", n)
+	}
+	_, err := dap.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 2, Kind: HerbivoreCode})
+	if err != nil {
+		t.Fatalf("insert cage failed %v", err)
+	}
+	if n := countCages(t, ctx, dap); n != 1 {
+		t.Errorf("session should read its own write from the primary, got %d cages", n)
+	}
+	if n := countCages(t, context.Background(), dap); n != 0 {
+		t.Errorf("reads outside the session should still use the replica, got %d cages", n)
+	}
+
+	ctx = WithSession(context.Background())
+	err = dap.Atomic(ctx, func(tx Tx) error {
+		_, err := tx.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 2, Kind: CarnivoreCode})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("atomic insert failed %v", err)
+	}
+	if n := countCages(t, ctx, dap); n != 2 {
+		t.Errorf("a unit of work should pin the session to the primary, got %d cages", n)
+	}
+}
+
+func TestReplicaFallback(t *testing.T) {
+	dap, replica := newReplicatedProvider(t)
+	ctx := context.Background()
+	_, err := dap.InsertCage(ctx, Cage{Status: StatusActive, Capacity: 2, Kind: HerbivoreCode})
+	if err != nil {
+		t.Fatalf("insert cage failed %v", err)
+	}
+	// break the replica
+	_, err = replica.Exec(`ALTER TABLE cages RENAME TO cages_hidden`)
+	if err != nil {
+		t.Fatalf("unable to break replica %v", err)
+	}
+	if n := countCages(t, ctx, dap); n != 1 {
+		t.Errorf("failed replica read should fall back to the primary, got %d cages", n)
+	}
+	if dap.rt.replicas[0].healthy(time.Now()) {
+		t.Errorf("failed replica should be marked down")
+	}
+
+	// repaired but still within the retry window
+	_, err = replica.Exec(`ALTER TABLE cages_hidden RENAME TO cages`)
+	if err != nil {
+		t.Fatalf("unable to repair replica %v", err)
+	}
+	if n := countCages(t, ctx, dap); n != 1 {
+		t.Errorf("replica should be left out until the retry window ends, got %d cages", n)
+	}
+
+	// retry window ends
+	dap.rt.replicas[0].downUntil.Store(time.Now().Add(-time.Second).UnixNano())
+	if n := countCages(t, ctx, dap); n != 0 {
+		t.Errorf("recovered replica should serve reads again, got %d cages", n)
+	}
+	if !dap.rt.replicas[0].healthy(time.Now()) || dap.rt.replicas[0].downUntil.Load() != 0 {
+		t.Errorf("recovered replica should be marked healthy")
+	}
+}
@@ -0,0 +1,308 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// cage and dinosaur repositories over the database or a transaction
+// lock is appended to single row reads so a transaction holds the rows it reads
+type sqlRepo struct {
+	q    queryer
+	d    *dialect
+	lock string
+}
+
+// repositories bound to a transaction
+// events holds the ids of the events written so the event clock moves on commit
+type sqlTx struct {
+	sqlRepo
+	events []uint64
+}
+
+func (tx *sqlTx) RecordEvent(ctx context.Context, kind string, cage int, data any) error {
+	id, err := writeOutboxTx(ctx, tx.q, kind, cage, data)
+	if err != nil {
+		return err
+	}
+	tx.events = append(tx.events, id)
+	return nil
+}
+
+// savepoints share one name as each is released or rolled back before the next
+func (tx *sqlTx) Savepoint(ctx context.Context, fn func() error) error {
+	_, err := tx.q.ExecContext(ctx, `SAVEPOINT bulk_row`)
+	if err != nil {
+		return err
+	}
+	fnErr := fn()
+	if fnErr == nil {
+		_, err = tx.q.ExecContext(ctx, `RELEASE SAVEPOINT bulk_row`)
+		return err
+	}
+	_, err = tx.q.ExecContext(ctx, `ROLLBACK TO SAVEPOINT bulk_row`)
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+func insertCage(ctx context.Context, q queryer, cage Cage) (Cage, error) {
+	cage.Version = 1
+	sqlStmt := `INSERT INTO cages (status, capacity, count, kind) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := q.QueryRowContext(ctx, sqlStmt, cage.Status, cage.Capacity, cage.Count, cage.Kind).Scan(&cage.ID)
+	return cage, err
+}
+
+func insertDinosaur(ctx context.Context, q queryer, d Dinosaur) (Dinosaur, error) {
+	d.Version = 1
+	sqlStmt := `INSERT INTO dinosaurs (species, name, diet, cage) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := q.QueryRowContext(ctx, sqlStmt, d.Species, d.Name, d.Diet, d.Cage).Scan(&d.ID)
+	return d, err
+}
+
+func addStatusTransition(ctx context.Context, q queryer, st StatusTransition) error {
+	sqlStmt := `INSERT INTO cage_status_log (cage, from_status, to_status, reason, actor) VALUES ($1, $2, $3, $4, $5)`
+	_, err := q.ExecContext(ctx, sqlStmt, st.Cage, st.From, st.To, st.Reason, st.Actor)
+	return err
+}
+
+// read all cage rows closing the result set
+func scanCages(rows *sql.Rows) ([]Cage, error) {
+	var cages []Cage
+	defer rows.Close()
+	for rows.Next() {
+		cage := Cage{}
+		err := rows.Scan(&cage.ID, &cage.Status, &cage.Capacity, &cage.Count, &cage.Kind, &cage.Version)
+		if err != nil {
+			return cages, err
+		}
+		cages = append(cages, cage)
+	}
+	return cages, rows.Err()
+}
+
+// read all dinosaur rows closing the result set
+func scanDinosaurs(rows *sql.Rows) ([]Dinosaur, error) {
+	var dinos []Dinosaur
+	defer rows.Close()
+	for rows.Next() {
+		dino := Dinosaur{}
+		err := rows.Scan(&dino.ID, &dino.Species, &dino.Name, &dino.Diet, &dino.Cage, &dino.Version)
+		if err != nil {
+			return dinos, err
+		}
+		dinos = append(dinos, dino)
+	}
+	return dinos, rows.Err()
+}
+
+// cages
+
+// persist a new cage returning it with its id
+func (repo *sqlRepo) InsertCage(ctx context.Context, cage Cage) (Cage, error) {
+	return insertCage(ctx, repo.q, cage)
+}
+
+// return a single cage
+func (repo *sqlRepo) GetCage(ctx context.Context, cageID int) (Cage, error) {
+	sqlStmt := `SELECT id, status, capacity, count, kind, version FROM cages WHERE id = $1` + repo.lock
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, cageID)
+	if err != nil {
+		return Cage{}, err
+	}
+	cages, err := scanCages(rows)
+	if err != nil {
+		return Cage{}, err
+	}
+	if len(cages) == 0 {
+		return Cage{}, fmt.Errorf("cage %d : %w", cageID, ErrCageNotFound)
+	}
+	return cages[0], nil
+}
+
+// return persisted cages
+func (repo *sqlRepo) GetCages(ctx context.Context, optStatus ...string) ([]Cage, error) {
+	var cages []Cage
+	var opts []interface{}
+	sqlStmt := `SELECT id, status, capacity, count, kind, version FROM cages`
+	if len(optStatus) != 0 {
+		sqlStmt = sqlStmt + ` WHERE status = $1`
+		opts = append(opts, &optStatus[0])
+	}
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, opts...)
+	if err != nil {
+		return cages, err
+	}
+	return scanCages(rows)
+}
+
+// return the cages of a status and kind in id order
+func (repo *sqlRepo) FindCages(ctx context.Context, status, kind string) ([]Cage, error) {
+	sqlStmt := `SELECT id, status, capacity, count, kind, version FROM cages WHERE status = $1 AND kind = $2 ORDER BY id` + repo.lock
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, status, kind)
+	if err != nil {
+		return nil, err
+	}
+	return scanCages(rows)
+}
+
+// persist the status, capacity and count of a cage bumping its version
+// the cage must still be at the version it was read at
+func (repo *sqlRepo) SaveCage(ctx context.Context, cage Cage) (Cage, error) {
+	sqlStmt := `UPDATE cages SET status = $1, capacity = $2, count = $3, version = version + 1 WHERE id = $4 AND version = $5 RETURNING id, status, capacity, count, kind, version`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, cage.Status, cage.Capacity, cage.Count, cage.ID, cage.Version)
+	if err != nil {
+		return Cage{}, err
+	}
+	cages, err := scanCages(rows)
+	if err != nil {
+		return Cage{}, err
+	}
+	if len(cages) == 0 {
+		return Cage{}, repo.staleVersion(ctx, "cages", "cage", cage.ID, cage.Version, ErrCageNotFound)
+	}
+	return cages[0], nil
+}
+
+// explain why an update at a version changed no row
+// either the row is gone or it has moved on from the version
+func (repo *sqlRepo) staleVersion(ctx context.Context, table, kind string, id, version int, notFound error) error {
+	var current int
+	err := repo.q.QueryRowContext(ctx, `SELECT version FROM `+table+` WHERE id = $1`, id).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%s %d : %w", kind, id, notFound)
+	case err != nil:
+		return err
+	}
+	return fmt.Errorf("%s %d is at version %d not %d : %w", kind, id, current, version, ErrVersionMismatch)
+}
+
+func (repo *sqlRepo) RemoveCage(ctx context.Context, cageID int) error {
+	sqlStmt := `DELETE FROM cages WHERE id = $1`
+	res, err := repo.q.ExecContext(ctx, sqlStmt, cageID)
+	if err != nil {
+		return err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return fmt.Errorf("cage %d : %w", cageID, ErrCageNotFound)
+	}
+	return nil
+}
+
+func (repo *sqlRepo) AddStatusTransition(ctx context.Context, st StatusTransition) error {
+	return addStatusTransition(ctx, repo.q, st)
+}
+
+// return the recorded status transitions for a cage oldest first
+func (repo *sqlRepo) GetCageStatusHistory(ctx context.Context, cageID int) ([]StatusTransition, error) {
+	var history []StatusTransition
+	sqlStmt := `SELECT cage, from_status, to_status, reason, actor, changed_at FROM cage_status_log WHERE cage = $1 ORDER BY id`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, cageID)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		st := StatusTransition{}
+		err := rows.Scan(&st.Cage, &st.From, &st.To, &st.Reason, &st.Actor, &st.ChangedAt)
+		if err != nil {
+			return history, err
+		}
+		history = append(history, st)
+	}
+	return history, nil
+}
+
+// dinosaurs
+
+// persist a new dinosaur in its cage returning it with its id
+func (repo *sqlRepo) InsertDinosaur(ctx context.Context, d Dinosaur) (Dinosaur, error) {
+	return insertDinosaur(ctx, repo.q, d)
+}
+
+// return a single dinosaur
+func (repo *sqlRepo) GetDinosaur(ctx context.Context, dinoID int) (Dinosaur, error) {
+	sqlStmt := `SELECT id, species, name, diet, cage, version FROM dinosaurs WHERE id = $1` + repo.lock
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, dinoID)
+	if err != nil {
+		return Dinosaur{}, err
+	}
+	dinos, err := scanDinosaurs(rows)
+	if err != nil {
+		return Dinosaur{}, err
+	}
+	if len(dinos) == 0 {
+		return Dinosaur{}, fmt.Errorf("dinosaur %d : %w", dinoID, ErrDinosaurNotFound)
+	}
+	return dinos[0], nil
+}
+
+func (repo *sqlRepo) GetDinosaurs(ctx context.Context, species ...string) ([]Dinosaur, error) {
+	var dinos []Dinosaur
+	var opts []interface{}
+	sqlStmt := `SELECT id, species, name, diet, cage, version FROM dinosaurs`
+	if len(species) != 0 {
+		sqlStmt = sqlStmt + ` WHERE species = $1`
+		opts = append(opts, &species[0])
+	}
+	log.Printf("sql : %v", sqlStmt)
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, opts...)
+	if err != nil {
+		log.Printf("err : %v", err)
+		return dinos, err
+	}
+	return scanDinosaurs(rows)
+}
+
+func (repo *sqlRepo) GetDinosaursForCage(ctx context.Context, cageID int) ([]Dinosaur, error) {
+	var dinos []Dinosaur
+	sqlStmt := `SELECT id, species, name, diet, cage, version FROM dinosaurs WHERE cage = $1 ORDER BY id`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, cageID)
+	if err != nil {
+		return dinos, err
+	}
+	return scanDinosaurs(rows)
+}
+
+// persist the name and cage of a dinosaur bumping its version
+// the dinosaur must still be at the version it was read at
+func (repo *sqlRepo) SaveDinosaur(ctx context.Context, d Dinosaur) (Dinosaur, error) {
+	sqlStmt := `UPDATE dinosaurs SET name = $1, cage = $2, version = version + 1 WHERE id = $3 AND version = $4 RETURNING id, species, name, diet, cage, version`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, d.Name, d.Cage, d.ID, d.Version)
+	if err != nil {
+		return Dinosaur{}, err
+	}
+	dinos, err := scanDinosaurs(rows)
+	if err != nil {
+		return Dinosaur{}, err
+	}
+	if len(dinos) == 0 {
+		return Dinosaur{}, repo.staleVersion(ctx, "dinosaurs", "dinosaur", int(d.ID), d.Version, ErrDinosaurNotFound)
+	}
+	return dinos[0], nil
+}
+
+func (repo *sqlRepo) RemoveDinosaur(ctx context.Context, dinoID int) error {
+	sqlStmt := `DELETE FROM dinosaurs WHERE id = $1`
+	res, err := repo.q.ExecContext(ctx, sqlStmt, dinoID)
+	if err != nil {
+		return err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return fmt.Errorf("dinosaur %d : %w", dinoID, ErrDinosaurNotFound)
+	}
+	return nil
+}
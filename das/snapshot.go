@@ -0,0 +1,114 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotEmpty = errors.New("database is not empty")
+
+// a consistent copy of all persisted species, cages and dinosaurs
+type Snapshot struct {
+	TakenAt   time.Time  `json:"taken_at"`
+	Species   []Species  `json:"species"`
+	Cages     []Cage     `json:"cages"`
+	Dinosaurs []Dinosaur `json:"dinosaurs"`
+}
+
+// read all species, cages and dinosaurs in a single repeatable read transaction
+func (pdb *SQLDataProvider) Snapshot(ctx context.Context) (Snapshot, error) {
+	snap := Snapshot{TakenAt: time.Now().UTC()}
+	tx, err := pdb.begin(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return snap, err
+	}
+	defer tx.Rollback()
+
+	sqlStmt := `SELECT name, diet, period, weight_kg, danger_level, max_per_cage, habitat FROM species ORDER BY name`
+	rows, err := tx.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return snap, err
+	}
+	snap.Species, err = scanSpecies(rows)
+	if err != nil {
+		return snap, err
+	}
+	sqlStmt = `SELECT id, status, capacity, count, kind, version FROM cages ORDER BY id`
+	rows, err = tx.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return snap, err
+	}
+	snap.Cages, err = scanCages(rows)
+	if err != nil {
+		return snap, err
+	}
+	sqlStmt = `SELECT id, species, name, diet, cage, version FROM dinosaurs ORDER BY id`
+	rows, err = tx.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return snap, err
+	}
+	snap.Dinosaurs, err = scanDinosaurs(rows)
+	if err != nil {
+		return snap, err
+	}
+	return snap, tx.Commit()
+}
+
+func (tx *sqlTx) CheckEmpty(ctx context.Context) error {
+	var num int
+	sqlStmt := `SELECT (SELECT count(*) FROM cages) + (SELECT count(*) FROM dinosaurs)`
+	err := tx.q.QueryRowContext(ctx, sqlStmt).Scan(&num)
+	if err != nil {
+		return err
+	}
+	if num != 0 {
+		return ErrNotEmpty
+	}
+	return nil
+}
+
+// insert the rows of a snapshot into an empty database keeping their ids
+// species are saved over any already known
+func (tx *sqlTx) LoadSnapshot(ctx context.Context, snap Snapshot) error {
+	err := tx.CheckEmpty(ctx)
+	if err != nil {
+		return err
+	}
+
+	var sqlStmt string
+	for _, s := range snap.Species {
+		err = saveSpecies(ctx, tx.q, s)
+		if err != nil {
+			return fmt.Errorf("species %s : %v", s.Name, err)
+		}
+	}
+	for _, c := range snap.Cages {
+		sqlStmt = `INSERT INTO cages (id, status, capacity, count, kind, version) VALUES ($1, $2, $3, $4, $5, ` + tx.d.greatest + `($6, 1))`
+		_, err = tx.q.ExecContext(ctx, sqlStmt, c.ID, c.Status, c.Capacity, c.Count, c.Kind, c.Version)
+		if err != nil {
+			return fmt.Errorf("cage %d : %v", c.ID, err)
+		}
+	}
+	for _, d := range snap.Dinosaurs {
+		sqlStmt = `INSERT INTO dinosaurs (id, species, name, diet, cage, version) VALUES ($1, $2, $3, $4, $5, ` + tx.d.greatest + `($6, 1))`
+		_, err = tx.q.ExecContext(ctx, sqlStmt, d.ID, d.Species, d.Name, d.Diet, d.Cage, d.Version)
+		if err != nil {
+			return fmt.Errorf("dinosaur %d : %v", d.ID, err)
+		}
+	}
+	// move the id sequences past the restored rows
+	for _, table := range []string{"cages", "dinosaurs"} {
+		sqlStmt = tx.d.resequence(table)
+		if len(sqlStmt) == 0 {
+			continue
+		}
+		_, err = tx.q.ExecContext(ctx, sqlStmt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrSpeciesNotFound = errors.New("species not found")
+
+// read all species rows closing the result set
+func scanSpecies(rows *sql.Rows) ([]Species, error) {
+	var species []Species
+	defer rows.Close()
+	for rows.Next() {
+		s := Species{}
+		err := rows.Scan(&s.Name, &s.Diet, &s.Period, &s.WeightKg, &s.DangerLevel, &s.MaxPerCage, &s.Habitat)
+		if err != nil {
+			return species, err
+		}
+		species = append(species, s)
+	}
+	return species, rows.Err()
+}
+
+func saveSpecies(ctx context.Context, q queryer, s Species) error {
+	sqlStmt := `INSERT INTO species (name, diet, period, weight_kg, danger_level, max_per_cage, habitat) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (name) DO UPDATE SET diet = excluded.diet, period = excluded.period, weight_kg = excluded.weight_kg,
+	danger_level = excluded.danger_level, max_per_cage = excluded.max_per_cage, habitat = excluded.habitat`
+	_, err := q.ExecContext(ctx, sqlStmt, s.Name, s.Diet, s.Period, s.WeightKg, s.DangerLevel, s.MaxPerCage, s.Habitat)
+	return err
+}
+
+// return a single species by its stored name
+func (repo *sqlRepo) GetSpecies(ctx context.Context, name string) (Species, error) {
+	sqlStmt := `SELECT name, diet, period, weight_kg, danger_level, max_per_cage, habitat FROM species WHERE name = $1` + repo.lock
+	rows, err := repo.q.QueryContext(ctx, sqlStmt, name)
+	if err != nil {
+		return Species{}, err
+	}
+	species, err := scanSpecies(rows)
+	if err != nil {
+		return Species{}, err
+	}
+	if len(species) == 0 {
+		return Species{}, fmt.Errorf("%s : %w", name, ErrSpeciesNotFound)
+	}
+	return species[0], nil
+}
+
+// return every species in name order
+func (repo *sqlRepo) ListSpecies(ctx context.Context) ([]Species, error) {
+	sqlStmt := `SELECT name, diet, period, weight_kg, danger_level, max_per_cage, habitat FROM species ORDER BY name`
+	rows, err := repo.q.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+	return scanSpecies(rows)
+}
+
+// persist a species replacing the details of any species of the same name
+func (repo *sqlRepo) SaveSpecies(ctx context.Context, s Species) error {
+	return saveSpecies(ctx, repo.q, s)
+}
+
+func (repo *sqlRepo) RemoveSpecies(ctx context.Context, name string) error {
+	sqlStmt := `DELETE FROM species WHERE name = $1`
+	res, err := repo.q.ExecContext(ctx, sqlStmt, name)
+	if err != nil {
+		return err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return fmt.Errorf("%s : %w", name, ErrSpeciesNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// transactions take the write lock as they begin and wait for one another
+// rather than failing, and webhook deliveries cascade with their webhook
+const sqliteOptions = "?_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+
+// replicas are copies of the database file kept up to date elsewhere
+const sqliteReplicaOptions = "?mode=ro&_pragma=busy_timeout(10000)"
+
+// open a sqlite database file and return a data access object
+// the tables are created from the postgres schema when the file has none
+// replicas are paths of read only copies of the file
+func ConnectSQLite(path, schema string, replicas ...string) (DataAccessProvider, error) {
+	db, err := sql.Open("sqlite", "file:"+path+sqliteOptions)
+	if err != nil {
+		return nil, err
+	}
+	err = migrateSQLite(context.Background(), db, schema)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	var replicaDBs []*sql.DB
+	for _, rpath := range replicas {
+		rdb, err := sql.Open("sqlite", "file:"+rpath+sqliteReplicaOptions)
+		if err != nil {
+			newSQLDataProvider(&sqlite, db, replicaDBs...).Close()
+			return nil, err
+		}
+		replicaDBs = append(replicaDBs, rdb)
+	}
+	return newSQLDataProvider(&sqlite, db, replicaDBs...), nil
+}
+
+func migrateSQLite(ctx context.Context, db *sql.DB, schema string) error {
+	var num int
+	sqlStmt := `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'cages'`
+	err := db.QueryRowContext(ctx, sqlStmt).Scan(&num)
+	if err != nil || num != 0 {
+		return err
+	}
+	_, err = db.ExecContext(ctx, sqliteSchema(schema))
+	return err
+}
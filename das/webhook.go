@@ -0,0 +1,177 @@
+package das
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryDead      = "DEAD"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// an outgoing webhook subscription
+// an empty event list subscribes to every event type
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// check if the webhook subscribes to an event type
+func (wh Webhook) Wants(eventType string) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, e := range wh.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// a single event delivery to a webhook and its outcome so far
+type Delivery struct {
+	ID           int       `json:"id"`
+	Webhook      int       `json:"webhook"`
+	EventID      uint64    `json:"event_id"`
+	EventType    string    `json:"event_type"`
+	Payload      string    `json:"payload"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	ResponseCode int       `json:"response_code,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (pdb *SQLDataProvider) AddWebhook(ctx context.Context, wh Webhook) (int, error) {
+	sqlStmt := `INSERT INTO webhooks (url, events, secret) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	err := pdb.db.QueryRowContext(ctx, sqlStmt, wh.URL, strings.Join(wh.Events, ","), wh.Secret).Scan(&id)
+	return id, err
+}
+
+func (pdb *SQLDataProvider) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var hooks []Webhook
+	sqlStmt := `SELECT id, url, events, secret, created_at FROM webhooks ORDER BY id`
+	rows, err := pdb.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return hooks, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		wh := Webhook{}
+		var events string
+		err := rows.Scan(&wh.ID, &wh.URL, &events, &wh.Secret, &wh.CreatedAt)
+		if err != nil {
+			return hooks, err
+		}
+		if len(events) != 0 {
+			wh.Events = strings.Split(events, ",")
+		}
+		hooks = append(hooks, wh)
+	}
+	return hooks, rows.Err()
+}
+
+func (pdb *SQLDataProvider) DeleteWebhook(ctx context.Context, id int) error {
+	sqlStmt := `DELETE FROM webhooks WHERE id = $1`
+	res, err := pdb.db.ExecContext(ctx, sqlStmt, id)
+	if err != nil {
+		return err
+	}
+	num, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return fmt.Errorf("webhook %d : %w", id, ErrWebhookNotFound)
+	}
+	return nil
+}
+
+func (pdb *SQLDataProvider) AddDelivery(ctx context.Context, d Delivery) (int, error) {
+	sqlStmt := `INSERT INTO webhook_deliveries (webhook, event_id, event_type, payload, status) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var id int
+	err := pdb.db.QueryRowContext(ctx, sqlStmt, d.Webhook, d.EventID, d.EventType, d.Payload, d.Status).Scan(&id)
+	return id, err
+}
+
+// take a pending delivery for a single attempt returning it as stored
+// false is returned if it is no longer pending or another claim on it is
+// younger than the lease, so only one attempt at a delivery runs at a time
+// across every instance sharing the database
+func (pdb *SQLDataProvider) ClaimDelivery(ctx context.Context, id int, lease time.Duration) (Delivery, bool, error) {
+	sqlStmt := `UPDATE webhook_deliveries SET claimed_at = ` + pdb.d.now + ` WHERE id = $1 AND status = $2 AND (claimed_at IS NULL OR claimed_at < ` + pdb.d.ago("$3") + `) RETURNING ` + deliveryColumns
+	rows, err := pdb.db.QueryContext(ctx, sqlStmt, id, DeliveryPending, int64(lease/time.Second))
+	if err != nil {
+		return Delivery{}, false, err
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil || len(deliveries) == 0 {
+		return Delivery{}, false, err
+	}
+	return deliveries[0], true, nil
+}
+
+// record the outcome of a delivery attempt releasing its claim
+func (pdb *SQLDataProvider) UpdateDelivery(ctx context.Context, d Delivery) error {
+	sqlStmt := `UPDATE webhook_deliveries SET status = $1, attempts = $2, response_code = $3, last_error = $4, updated_at = ` + pdb.d.now + `, claimed_at = NULL WHERE id = $5`
+	_, err := pdb.db.ExecContext(ctx, sqlStmt, d.Status, d.Attempts, d.ResponseCode, d.LastError, d.ID)
+	return err
+}
+
+// list deliveries newest first optionally for a single webhook (id > 0) and status
+func (pdb *SQLDataProvider) ListDeliveries(ctx context.Context, webhookID int, status string) ([]Delivery, error) {
+	var opts []interface{}
+	var where []string
+	sqlStmt := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries`
+	if webhookID > 0 {
+		opts = append(opts, webhookID)
+		where = append(where, fmt.Sprintf("webhook = $%d", len(opts)))
+	}
+	if len(status) != 0 {
+		opts = append(opts, status)
+		where = append(where, fmt.Sprintf("status = $%d", len(opts)))
+	}
+	if len(where) != 0 {
+		sqlStmt = sqlStmt + ` WHERE ` + strings.Join(where, " AND ")
+	}
+	sqlStmt = sqlStmt + ` ORDER BY id DESC`
+	rows, err := pdb.db.QueryContext(ctx, sqlStmt, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+const deliveryColumns = `id, webhook, event_id, event_type, payload, status, attempts, response_code, last_error, updated_at`
+
+// read all delivery rows closing the result set
+func scanDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	var deliveries []Delivery
+	defer rows.Close()
+	for rows.Next() {
+		d := Delivery{}
+		var code sql.NullInt64
+		var lastError sql.NullString
+		err := rows.Scan(&d.ID, &d.Webhook, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &code, &lastError, &d.UpdatedAt)
+		if err != nil {
+			return deliveries, err
+		}
+		d.ResponseCode = int(code.Int64)
+		d.LastError = lastError.String
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -3,17 +3,86 @@ CREATE TABLE dinosaurs (
 	species TEXT NOT NULL,
 	name TEXT,
 	cage INTEGER,
-	diet CHAR(1) NOT NULL
+	diet CHAR(1) NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1
 );	
 
 CREATE INDEX idx_species ON dinosaurs(species);
 CREATE INDEX idx_cage_id ON dinosaurs(cage);
 
+CREATE TABLE species (
+	name TEXT PRIMARY KEY,
+	diet CHAR(1) NOT NULL,
+	period TEXT NOT NULL DEFAULT '',
+	weight_kg DOUBLE PRECISION NOT NULL DEFAULT 0,
+	danger_level INTEGER NOT NULL DEFAULT 0,
+	max_per_cage INTEGER NOT NULL DEFAULT 0,
+	habitat TEXT NOT NULL DEFAULT ''
+);
+
 CREATE TABLE cages (
 	id serial,
 	status TEXT NOT NULL,
 	capacity integer NOT NULL,
 	count integer NOT NULL,
-	kind char(1) NOT NULL
+	kind char(1) NOT NULL,
+	version integer NOT NULL DEFAULT 1
+);
+
+
+CREATE TABLE cage_status_log (
+	id serial,
+	cage INTEGER NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	actor TEXT NOT NULL DEFAULT '',
+	changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_status_log_cage ON cage_status_log(cage);
+
+CREATE TABLE webhooks (
+	id serial PRIMARY KEY,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '',
+	secret TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE webhook_deliveries (
+	id serial,
+	webhook INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event_id BIGINT NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_code INTEGER,
+	last_error TEXT,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	claimed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_deliveries_webhook ON webhook_deliveries(webhook);
+CREATE INDEX idx_deliveries_status ON webhook_deliveries(status);
+
+CREATE TABLE outbox (
+	id bigserial PRIMARY KEY,
+	event_type TEXT NOT NULL,
+	cage INTEGER NOT NULL DEFAULT 0,
+	payload TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	dispatched_at TIMESTAMPTZ,
+	tick BIGINT
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(id) WHERE dispatched_at IS NULL;
+CREATE INDEX idx_outbox_tick ON outbox(tick, id);
+
+CREATE TABLE outbox_clock (
+	id INTEGER PRIMARY KEY,
+	tick BIGINT NOT NULL
 );
 
+INSERT INTO outbox_clock (id, tick) VALUES (1, 0);
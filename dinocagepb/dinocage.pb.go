@@ -0,0 +1,1093 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: dinocage.proto
+
+package dinocagepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Species struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Diet  string                 `protobuf:"bytes,2,opt,name=diet,proto3" json:"diet,omitempty"`
+	// details left unset keep those of a stored species when added again
+	Period        string  `protobuf:"bytes,3,opt,name=period,proto3" json:"period,omitempty"`
+	WeightKg      float64 `protobuf:"fixed64,4,opt,name=weight_kg,json=weightKg,proto3" json:"weight_kg,omitempty"`
+	DangerLevel   int32   `protobuf:"varint,5,opt,name=danger_level,json=dangerLevel,proto3" json:"danger_level,omitempty"`
+	MaxPerCage    int32   `protobuf:"varint,6,opt,name=max_per_cage,json=maxPerCage,proto3" json:"max_per_cage,omitempty"`
+	Habitat       string  `protobuf:"bytes,7,opt,name=habitat,proto3" json:"habitat,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Species) Reset() {
+	*x = Species{}
+	mi := &file_dinocage_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Species) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Species) ProtoMessage() {}
+
+func (x *Species) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Species.ProtoReflect.Descriptor instead.
+func (*Species) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Species) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Species) GetDiet() string {
+	if x != nil {
+		return x.Diet
+	}
+	return ""
+}
+
+func (x *Species) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *Species) GetWeightKg() float64 {
+	if x != nil {
+		return x.WeightKg
+	}
+	return 0
+}
+
+func (x *Species) GetDangerLevel() int32 {
+	if x != nil {
+		return x.DangerLevel
+	}
+	return 0
+}
+
+func (x *Species) GetMaxPerCage() int32 {
+	if x != nil {
+		return x.MaxPerCage
+	}
+	return 0
+}
+
+func (x *Species) GetHabitat() string {
+	if x != nil {
+		return x.Habitat
+	}
+	return ""
+}
+
+type Cage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Capacity      int32                  `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Count         int32                  `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	Kind          string                 `protobuf:"bytes,5,opt,name=kind,proto3" json:"kind,omitempty"`
+	Version       int32                  `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cage) Reset() {
+	*x = Cage{}
+	mi := &file_dinocage_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cage) ProtoMessage() {}
+
+func (x *Cage) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cage.ProtoReflect.Descriptor instead.
+func (*Cage) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Cage) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Cage) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Cage) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *Cage) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Cage) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Cage) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Dinosaur struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Species       string                 `protobuf:"bytes,2,opt,name=species,proto3" json:"species,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Diet          string                 `protobuf:"bytes,4,opt,name=diet,proto3" json:"diet,omitempty"`
+	Cage          uint32                 `protobuf:"varint,5,opt,name=cage,proto3" json:"cage,omitempty"`
+	Version       int32                  `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Dinosaur) Reset() {
+	*x = Dinosaur{}
+	mi := &file_dinocage_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Dinosaur) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dinosaur) ProtoMessage() {}
+
+func (x *Dinosaur) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dinosaur.ProtoReflect.Descriptor instead.
+func (*Dinosaur) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Dinosaur) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Dinosaur) GetSpecies() string {
+	if x != nil {
+		return x.Species
+	}
+	return ""
+}
+
+func (x *Dinosaur) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Dinosaur) GetDiet() string {
+	if x != nil {
+		return x.Diet
+	}
+	return ""
+}
+
+func (x *Dinosaur) GetCage() uint32 {
+	if x != nil {
+		return x.Cage
+	}
+	return 0
+}
+
+func (x *Dinosaur) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type  string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Cage  int32                  `protobuf:"varint,3,opt,name=cage,proto3" json:"cage,omitempty"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	// json encoded event payload
+	Data          string `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_dinocage_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Event) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetCage() int32 {
+	if x != nil {
+		return x.Cage
+	}
+	return 0
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+type ListSpeciesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSpeciesRequest) Reset() {
+	*x = ListSpeciesRequest{}
+	mi := &file_dinocage_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSpeciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSpeciesRequest) ProtoMessage() {}
+
+func (x *ListSpeciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSpeciesRequest.ProtoReflect.Descriptor instead.
+func (*ListSpeciesRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{4}
+}
+
+type AddCageRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// H or C
+	Diet string `protobuf:"bytes,1,opt,name=diet,proto3" json:"diet,omitempty"`
+	// defaults to the standard cage capacity when zero
+	Capacity      int32 `protobuf:"varint,2,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddCageRequest) Reset() {
+	*x = AddCageRequest{}
+	mi := &file_dinocage_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddCageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddCageRequest) ProtoMessage() {}
+
+func (x *AddCageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddCageRequest.ProtoReflect.Descriptor instead.
+func (*AddCageRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AddCageRequest) GetDiet() string {
+	if x != nil {
+		return x.Diet
+	}
+	return ""
+}
+
+func (x *AddCageRequest) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+type GetCageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCageRequest) Reset() {
+	*x = GetCageRequest{}
+	mi := &file_dinocage_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCageRequest) ProtoMessage() {}
+
+func (x *GetCageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCageRequest.ProtoReflect.Descriptor instead.
+func (*GetCageRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetCageRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListCagesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// optional status filter
+	Status        string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCagesRequest) Reset() {
+	*x = ListCagesRequest{}
+	mi := &file_dinocage_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCagesRequest) ProtoMessage() {}
+
+func (x *ListCagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCagesRequest.ProtoReflect.Descriptor instead.
+func (*ListCagesRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListCagesRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type SetCageStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Version       int32                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Actor         string                 `protobuf:"bytes,5,opt,name=actor,proto3" json:"actor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCageStatusRequest) Reset() {
+	*x = SetCageStatusRequest{}
+	mi := &file_dinocage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCageStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCageStatusRequest) ProtoMessage() {}
+
+func (x *SetCageStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCageStatusRequest.ProtoReflect.Descriptor instead.
+func (*SetCageStatusRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetCageStatusRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SetCageStatusRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SetCageStatusRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *SetCageStatusRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *SetCageStatusRequest) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+type AddDinosaurRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Dinosaur *Dinosaur              `protobuf:"bytes,1,opt,name=dinosaur,proto3" json:"dinosaur,omitempty"`
+	// overrides the server placement strategy when set
+	Strategy      string `protobuf:"bytes,2,opt,name=strategy,proto3" json:"strategy,omitempty"`
+	AutoCreate    *bool  `protobuf:"varint,3,opt,name=auto_create,json=autoCreate,proto3,oneof" json:"auto_create,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddDinosaurRequest) Reset() {
+	*x = AddDinosaurRequest{}
+	mi := &file_dinocage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddDinosaurRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddDinosaurRequest) ProtoMessage() {}
+
+func (x *AddDinosaurRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddDinosaurRequest.ProtoReflect.Descriptor instead.
+func (*AddDinosaurRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AddDinosaurRequest) GetDinosaur() *Dinosaur {
+	if x != nil {
+		return x.Dinosaur
+	}
+	return nil
+}
+
+func (x *AddDinosaurRequest) GetStrategy() string {
+	if x != nil {
+		return x.Strategy
+	}
+	return ""
+}
+
+func (x *AddDinosaurRequest) GetAutoCreate() bool {
+	if x != nil && x.AutoCreate != nil {
+		return *x.AutoCreate
+	}
+	return false
+}
+
+type PlaceDinosaurRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cage          uint32                 `protobuf:"varint,1,opt,name=cage,proto3" json:"cage,omitempty"`
+	Dinosaur      *Dinosaur              `protobuf:"bytes,2,opt,name=dinosaur,proto3" json:"dinosaur,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlaceDinosaurRequest) Reset() {
+	*x = PlaceDinosaurRequest{}
+	mi := &file_dinocage_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlaceDinosaurRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaceDinosaurRequest) ProtoMessage() {}
+
+func (x *PlaceDinosaurRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaceDinosaurRequest.ProtoReflect.Descriptor instead.
+func (*PlaceDinosaurRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PlaceDinosaurRequest) GetCage() uint32 {
+	if x != nil {
+		return x.Cage
+	}
+	return 0
+}
+
+func (x *PlaceDinosaurRequest) GetDinosaur() *Dinosaur {
+	if x != nil {
+		return x.Dinosaur
+	}
+	return nil
+}
+
+type AddDinosaurResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddDinosaurResponse) Reset() {
+	*x = AddDinosaurResponse{}
+	mi := &file_dinocage_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddDinosaurResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddDinosaurResponse) ProtoMessage() {}
+
+func (x *AddDinosaurResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddDinosaurResponse.ProtoReflect.Descriptor instead.
+func (*AddDinosaurResponse) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{11}
+}
+
+type GetDinosaurRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDinosaurRequest) Reset() {
+	*x = GetDinosaurRequest{}
+	mi := &file_dinocage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDinosaurRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDinosaurRequest) ProtoMessage() {}
+
+func (x *GetDinosaurRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDinosaurRequest.ProtoReflect.Descriptor instead.
+func (*GetDinosaurRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetDinosaurRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListDinosaursRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// optional species filter
+	Species string `protobuf:"bytes,1,opt,name=species,proto3" json:"species,omitempty"`
+	// optional cage filter
+	Cage          uint32 `protobuf:"varint,2,opt,name=cage,proto3" json:"cage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDinosaursRequest) Reset() {
+	*x = ListDinosaursRequest{}
+	mi := &file_dinocage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDinosaursRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDinosaursRequest) ProtoMessage() {}
+
+func (x *ListDinosaursRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDinosaursRequest.ProtoReflect.Descriptor instead.
+func (*ListDinosaursRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListDinosaursRequest) GetSpecies() string {
+	if x != nil {
+		return x.Species
+	}
+	return ""
+}
+
+func (x *ListDinosaursRequest) GetCage() uint32 {
+	if x != nil {
+		return x.Cage
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Types         []string               `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"`
+	Cage          int32                  `protobuf:"varint,2,opt,name=cage,proto3" json:"cage,omitempty"`
+	LastEventId   uint64                 `protobuf:"varint,3,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_dinocage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dinocage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_dinocage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WatchRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetCage() int32 {
+	if x != nil {
+		return x.Cage
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetLastEventId() uint64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+var File_dinocage_proto protoreflect.FileDescriptor
+
+const file_dinocage_proto_rawDesc = "" +
+	"\n" +
+	"\x0edinocage.proto\x12\vdinocage.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc5\x01\n" +
+	"\aSpecies\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04diet\x18\x02 \x01(\tR\x04diet\x12\x16\n" +
+	"\x06period\x18\x03 \x01(\tR\x06period\x12\x1b\n" +
+	"\tweight_kg\x18\x04 \x01(\x01R\bweightKg\x12!\n" +
+	"\fdanger_level\x18\x05 \x01(\x05R\vdangerLevel\x12 \n" +
+	"\fmax_per_cage\x18\x06 \x01(\x05R\n" +
+	"maxPerCage\x12\x18\n" +
+	"\ahabitat\x18\a \x01(\tR\ahabitat\"\x8e\x01\n" +
+	"\x04Cage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1a\n" +
+	"\bcapacity\x18\x03 \x01(\x05R\bcapacity\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x05R\x05count\x12\x12\n" +
+	"\x04kind\x18\x05 \x01(\tR\x04kind\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x05R\aversion\"\x8a\x01\n" +
+	"\bDinosaur\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x18\n" +
+	"\aspecies\x18\x02 \x01(\tR\aspecies\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x12\n" +
+	"\x04diet\x18\x04 \x01(\tR\x04diet\x12\x12\n" +
+	"\x04cage\x18\x05 \x01(\rR\x04cage\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x05R\aversion\"\x83\x01\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04cage\x18\x03 \x01(\x05R\x04cage\x12.\n" +
+	"\x04time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\tR\x04data\"\x14\n" +
+	"\x12ListSpeciesRequest\"@\n" +
+	"\x0eAddCageRequest\x12\x12\n" +
+	"\x04diet\x18\x01 \x01(\tR\x04diet\x12\x1a\n" +
+	"\bcapacity\x18\x02 \x01(\x05R\bcapacity\" \n" +
+	"\x0eGetCageRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"*\n" +
+	"\x10ListCagesRequest\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"\x86\x01\n" +
+	"\x14SetCageStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x05R\aversion\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12\x14\n" +
+	"\x05actor\x18\x05 \x01(\tR\x05actor\"\x99\x01\n" +
+	"\x12AddDinosaurRequest\x121\n" +
+	"\bdinosaur\x18\x01 \x01(\v2\x15.dinocage.v1.DinosaurR\bdinosaur\x12\x1a\n" +
+	"\bstrategy\x18\x02 \x01(\tR\bstrategy\x12$\n" +
+	"\vauto_create\x18\x03 \x01(\bH\x00R\n" +
+	"autoCreate\x88\x01\x01B\x0e\n" +
+	"\f_auto_create\"]\n" +
+	"\x14PlaceDinosaurRequest\x12\x12\n" +
+	"\x04cage\x18\x01 \x01(\rR\x04cage\x121\n" +
+	"\bdinosaur\x18\x02 \x01(\v2\x15.dinocage.v1.DinosaurR\bdinosaur\"\x15\n" +
+	"\x13AddDinosaurResponse\"$\n" +
+	"\x12GetDinosaurRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"D\n" +
+	"\x14ListDinosaursRequest\x12\x18\n" +
+	"\aspecies\x18\x01 \x01(\tR\aspecies\x12\x12\n" +
+	"\x04cage\x18\x02 \x01(\rR\x04cage\"\\\n" +
+	"\fWatchRequest\x12\x14\n" +
+	"\x05types\x18\x01 \x03(\tR\x05types\x12\x12\n" +
+	"\x04cage\x18\x02 \x01(\x05R\x04cage\x12\"\n" +
+	"\rlast_event_id\x18\x03 \x01(\x04R\vlastEventId2\xfc\x05\n" +
+	"\x04Park\x128\n" +
+	"\n" +
+	"AddSpecies\x12\x14.dinocage.v1.Species\x1a\x14.dinocage.v1.Species\x12F\n" +
+	"\vListSpecies\x12\x1f.dinocage.v1.ListSpeciesRequest\x1a\x14.dinocage.v1.Species0\x01\x129\n" +
+	"\aAddCage\x12\x1b.dinocage.v1.AddCageRequest\x1a\x11.dinocage.v1.Cage\x129\n" +
+	"\aGetCage\x12\x1b.dinocage.v1.GetCageRequest\x1a\x11.dinocage.v1.Cage\x12?\n" +
+	"\tListCages\x12\x1d.dinocage.v1.ListCagesRequest\x1a\x11.dinocage.v1.Cage0\x01\x12E\n" +
+	"\rSetCageStatus\x12!.dinocage.v1.SetCageStatusRequest\x1a\x11.dinocage.v1.Cage\x12P\n" +
+	"\vAddDinosaur\x12\x1f.dinocage.v1.AddDinosaurRequest\x1a .dinocage.v1.AddDinosaurResponse\x12T\n" +
+	"\rPlaceDinosaur\x12!.dinocage.v1.PlaceDinosaurRequest\x1a .dinocage.v1.AddDinosaurResponse\x12E\n" +
+	"\vGetDinosaur\x12\x1f.dinocage.v1.GetDinosaurRequest\x1a\x15.dinocage.v1.Dinosaur\x12K\n" +
+	"\rListDinosaurs\x12!.dinocage.v1.ListDinosaursRequest\x1a\x15.dinocage.v1.Dinosaur0\x01\x128\n" +
+	"\x05Watch\x12\x19.dinocage.v1.WatchRequest\x1a\x12.dinocage.v1.Event0\x01B\x15Z\x13dinocage/dinocagepbb\x06proto3"
+
+var (
+	file_dinocage_proto_rawDescOnce sync.Once
+	file_dinocage_proto_rawDescData []byte
+)
+
+func file_dinocage_proto_rawDescGZIP() []byte {
+	file_dinocage_proto_rawDescOnce.Do(func() {
+		file_dinocage_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_dinocage_proto_rawDesc), len(file_dinocage_proto_rawDesc)))
+	})
+	return file_dinocage_proto_rawDescData
+}
+
+var file_dinocage_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_dinocage_proto_goTypes = []any{
+	(*Species)(nil),               // 0: dinocage.v1.Species
+	(*Cage)(nil),                  // 1: dinocage.v1.Cage
+	(*Dinosaur)(nil),              // 2: dinocage.v1.Dinosaur
+	(*Event)(nil),                 // 3: dinocage.v1.Event
+	(*ListSpeciesRequest)(nil),    // 4: dinocage.v1.ListSpeciesRequest
+	(*AddCageRequest)(nil),        // 5: dinocage.v1.AddCageRequest
+	(*GetCageRequest)(nil),        // 6: dinocage.v1.GetCageRequest
+	(*ListCagesRequest)(nil),      // 7: dinocage.v1.ListCagesRequest
+	(*SetCageStatusRequest)(nil),  // 8: dinocage.v1.SetCageStatusRequest
+	(*AddDinosaurRequest)(nil),    // 9: dinocage.v1.AddDinosaurRequest
+	(*PlaceDinosaurRequest)(nil),  // 10: dinocage.v1.PlaceDinosaurRequest
+	(*AddDinosaurResponse)(nil),   // 11: dinocage.v1.AddDinosaurResponse
+	(*GetDinosaurRequest)(nil),    // 12: dinocage.v1.GetDinosaurRequest
+	(*ListDinosaursRequest)(nil),  // 13: dinocage.v1.ListDinosaursRequest
+	(*WatchRequest)(nil),          // 14: dinocage.v1.WatchRequest
+	(*timestamppb.Timestamp)(nil), // 15: google.protobuf.Timestamp
+}
+var file_dinocage_proto_depIdxs = []int32{
+	15, // 0: dinocage.v1.Event.time:type_name -> google.protobuf.Timestamp
+	2,  // 1: dinocage.v1.AddDinosaurRequest.dinosaur:type_name -> dinocage.v1.Dinosaur
+	2,  // 2: dinocage.v1.PlaceDinosaurRequest.dinosaur:type_name -> dinocage.v1.Dinosaur
+	0,  // 3: dinocage.v1.Park.AddSpecies:input_type -> dinocage.v1.Species
+	4,  // 4: dinocage.v1.Park.ListSpecies:input_type -> dinocage.v1.ListSpeciesRequest
+	5,  // 5: dinocage.v1.Park.AddCage:input_type -> dinocage.v1.AddCageRequest
+	6,  // 6: dinocage.v1.Park.GetCage:input_type -> dinocage.v1.GetCageRequest
+	7,  // 7: dinocage.v1.Park.ListCages:input_type -> dinocage.v1.ListCagesRequest
+	8,  // 8: dinocage.v1.Park.SetCageStatus:input_type -> dinocage.v1.SetCageStatusRequest
+	9,  // 9: dinocage.v1.Park.AddDinosaur:input_type -> dinocage.v1.AddDinosaurRequest
+	10, // 10: dinocage.v1.Park.PlaceDinosaur:input_type -> dinocage.v1.PlaceDinosaurRequest
+	12, // 11: dinocage.v1.Park.GetDinosaur:input_type -> dinocage.v1.GetDinosaurRequest
+	13, // 12: dinocage.v1.Park.ListDinosaurs:input_type -> dinocage.v1.ListDinosaursRequest
+	14, // 13: dinocage.v1.Park.Watch:input_type -> dinocage.v1.WatchRequest
+	0,  // 14: dinocage.v1.Park.AddSpecies:output_type -> dinocage.v1.Species
+	0,  // 15: dinocage.v1.Park.ListSpecies:output_type -> dinocage.v1.Species
+	1,  // 16: dinocage.v1.Park.AddCage:output_type -> dinocage.v1.Cage
+	1,  // 17: dinocage.v1.Park.GetCage:output_type -> dinocage.v1.Cage
+	1,  // 18: dinocage.v1.Park.ListCages:output_type -> dinocage.v1.Cage
+	1,  // 19: dinocage.v1.Park.SetCageStatus:output_type -> dinocage.v1.Cage
+	11, // 20: dinocage.v1.Park.AddDinosaur:output_type -> dinocage.v1.AddDinosaurResponse
+	11, // 21: dinocage.v1.Park.PlaceDinosaur:output_type -> dinocage.v1.AddDinosaurResponse
+	2,  // 22: dinocage.v1.Park.GetDinosaur:output_type -> dinocage.v1.Dinosaur
+	2,  // 23: dinocage.v1.Park.ListDinosaurs:output_type -> dinocage.v1.Dinosaur
+	3,  // 24: dinocage.v1.Park.Watch:output_type -> dinocage.v1.Event
+	14, // [14:25] is the sub-list for method output_type
+	3,  // [3:14] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_dinocage_proto_init() }
+func file_dinocage_proto_init() {
+	if File_dinocage_proto != nil {
+		return
+	}
+	file_dinocage_proto_msgTypes[9].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_dinocage_proto_rawDesc), len(file_dinocage_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_dinocage_proto_goTypes,
+		DependencyIndexes: file_dinocage_proto_depIdxs,
+		MessageInfos:      file_dinocage_proto_msgTypes,
+	}.Build()
+	File_dinocage_proto = out.File
+	file_dinocage_proto_goTypes = nil
+	file_dinocage_proto_depIdxs = nil
+}
@@ -0,0 +1,526 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: dinocage.proto
+
+package dinocagepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Park_AddSpecies_FullMethodName    = "/dinocage.v1.Park/AddSpecies"
+	Park_ListSpecies_FullMethodName   = "/dinocage.v1.Park/ListSpecies"
+	Park_AddCage_FullMethodName       = "/dinocage.v1.Park/AddCage"
+	Park_GetCage_FullMethodName       = "/dinocage.v1.Park/GetCage"
+	Park_ListCages_FullMethodName     = "/dinocage.v1.Park/ListCages"
+	Park_SetCageStatus_FullMethodName = "/dinocage.v1.Park/SetCageStatus"
+	Park_AddDinosaur_FullMethodName   = "/dinocage.v1.Park/AddDinosaur"
+	Park_PlaceDinosaur_FullMethodName = "/dinocage.v1.Park/PlaceDinosaur"
+	Park_GetDinosaur_FullMethodName   = "/dinocage.v1.Park/GetDinosaur"
+	Park_ListDinosaurs_FullMethodName = "/dinocage.v1.Park/ListDinosaurs"
+	Park_Watch_FullMethodName         = "/dinocage.v1.Park/Watch"
+)
+
+// ParkClient is the client API for Park service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// park management api mirroring the v1 rest endpoints
+type ParkClient interface {
+	AddSpecies(ctx context.Context, in *Species, opts ...grpc.CallOption) (*Species, error)
+	ListSpecies(ctx context.Context, in *ListSpeciesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Species], error)
+	AddCage(ctx context.Context, in *AddCageRequest, opts ...grpc.CallOption) (*Cage, error)
+	GetCage(ctx context.Context, in *GetCageRequest, opts ...grpc.CallOption) (*Cage, error)
+	ListCages(ctx context.Context, in *ListCagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Cage], error)
+	// requires the current cage version as with the rest If-Match header
+	SetCageStatus(ctx context.Context, in *SetCageStatusRequest, opts ...grpc.CallOption) (*Cage, error)
+	// place a new dinosaur in a cage chosen by the placement strategy
+	AddDinosaur(ctx context.Context, in *AddDinosaurRequest, opts ...grpc.CallOption) (*AddDinosaurResponse, error)
+	// place a new dinosaur in the given cage
+	PlaceDinosaur(ctx context.Context, in *PlaceDinosaurRequest, opts ...grpc.CallOption) (*AddDinosaurResponse, error)
+	GetDinosaur(ctx context.Context, in *GetDinosaurRequest, opts ...grpc.CallOption) (*Dinosaur, error)
+	ListDinosaurs(ctx context.Context, in *ListDinosaursRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Dinosaur], error)
+	// stream park changes resuming after last_event_id
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type parkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewParkClient(cc grpc.ClientConnInterface) ParkClient {
+	return &parkClient{cc}
+}
+
+func (c *parkClient) AddSpecies(ctx context.Context, in *Species, opts ...grpc.CallOption) (*Species, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Species)
+	err := c.cc.Invoke(ctx, Park_AddSpecies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) ListSpecies(ctx context.Context, in *ListSpeciesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Species], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Park_ServiceDesc.Streams[0], Park_ListSpecies_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListSpeciesRequest, Species]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_ListSpeciesClient = grpc.ServerStreamingClient[Species]
+
+func (c *parkClient) AddCage(ctx context.Context, in *AddCageRequest, opts ...grpc.CallOption) (*Cage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cage)
+	err := c.cc.Invoke(ctx, Park_AddCage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) GetCage(ctx context.Context, in *GetCageRequest, opts ...grpc.CallOption) (*Cage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cage)
+	err := c.cc.Invoke(ctx, Park_GetCage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) ListCages(ctx context.Context, in *ListCagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Cage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Park_ServiceDesc.Streams[1], Park_ListCages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListCagesRequest, Cage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_ListCagesClient = grpc.ServerStreamingClient[Cage]
+
+func (c *parkClient) SetCageStatus(ctx context.Context, in *SetCageStatusRequest, opts ...grpc.CallOption) (*Cage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cage)
+	err := c.cc.Invoke(ctx, Park_SetCageStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) AddDinosaur(ctx context.Context, in *AddDinosaurRequest, opts ...grpc.CallOption) (*AddDinosaurResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddDinosaurResponse)
+	err := c.cc.Invoke(ctx, Park_AddDinosaur_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) PlaceDinosaur(ctx context.Context, in *PlaceDinosaurRequest, opts ...grpc.CallOption) (*AddDinosaurResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddDinosaurResponse)
+	err := c.cc.Invoke(ctx, Park_PlaceDinosaur_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) GetDinosaur(ctx context.Context, in *GetDinosaurRequest, opts ...grpc.CallOption) (*Dinosaur, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Dinosaur)
+	err := c.cc.Invoke(ctx, Park_GetDinosaur_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parkClient) ListDinosaurs(ctx context.Context, in *ListDinosaursRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Dinosaur], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Park_ServiceDesc.Streams[2], Park_ListDinosaurs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListDinosaursRequest, Dinosaur]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_ListDinosaursClient = grpc.ServerStreamingClient[Dinosaur]
+
+func (c *parkClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Park_ServiceDesc.Streams[3], Park_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_WatchClient = grpc.ServerStreamingClient[Event]
+
+// ParkServer is the server API for Park service.
+// All implementations must embed UnimplementedParkServer
+// for forward compatibility.
+//
+// park management api mirroring the v1 rest endpoints
+type ParkServer interface {
+	AddSpecies(context.Context, *Species) (*Species, error)
+	ListSpecies(*ListSpeciesRequest, grpc.ServerStreamingServer[Species]) error
+	AddCage(context.Context, *AddCageRequest) (*Cage, error)
+	GetCage(context.Context, *GetCageRequest) (*Cage, error)
+	ListCages(*ListCagesRequest, grpc.ServerStreamingServer[Cage]) error
+	// requires the current cage version as with the rest If-Match header
+	SetCageStatus(context.Context, *SetCageStatusRequest) (*Cage, error)
+	// place a new dinosaur in a cage chosen by the placement strategy
+	AddDinosaur(context.Context, *AddDinosaurRequest) (*AddDinosaurResponse, error)
+	// place a new dinosaur in the given cage
+	PlaceDinosaur(context.Context, *PlaceDinosaurRequest) (*AddDinosaurResponse, error)
+	GetDinosaur(context.Context, *GetDinosaurRequest) (*Dinosaur, error)
+	ListDinosaurs(*ListDinosaursRequest, grpc.ServerStreamingServer[Dinosaur]) error
+	// stream park changes resuming after last_event_id
+	Watch(*WatchRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedParkServer()
+}
+
+// UnimplementedParkServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedParkServer struct{}
+
+func (UnimplementedParkServer) AddSpecies(context.Context, *Species) (*Species, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSpecies not implemented")
+}
+func (UnimplementedParkServer) ListSpecies(*ListSpeciesRequest, grpc.ServerStreamingServer[Species]) error {
+	return status.Errorf(codes.Unimplemented, "method ListSpecies not implemented")
+}
+func (UnimplementedParkServer) AddCage(context.Context, *AddCageRequest) (*Cage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddCage not implemented")
+}
+func (UnimplementedParkServer) GetCage(context.Context, *GetCageRequest) (*Cage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCage not implemented")
+}
+func (UnimplementedParkServer) ListCages(*ListCagesRequest, grpc.ServerStreamingServer[Cage]) error {
+	return status.Errorf(codes.Unimplemented, "method ListCages not implemented")
+}
+func (UnimplementedParkServer) SetCageStatus(context.Context, *SetCageStatusRequest) (*Cage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCageStatus not implemented")
+}
+func (UnimplementedParkServer) AddDinosaur(context.Context, *AddDinosaurRequest) (*AddDinosaurResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddDinosaur not implemented")
+}
+func (UnimplementedParkServer) PlaceDinosaur(context.Context, *PlaceDinosaurRequest) (*AddDinosaurResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceDinosaur not implemented")
+}
+func (UnimplementedParkServer) GetDinosaur(context.Context, *GetDinosaurRequest) (*Dinosaur, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDinosaur not implemented")
+}
+func (UnimplementedParkServer) ListDinosaurs(*ListDinosaursRequest, grpc.ServerStreamingServer[Dinosaur]) error {
+	return status.Errorf(codes.Unimplemented, "method ListDinosaurs not implemented")
+}
+func (UnimplementedParkServer) Watch(*WatchRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedParkServer) mustEmbedUnimplementedParkServer() {}
+func (UnimplementedParkServer) testEmbeddedByValue()              {}
+
+// UnsafeParkServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ParkServer will
+// result in compilation errors.
+type UnsafeParkServer interface {
+	mustEmbedUnimplementedParkServer()
+}
+
+func RegisterParkServer(s grpc.ServiceRegistrar, srv ParkServer) {
+	// If the following call pancis, it indicates UnimplementedParkServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Park_ServiceDesc, srv)
+}
+
+func _Park_AddSpecies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Species)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).AddSpecies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_AddSpecies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).AddSpecies(ctx, req.(*Species))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_ListSpecies_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListSpeciesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ParkServer).ListSpecies(m, &grpc.GenericServerStream[ListSpeciesRequest, Species]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_ListSpeciesServer = grpc.ServerStreamingServer[Species]
+
+func _Park_AddCage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).AddCage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_AddCage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).AddCage(ctx, req.(*AddCageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_GetCage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).GetCage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_GetCage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).GetCage(ctx, req.(*GetCageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_ListCages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ParkServer).ListCages(m, &grpc.GenericServerStream[ListCagesRequest, Cage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_ListCagesServer = grpc.ServerStreamingServer[Cage]
+
+func _Park_SetCageStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCageStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).SetCageStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_SetCageStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).SetCageStatus(ctx, req.(*SetCageStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_AddDinosaur_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddDinosaurRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).AddDinosaur(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_AddDinosaur_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).AddDinosaur(ctx, req.(*AddDinosaurRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_PlaceDinosaur_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceDinosaurRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).PlaceDinosaur(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_PlaceDinosaur_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).PlaceDinosaur(ctx, req.(*PlaceDinosaurRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_GetDinosaur_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDinosaurRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParkServer).GetDinosaur(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Park_GetDinosaur_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParkServer).GetDinosaur(ctx, req.(*GetDinosaurRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Park_ListDinosaurs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListDinosaursRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ParkServer).ListDinosaurs(m, &grpc.GenericServerStream[ListDinosaursRequest, Dinosaur]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_ListDinosaursServer = grpc.ServerStreamingServer[Dinosaur]
+
+func _Park_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ParkServer).Watch(m, &grpc.GenericServerStream[WatchRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Park_WatchServer = grpc.ServerStreamingServer[Event]
+
+// Park_ServiceDesc is the grpc.ServiceDesc for Park service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Park_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dinocage.v1.Park",
+	HandlerType: (*ParkServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddSpecies",
+			Handler:    _Park_AddSpecies_Handler,
+		},
+		{
+			MethodName: "AddCage",
+			Handler:    _Park_AddCage_Handler,
+		},
+		{
+			MethodName: "GetCage",
+			Handler:    _Park_GetCage_Handler,
+		},
+		{
+			MethodName: "SetCageStatus",
+			Handler:    _Park_SetCageStatus_Handler,
+		},
+		{
+			MethodName: "AddDinosaur",
+			Handler:    _Park_AddDinosaur_Handler,
+		},
+		{
+			MethodName: "PlaceDinosaur",
+			Handler:    _Park_PlaceDinosaur_Handler,
+		},
+		{
+			MethodName: "GetDinosaur",
+			Handler:    _Park_GetDinosaur_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListSpecies",
+			Handler:       _Park_ListSpecies_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListCages",
+			Handler:       _Park_ListCages_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListDinosaurs",
+			Handler:       _Park_ListDinosaurs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Park_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "dinocage.proto",
+}
@@ -1,10 +1,28 @@
+# postgres|sqlite, for sqlite ENV_DB_NAME is the database file
+#export ENV_DB_DRIVER="postgres"
 export ENV_DB_HOST="localhost"
 export ENV_DB_PORT="5432"
 export ENV_DB_NAME="postgres"
 export ENV_DB_USR="postgres"
 export ENV_DB_PWD="dino"
+# comma separated read replicas, host:port for postgres or file paths for sqlite
+#export ENV_DB_REPLICAS=""
 # svr default to this
 #export ENV_SVR_ENDPOINT=":8000"
+# grpc server defaults to this
+#export ENV_GRPC_ENDPOINT=":9000"
 
 
 
+# cage selection for automatic placement first-fit|best-fit|worst-fit|round-robin
+#export ENV_PLACEMENT_STRATEGY="first-fit"
+# set false to return 409 rather than create a cage when none have room
+#export ENV_AUTO_CREATE_CAGES="true"
+# how long responses are kept for replay against an Idempotency-Key
+#export ENV_IDEMPOTENCY_WINDOW="24h"
+# how long cages and cage listings are cached, 0 turns the cache off
+#export ENV_CACHE_TTL="10s"
+# most entries kept by each cache
+#export ENV_CACHE_SIZE="1024"
+# how often the species file is checked for changes, 0 only reloads on SIGHUP
+#export ENV_SPECIES_WATCH_INTERVAL="5s"
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dinocage/das"
+)
+
+const (
+	DefaultEventBuffer = 1024
+	subscriberBuffer   = 64
+	heartbeatInterval  = 15 * time.Second
+)
+
+// a change to the park dispatched from the outbox
+type Event struct {
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Cage int       `json:"cage,omitempty"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// filter applied to events sent to a subscriber
+type EventFilter struct {
+	Types map[string]bool
+	Cage  int
+}
+
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Types) != 0 && !f.Types[e.Type] {
+		return false
+	}
+	return f.Cage == 0 || f.Cage == e.Cage
+}
+
+// receives every dispatched event in order
+// an error causes the event to be redelivered so sinks must tolerate duplicates
+type EventSink interface {
+	Deliver(ctx context.Context, e Event) error
+}
+
+type subscriber struct {
+	filter EventFilter
+	ch     chan Event
+}
+
+// fan out of park events to subscribers keeping a bounded ring buffer
+// of recent events so clients can resume from the last event they saw
+// events arrive in commit order which need not be id order, so the ids in
+// the ring are remembered to drop redelivered events
+type EventHub struct {
+	mu       sync.Mutex
+	ring     []Event
+	start    int
+	seen     map[uint64]bool
+	subs     map[*subscriber]bool
+	done     chan struct{}
+	once     sync.Once
+	Interval time.Duration
+}
+
+func NewEventHub(size int) *EventHub {
+	if size < 1 {
+		size = DefaultEventBuffer
+	}
+	return &EventHub{
+		ring:     make([]Event, 0, size),
+		seen:     make(map[uint64]bool, size),
+		subs:     make(map[*subscriber]bool),
+		done:     make(chan struct{}),
+		Interval: DefaultOutboxInterval,
+	}
+}
+
+// end all streams so the server can shut down
+func (eh *EventHub) Close() {
+	eh.once.Do(func() { close(eh.done) })
+}
+
+// the hub as an event sink keeping the event id
+// events still in the ring buffer are ignored when delivered again
+func (eh *EventHub) Deliver(ctx context.Context, e Event) error {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	if eh.seen[e.ID] {
+		return nil
+	}
+	eh.record(e)
+	return nil
+}
+
+// deliver the events committed by every instance sharing the outbox in
+// commit order until the context is done, starting from those committed
+// after the hub starts following
+func (eh *EventHub) Follow(ctx context.Context, store das.OutboxStore) {
+	var after das.EventCursor
+	var err error
+	after.Tick, err = store.EventClock(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("event hub unable to read the event clock : %v", err)
+	}
+	ticker := time.NewTicker(eh.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		after, err = eh.follow(ctx, store, after)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("event hub unable to follow events : %v", err)
+		}
+	}
+}
+
+// deliver the events committed after the cursor returning the new cursor
+func (eh *EventHub) follow(ctx context.Context, store das.OutboxStore, after das.EventCursor) (das.EventCursor, error) {
+	for {
+		events, err := store.CommittedEvents(ctx, after, outboxBatchSize)
+		if err != nil {
+			return after, err
+		}
+		for _, oe := range events {
+			eh.Deliver(ctx, Event{ID: oe.ID, Type: oe.Type, Cage: oe.Cage, Time: oe.CreatedAt, Data: oe.Payload})
+			after = oe.Cursor()
+		}
+		if len(events) < outboxBatchSize {
+			return after, nil
+		}
+	}
+}
+
+// add an event to the ring buffer and deliver it to all matching subscribers
+// subscribers that cannot keep up are dropped and must resume
+func (eh *EventHub) record(e Event) {
+	eh.seen[e.ID] = true
+	if len(eh.ring) < cap(eh.ring) {
+		eh.ring = append(eh.ring, e)
+	} else {
+		delete(eh.seen, eh.ring[eh.start].ID)
+		eh.ring[eh.start] = e
+		eh.start = (eh.start + 1) % len(eh.ring)
+	}
+	for s := range eh.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			delete(eh.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// buffered events that arrived after the event with the given id oldest first
+// when that event is no longer buffered those with a higher id are returned
+func (eh *EventHub) since(lastID uint64, filter EventFilter) []Event {
+	from := 0
+	for i := 0; i < len(eh.ring); i++ {
+		if eh.ring[(eh.start+i)%len(eh.ring)].ID == lastID {
+			from = i + 1
+			lastID = 0
+			break
+		}
+	}
+	var events []Event
+	for i := from; i < len(eh.ring); i++ {
+		e := eh.ring[(eh.start+i)%len(eh.ring)]
+		if e.ID > lastID && filter.Match(e) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// register a subscriber returning the buffered events after lastID
+// along with the channel for new events
+func (eh *EventHub) Subscribe(lastID uint64, filter EventFilter) ([]Event, *subscriber) {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	s := &subscriber{filter: filter, ch: make(chan Event, subscriberBuffer)}
+	eh.subs[s] = true
+	return eh.since(lastID, filter), s
+}
+
+func (eh *EventHub) Unsubscribe(s *subscriber) {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	if eh.subs[s] {
+		delete(eh.subs, s)
+		close(s.ch)
+	}
+}
+
+// write a single event in server sent events format
+func WriteEvent(w http.ResponseWriter, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b)
+	return err
+}
+
+// build an event filter from the types and cage query parameters
+func EventFilterFor(r *http.Request) (EventFilter, error) {
+	var filter EventFilter
+	if types := r.URL.Query().Get("types"); len(types) != 0 {
+		filter.Types = make(map[string]bool)
+		for _, t := range strings.Split(types, ",") {
+			filter.Types[strings.TrimSpace(t)] = true
+		}
+	}
+	if cage := r.URL.Query().Get("cage"); len(cage) != 0 {
+		id, err := strconv.Atoi(cage)
+		if err != nil || id < 1 {
+			return filter, fmt.Errorf("bad cage parameter must be a cage id")
+		}
+		filter.Cage = id
+	}
+	return filter, nil
+}
+
+// server sent events stream of park changes handler
+// resumes after the Last-Event-ID header when given
+func (ah AppHandlers) Events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || ah.events == nil {
+		WriteMsg(w, http.StatusNotImplemented, "event streaming not supported")
+		return
+	}
+	filter, err := EventFilterFor(r)
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var lastID uint64
+	if last := r.Header.Get("Last-Event-ID"); len(last) != 0 {
+		lastID, err = strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			WriteMsg(w, http.StatusBadRequest, "Last-Event-ID is not a valid event id")
+			return
+		}
+	}
+
+	backlog, sub := ah.events.Subscribe(lastID, filter)
+	defer ah.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for _, e := range backlog {
+		if WriteEvent(w, e) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ah.events.done:
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		case e, ok := <-sub.ch:
+			if !ok {
+				// dropped for falling behind the client should resume
+				return
+			}
+			err = WriteEvent(w, e)
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
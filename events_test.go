@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dinocage/das"
+)
+
+// deliver an event to the hub as the outbox would
+func deliver(hub *EventHub, id uint64, kind string, cage int, data any) {
+	hub.Deliver(context.Background(), Event{ID: id, Type: kind, Cage: cage, Time: time.Now(), Data: data})
+}
+
+func TestEventHubResume(t *testing.T) {
+	hub := NewEventHub(2)
+	deliver(hub, 1, das.EventCageCreated, 1, nil)
+	deliver(hub, 2, das.EventDinoPlaced, 1, nil)
+	deliver(hub, 3, das.EventDinoPlaced, 2, nil)
+	// a redelivered event is ignored
+	deliver(hub, 2, das.EventDinoPlaced, 1, nil)
+
+	// the first event has fallen out of the ring buffer
+	backlog, sub := hub.Subscribe(0, EventFilter{})
+	defer hub.Unsubscribe(sub)
+	if len(backlog) != 2 || backlog[0].ID != 2 || backlog[1].ID != 3 {
+		t.Errorf("unexpected backlog %+v", backlog)
+	}
+
+	backlog, sub2 := hub.Subscribe(2, EventFilter{Cage: 2})
+	defer hub.Unsubscribe(sub2)
+	if len(backlog) != 1 || backlog[0].ID != 3 {
+		t.Errorf("unexpected filtered backlog %+v", backlog)
+	}
+
+	deliver(hub, 4, das.EventCageStatusChanged, 1, nil)
+	select {
+	case e := <-sub.ch:
+		if e.ID != 4 {
+			t.Errorf("expected event 4 got %d", e.ID)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("subscriber did not receive event")
+	}
+	select {
+	case e := <-sub2.ch:
+		t.Errorf("filtered subscriber received event for cage %d", e.Cage)
+	default:
+	}
+}
+
+func TestEventHubOutOfOrder(t *testing.T) {
+	hub := NewEventHub(DefaultEventBuffer)
+	backlog, sub := hub.Subscribe(0, EventFilter{})
+	defer hub.Unsubscribe(sub)
+	if len(backlog) != 0 {
+		t.Errorf("unexpected backlog %+v", backlog)
+	}
+
+	// event 1 commits after event 2 so arrives after it
+	deliver(hub, 2, das.EventDinoPlaced, 1, nil)
+	deliver(hub, 1, das.EventCageCreated, 1, nil)
+	deliver(hub, 2, das.EventDinoPlaced, 1, nil)
+	for _, want := range []uint64{2, 1} {
+		select {
+		case e := <-sub.ch:
+			if e.ID != want {
+				t.Errorf("expected event %d got %d", want, e.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("subscriber did not receive event %d", want)
+		}
+	}
+	select {
+	case e := <-sub.ch:
+		t.Errorf("redelivered event %d reached the subscriber", e.ID)
+	default:
+	}
+
+	// a client that saw event 2 resumes with the events that arrived after it
+	backlog, sub2 := hub.Subscribe(2, EventFilter{})
+	defer hub.Unsubscribe(sub2)
+	if len(backlog) != 1 || backlog[0].ID != 1 {
+		t.Errorf("expected event 1 after event 2 got %+v", backlog)
+	}
+}
+
+// two providers over one sqlite file standing in for two server instances
+func sqlitePeers(t *testing.T) (das.DataAccessProvider, das.DataAccessProvider) {
+	path := filepath.Join(t.TempDir(), "dinocage.db")
+	var peers []das.DataAccessProvider
+	for i := 0; i < 2; i++ {
+		dap, err := das.ConnectSQLite(path, schema)
+		if err != nil {
+			t.Fatalf("unable to open sqlite %v", err)
+		}
+		t.Cleanup(dap.Close)
+		peers = append(peers, dap)
+	}
+	return peers[0], peers[1]
+}
+
+func TestEventHubFollowsPeers(t *testing.T) {
+	ctx := context.Background()
+	mine, theirs := sqlitePeers(t)
+	hub := NewEventHub(DefaultEventBuffer)
+	_, sub := hub.Subscribe(0, EventFilter{})
+	defer hub.Unsubscribe(sub)
+
+	// an event committed by the other instance reaches this hub even once dispatched there
+	err := theirs.Atomic(ctx, func(tx das.Tx) error {
+		return tx.RecordEvent(ctx, das.EventCageCreated, 4, das.Cage{ID: 4})
+	})
+	if err != nil {
+		t.Fatalf("unit of work failed %v", err)
+	}
+	pending, _ := theirs.PendingEvents(ctx, 10)
+	theirs.MarkDispatched(ctx, pending[0].ID)
+	after, err := hub.follow(ctx, mine, das.EventCursor{})
+	if err != nil || after.Tick != 1 {
+		t.Fatalf("follow returned %+v %v", after, err)
+	}
+	select {
+	case e := <-sub.ch:
+		if e.Type != das.EventCageCreated || e.Cage != 4 {
+			t.Errorf("unexpected event %+v", e)
+		}
+	default:
+		t.Errorf("the event committed by the other instance was not delivered")
+	}
+	if after, _ = hub.follow(ctx, mine, after); after.Tick != 1 || len(sub.ch) != 0 {
+		t.Errorf("expected nothing new after %+v", after)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	hub := NewEventHub(DefaultEventBuffer)
+	ah := &AppHandlers{events: hub}
+	svr := httptest.NewServer(http.HandlerFunc(ah.Events))
+	defer svr.Close()
+
+	deliver(hub, 1, das.EventCageCreated, 4, nil)
+	deliver(hub, 2, das.EventSpeciesAdded, 0, das.Species{Name: "compsognathus", Diet: "C"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, "GET", svr.URL+"?types=species.added,dino.added", nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed with %v", err)
+	}
+	r.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("stream request failed with %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+
+	deliver(hub, 3, das.EventCageCreated, 5, nil)
+	deliver(hub, 4, das.EventDinoAdded, 0, nil)
+
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ids) < 2 && scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	if strings.Join(ids, ",") != "2,4" {
+		t.Errorf("expected events 2,4 got %v", ids)
+	}
+}
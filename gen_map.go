@@ -1,6 +1,8 @@
 package main
 
 import (
+	"cmp"
+	"slices"
 	"sync"
 )
 
@@ -10,6 +12,12 @@ type GenMap[K comparable, V any] struct {
 	speciesMap sync.Map
 }
 
+// a key and its value at the time of a snapshot
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
 func (sm *GenMap[K, V]) Store(key K, value V) {
 	sm.speciesMap.Store(key, value)
 }
@@ -23,8 +31,78 @@ func (sm *GenMap[K, V]) Load(key K) (V, bool) {
 
 }
 
+func (sm *GenMap[K, V]) Delete(key K) {
+	sm.speciesMap.Delete(key)
+}
+
+// return the existing value if present otherwise store and return the given value
+// loaded reports whether the value was already present
+func (sm *GenMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	v, loaded := sm.speciesMap.LoadOrStore(key, value)
+	return v.(V), loaded
+}
+
+// delete the value for a key returning the previous value if any
+func (sm *GenMap[K, V]) LoadAndDelete(key K) (V, bool) {
+	var val V
+	if value, loaded := sm.speciesMap.LoadAndDelete(key); loaded {
+		return value.(V), true
+	}
+	return val, false
+}
+
+// store new only if the current value equals old
+// panics when V is not a comparable type
+func (sm *GenMap[K, V]) CompareAndSwap(key K, old, new V) bool {
+	return sm.speciesMap.CompareAndSwap(key, old, new)
+}
+
 func (sm *GenMap[K, V]) Range(f func(key K, value V) bool) {
 	sm.speciesMap.Range(func(k, v any) bool {
 		return f(k.(K), v.(V))
 	})
 }
+
+// number of entries, counted by ranging over the map so it may be
+// out of date by the time it returns when there are concurrent changes
+func (sm *GenMap[K, V]) Len() int {
+	n := 0
+	sm.speciesMap.Range(func(k, v any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// a snapshot of the keys in no particular order
+func (sm *GenMap[K, V]) Keys() []K {
+	var keys []K
+	sm.Range(func(key K, value V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// a snapshot of the values in no particular order
+func (sm *GenMap[K, V]) Values() []V {
+	var values []V
+	sm.Range(func(key K, value V) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// a snapshot of the entries ordered by key
+func SortedEntries[K cmp.Ordered, V any](sm *GenMap[K, V]) []Entry[K, V] {
+	var entries []Entry[K, V]
+	sm.Range(func(key K, value V) bool {
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return true
+	})
+	slices.SortFunc(entries, func(a, b Entry[K, V]) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return entries
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestGenMapOperations(t *testing.T) {
+	m := &GenMap[string, int]{}
+	if v, loaded := m.LoadOrStore("a", 1); loaded || v != 1 {
+		t.Errorf("first LoadOrStore should store got %v %v", v, loaded)
+	}
+	if v, loaded := m.LoadOrStore("a", 2); !loaded || v != 1 {
+		t.Errorf("second LoadOrStore should load got %v %v", v, loaded)
+	}
+	if m.CompareAndSwap("a", 2, 3) {
+		t.Errorf("swap against the wrong value should fail")
+	}
+	if !m.CompareAndSwap("a", 1, 3) {
+		t.Errorf("swap against the current value should succeed")
+	}
+	m.Store("b", 2)
+	m.Store("c", 1)
+	if m.Len() != 3 {
+		t.Errorf("expected 3 entries got %d", m.Len())
+	}
+	keys := m.Keys()
+	slices.Sort(keys)
+	values := m.Values()
+	slices.Sort(values)
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c"}) || !reflect.DeepEqual(values, []int{1, 2, 3}) {
+		t.Errorf("unexpected keys %v values %v", keys, values)
+	}
+	want := []Entry[string, int]{{"a", 3}, {"b", 2}, {"c", 1}}
+	if entries := SortedEntries(m); !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected %v got %v", want, entries)
+	}
+
+	if v, ok := m.LoadAndDelete("a"); !ok || v != 3 {
+		t.Errorf("LoadAndDelete returned %v %v", v, ok)
+	}
+	if _, ok := m.LoadAndDelete("a"); ok {
+		t.Errorf("second LoadAndDelete should find nothing")
+	}
+	m.Delete("b")
+	if _, ok := m.Load("b"); ok || m.Len() != 1 {
+		t.Errorf("expected only c to remain got %v", m.Keys())
+	}
+}
+
+// run under the race detector, every goroutine contends for the same keys
+func TestGenMapConcurrentUse(t *testing.T) {
+	m := &GenMap[int, int]{}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := i % 10
+				switch i % 6 {
+				case 0:
+					m.Store(key, g)
+				case 1:
+					m.LoadOrStore(key, g)
+				case 2:
+					if v, ok := m.Load(key); ok {
+						m.CompareAndSwap(key, v, v+1)
+					}
+				case 3:
+					m.LoadAndDelete(key)
+				case 4:
+					m.Len()
+					m.Keys()
+				default:
+					SortedEntries(m)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := m.Len(); n > 10 {
+		t.Errorf("expected at most 10 entries got %d", n)
+	}
+}
+
+// increments retried with CompareAndSwap are never lost
+func TestGenMapCompareAndSwapCounts(t *testing.T) {
+	m := &GenMap[string, int]{}
+	m.Store("count", 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for {
+					v, _ := m.Load("count")
+					if m.CompareAndSwap("count", v, v+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := m.Load("count"); v != 800 {
+		t.Errorf("expected 800 got %d", v)
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"dinocage/das"
+	"dinocage/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const graphQLSchema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+type Query {
+	cages(status: String): [Cage!]!
+	cage(id: Int!): Cage
+	dinosaurs(species: String): [Dinosaur!]!
+	dinosaur(id: Int!): Dinosaur
+	species: [Species!]!
+}
+
+type Mutation {
+	# place a new dinosaur in a cage chosen by the placement strategy
+	addDinosaur(species: String!, name: String!, strategy: String, autoCreate: Boolean): Boolean!
+	# place a new dinosaur in the given cage returning the cage
+	placeDinosaur(cage: Int!, species: String!, name: String!): Cage!
+	# requires the current cage version as with the rest If-Match header
+	setCageStatus(id: Int!, status: String!, version: Int!, reason: String, actor: String): Cage!
+}
+
+type Cage {
+	id: Int!
+	status: String!
+	capacity: Int!
+	count: Int!
+	kind: String!
+	version: Int!
+	dinosaurs: [Dinosaur!]!
+}
+
+type Dinosaur {
+	id: Int!
+	name: String!
+	diet: String!
+	version: Int!
+	species: Species
+	cage: Cage
+}
+
+type Species {
+	name: String!
+	diet: String!
+	period: String
+	weightKg: Float
+	dangerLevel: Int
+	maxPerCage: Int
+	habitat: String
+	dinosaurs: [Dinosaur!]!
+}
+`
+
+// per request loader batching the keys of every resolved parent
+// keys are queued as parents are resolved and the first load fetches
+// all queued keys in a single call
+type batchLoader[K comparable, V any] struct {
+	mu      sync.Mutex
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	queued  []K
+	loaded  map[K]V
+	fetched map[K]bool
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{fetch: fetch, loaded: make(map[K]V), fetched: make(map[K]bool)}
+}
+
+// queue keys to be fetched together by the next load
+func (bl *batchLoader[K, V]) Prime(keys ...K) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	for _, k := range keys {
+		if !bl.fetched[k] {
+			bl.queued = append(bl.queued, k)
+		}
+	}
+}
+
+// return the value for a key fetching it with all queued keys if not already loaded
+// a key with no value returns the zero value
+func (bl *batchLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	if bl.fetched[key] {
+		return bl.loaded[key], nil
+	}
+	keys := []K{key}
+	seen := map[K]bool{key: true}
+	for _, k := range bl.queued {
+		if !seen[k] && !bl.fetched[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	bl.queued = nil
+	values, err := bl.fetch(ctx, keys)
+	if err != nil {
+		var v V
+		return v, err
+	}
+	for _, k := range keys {
+		bl.fetched[k] = true
+		if v, ok := values[k]; ok {
+			bl.loaded[k] = v
+		}
+	}
+	return bl.loaded[key], nil
+}
+
+// loaders for the nested fields of a single graphql request
+type parkLoaders struct {
+	cageDinosaurs    *batchLoader[int, []das.Dinosaur]
+	dinosaurCage     *batchLoader[int, das.Cage]
+	speciesDinosaurs *batchLoader[string, []das.Dinosaur]
+	species          *batchLoader[string, das.Species]
+}
+
+type loadersKey struct{}
+
+func newParkLoaders(dap das.DataAccessProvider) *parkLoaders {
+	return &parkLoaders{
+		cageDinosaurs: newBatchLoader(func(ctx context.Context, ids []int) (map[int][]das.Dinosaur, error) {
+			dinos, err := dap.GetDinosaursForCages(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byCage := make(map[int][]das.Dinosaur)
+			for _, d := range dinos {
+				byCage[int(d.Cage)] = append(byCage[int(d.Cage)], d)
+			}
+			return byCage, nil
+		}),
+		dinosaurCage: newBatchLoader(func(ctx context.Context, ids []int) (map[int]das.Cage, error) {
+			cages, err := dap.GetCagesByID(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[int]das.Cage)
+			for _, c := range cages {
+				byID[c.ID] = c
+			}
+			return byID, nil
+		}),
+		speciesDinosaurs: newBatchLoader(func(ctx context.Context, names []string) (map[string][]das.Dinosaur, error) {
+			dinos, err := dap.GetDinosaursForSpecies(ctx, names)
+			if err != nil {
+				return nil, err
+			}
+			bySpecies := make(map[string][]das.Dinosaur)
+			for _, d := range dinos {
+				name := strings.ToLower(d.Species)
+				bySpecies[name] = append(bySpecies[name], d)
+			}
+			return bySpecies, nil
+		}),
+		species: newBatchLoader(func(ctx context.Context, names []string) (map[string]das.Species, error) {
+			species, err := dap.ListSpecies(ctx)
+			if err != nil {
+				return nil, err
+			}
+			byName := make(map[string]das.Species)
+			for _, s := range species {
+				byName[s.Name] = s
+			}
+			return byName, nil
+		}),
+	}
+}
+
+func loadersFrom(ctx context.Context) *parkLoaders {
+	return ctx.Value(loadersKey{}).(*parkLoaders)
+}
+
+// root resolver sharing the data access provider and species of the rest handlers
+type graphQLResolver struct {
+	ah *AppHandlers
+}
+
+type cageResolver struct {
+	root *graphQLResolver
+	cage das.Cage
+}
+
+type dinosaurResolver struct {
+	root *graphQLResolver
+	dino das.Dinosaur
+}
+
+type speciesResolver struct {
+	root    *graphQLResolver
+	species das.Species
+}
+
+// wrap cages priming the loader for their dinosaurs
+func (gr *graphQLResolver) cages(ctx context.Context, cages []das.Cage) []*cageResolver {
+	resolvers := make([]*cageResolver, 0, len(cages))
+	ids := make([]int, 0, len(cages))
+	for _, c := range cages {
+		resolvers = append(resolvers, &cageResolver{root: gr, cage: c})
+		ids = append(ids, c.ID)
+	}
+	loadersFrom(ctx).cageDinosaurs.Prime(ids...)
+	return resolvers
+}
+
+// wrap dinosaurs priming the loader for their cages
+func (gr *graphQLResolver) dinosaurs(ctx context.Context, dinos []das.Dinosaur) []*dinosaurResolver {
+	resolvers := make([]*dinosaurResolver, 0, len(dinos))
+	ids := make([]int, 0, len(dinos))
+	for _, d := range dinos {
+		resolvers = append(resolvers, &dinosaurResolver{root: gr, dino: d})
+		if d.Cage != 0 {
+			ids = append(ids, int(d.Cage))
+		}
+	}
+	loadersFrom(ctx).dinosaurCage.Prime(ids...)
+	return resolvers
+}
+
+func (gr *graphQLResolver) Cages(ctx context.Context, args struct{ Status *string }) ([]*cageResolver, error) {
+	var filter []string
+	if args.Status != nil {
+		if !das.ValidStatus(*args.Status) {
+			return nil, fmt.Errorf("invalid status %s", *args.Status)
+		}
+		filter = append(filter, *args.Status)
+	}
+	cages, err := gr.ah.dap.GetCages(ctx, filter...)
+	if err != nil {
+		return nil, err
+	}
+	return gr.cages(ctx, cages), nil
+}
+
+func (gr *graphQLResolver) Cage(ctx context.Context, args struct{ ID int32 }) (*cageResolver, error) {
+	cage, err := gr.ah.dap.GetCage(ctx, int(args.ID))
+	if errors.Is(err, das.ErrCageNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gr.cages(ctx, []das.Cage{cage})[0], nil
+}
+
+func (gr *graphQLResolver) Dinosaurs(ctx context.Context, args struct{ Species *string }) ([]*dinosaurResolver, error) {
+	var dinos []das.Dinosaur
+	var err error
+	if args.Species != nil {
+		dinos, err = gr.ah.dap.GetDinosaurs(ctx, *args.Species)
+	} else {
+		dinos, err = gr.ah.dap.GetDinosaurs(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gr.dinosaurs(ctx, dinos), nil
+}
+
+func (gr *graphQLResolver) Dinosaur(ctx context.Context, args struct{ ID int32 }) (*dinosaurResolver, error) {
+	dino, err := gr.ah.dap.GetDinosaur(ctx, int(args.ID))
+	if errors.Is(err, das.ErrDinosaurNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gr.dinosaurs(ctx, []das.Dinosaur{dino})[0], nil
+}
+
+func (gr *graphQLResolver) Species(ctx context.Context) ([]*speciesResolver, error) {
+	species, err := gr.ah.park.ListSpecies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resolvers []*speciesResolver
+	var names []string
+	for _, s := range species {
+		resolvers = append(resolvers, &speciesResolver{root: gr, species: s})
+		names = append(names, s.Name)
+	}
+	loadersFrom(ctx).speciesDinosaurs.Prime(names...)
+	return resolvers, nil
+}
+
+// look up the diet of a known species
+func (gr *graphQLResolver) speciesDiet(ctx context.Context, species string) (string, error) {
+	s, err := gr.ah.park.Species(ctx, species)
+	if errors.Is(err, service.ErrUnknownSpecies) {
+		return "", fmt.Errorf("unknown species %s", species)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(s.Diet), nil
+}
+
+func (gr *graphQLResolver) AddDinosaur(ctx context.Context, args struct {
+	Species    string
+	Name       string
+	Strategy   *string
+	AutoCreate *bool
+}) (bool, error) {
+	diet, err := gr.speciesDiet(ctx, args.Species)
+	if err != nil {
+		return false, err
+	}
+	opts := gr.ah.park.Placement()
+	if args.Strategy != nil {
+		if !das.ValidStrategy(*args.Strategy) {
+			return false, fmt.Errorf("unknown placement strategy %s", *args.Strategy)
+		}
+		opts.Strategy = *args.Strategy
+	}
+	if args.AutoCreate != nil {
+		opts.AutoCreate = *args.AutoCreate
+	}
+	_, err = gr.ah.park.AddDinosaur(ctx, das.Dinosaur{Species: args.Species, Name: args.Name, Diet: diet}, opts)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (gr *graphQLResolver) PlaceDinosaur(ctx context.Context, args struct {
+	Cage    int32
+	Species string
+	Name    string
+}) (*cageResolver, error) {
+	diet, err := gr.speciesDiet(ctx, args.Species)
+	if err != nil {
+		return nil, err
+	}
+	cageID := int(args.Cage)
+	_, err = gr.ah.park.PlaceDinosaur(ctx, cageID, das.Dinosaur{Species: args.Species, Name: args.Name, Diet: diet})
+	if err != nil {
+		return nil, err
+	}
+	cage, err := gr.ah.dap.GetCage(ctx, cageID)
+	if err != nil {
+		return nil, err
+	}
+	return gr.cages(ctx, []das.Cage{cage})[0], nil
+}
+
+func (gr *graphQLResolver) SetCageStatus(ctx context.Context, args struct {
+	ID      int32
+	Status  string
+	Version int32
+	Reason  *string
+	Actor   *string
+}) (*cageResolver, error) {
+	if !das.ValidStatus(args.Status) {
+		return nil, fmt.Errorf("invalid status %s", args.Status)
+	}
+	change := das.StatusChange{Status: args.Status, Version: int(args.Version)}
+	if args.Reason != nil {
+		change.Reason = *args.Reason
+	}
+	if args.Actor != nil {
+		change.Actor = *args.Actor
+	}
+	cageID := int(args.ID)
+	cage, err := gr.ah.park.SetCageStatus(ctx, cageID, change)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set cage %d status to %s : %w", cageID, args.Status, err)
+	}
+	return gr.cages(ctx, []das.Cage{cage})[0], nil
+}
+
+func (cr *cageResolver) ID() int32       { return int32(cr.cage.ID) }
+func (cr *cageResolver) Status() string  { return cr.cage.Status }
+func (cr *cageResolver) Capacity() int32 { return int32(cr.cage.Capacity) }
+func (cr *cageResolver) Count() int32    { return int32(cr.cage.Count) }
+func (cr *cageResolver) Kind() string    { return cr.cage.Kind }
+func (cr *cageResolver) Version() int32  { return int32(cr.cage.Version) }
+
+func (cr *cageResolver) Dinosaurs(ctx context.Context) ([]*dinosaurResolver, error) {
+	dinos, err := loadersFrom(ctx).cageDinosaurs.Load(ctx, cr.cage.ID)
+	if err != nil {
+		return nil, err
+	}
+	return cr.root.dinosaurs(ctx, dinos), nil
+}
+
+func (dr *dinosaurResolver) ID() int32      { return int32(dr.dino.ID) }
+func (dr *dinosaurResolver) Name() string   { return dr.dino.Name }
+func (dr *dinosaurResolver) Diet() string   { return dr.dino.Diet }
+func (dr *dinosaurResolver) Version() int32 { return int32(dr.dino.Version) }
+
+func (dr *dinosaurResolver) Species(ctx context.Context) (*speciesResolver, error) {
+	name := strings.ToLower(dr.dino.Species)
+	species, err := loadersFrom(ctx).species.Load(ctx, name)
+	if err != nil || len(species.Name) == 0 {
+		return nil, err
+	}
+	loadersFrom(ctx).speciesDinosaurs.Prime(name)
+	return &speciesResolver{root: dr.root, species: species}, nil
+}
+
+func (dr *dinosaurResolver) Cage(ctx context.Context) (*cageResolver, error) {
+	if dr.dino.Cage == 0 {
+		return nil, nil
+	}
+	cage, err := loadersFrom(ctx).dinosaurCage.Load(ctx, int(dr.dino.Cage))
+	if err != nil {
+		return nil, err
+	}
+	if cage.ID == 0 {
+		return nil, nil
+	}
+	return dr.root.cages(ctx, []das.Cage{cage})[0], nil
+}
+
+func (sr *speciesResolver) Name() string { return sr.species.Name }
+func (sr *speciesResolver) Diet() string { return sr.species.Diet }
+
+// details a species leaves unset resolve to null
+func (sr *speciesResolver) Period() *string     { return optional(sr.species.Period) }
+func (sr *speciesResolver) WeightKg() *float64  { return optional(sr.species.WeightKg) }
+func (sr *speciesResolver) DangerLevel() *int32 { return optional(int32(sr.species.DangerLevel)) }
+func (sr *speciesResolver) MaxPerCage() *int32  { return optional(int32(sr.species.MaxPerCage)) }
+func (sr *speciesResolver) Habitat() *string    { return optional(sr.species.Habitat) }
+
+func optional[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+func (sr *speciesResolver) Dinosaurs(ctx context.Context) ([]*dinosaurResolver, error) {
+	dinos, err := loadersFrom(ctx).speciesDinosaurs.Load(ctx, sr.species.Name)
+	if err != nil {
+		return nil, err
+	}
+	return sr.root.dinosaurs(ctx, dinos), nil
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphql endpoint handler
+type GraphQLHandler struct {
+	schema   *graphql.Schema
+	resolver *graphQLResolver
+}
+
+func NewGraphQLHandler(appHandlers *AppHandlers) *GraphQLHandler {
+	resolver := &graphQLResolver{ah: appHandlers}
+	return &GraphQLHandler{
+		schema:   graphql.MustParseSchema(graphQLSchema, resolver),
+		resolver: resolver,
+	}
+}
+
+// execute a graphql request with fresh loaders so batches never span requests
+func (gh *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	defer r.Body.Close()
+	err := DecodeJSON(w, r, &req)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	ctx := context.WithValue(r.Context(), loadersKey{}, newParkLoaders(gh.resolver.ah.dap))
+	resp := gh.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	b, err := json.Marshal(resp)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	WriteMsg(w, http.StatusOK, string(b))
+}
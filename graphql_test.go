@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"dinocage/das"
+	"dinocage/mocks"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+type graphQLResult struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// post a query to the graphql handler returning the decoded result
+func execGraphQL(t *testing.T, ah *AppHandlers, query string, variables map[string]any) graphQLResult {
+	body, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	r := httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	NewGraphQLHandler(ah).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("graphql request failed with status %d", w.Code)
+	}
+	var res graphQLResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unable to decode graphql response %v", err)
+	}
+	return res
+}
+
+func sortedIDs(ids []int) []int {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func TestGraphQLCagesBatchesDinosaurs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, das.Species{Name: "tyrannosaurus", Diet: das.CarnivoreCode})
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+
+	mockDap.EXPECT().GetCages(gomock.Any()).Return([]das.Cage{
+		{ID: 1, Status: das.StatusActive, Capacity: 4, Count: 2, Kind: das.CarnivoreCode},
+		{ID: 2, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode},
+		{ID: 3, Status: das.StatusActive, Capacity: 4, Kind: das.HerbivoreCode},
+	}, nil)
+	// one query for the dinosaurs of every cage and one for their cages
+	mockDap.EXPECT().GetDinosaursForCages(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ids []int) ([]das.Dinosaur, error) {
+		if got := sortedIDs(ids); len(got) != 3 || got[0] != 1 || got[2] != 3 {
+			t.Errorf("expected a single batch for all cages got %v", ids)
+		}
+		return []das.Dinosaur{
+			{ID: 10, Species: "Tyrannosaurus", Name: "rex", Diet: das.CarnivoreCode, Cage: 1},
+			{ID: 11, Species: "Tyrannosaurus", Name: "sue", Diet: das.CarnivoreCode, Cage: 1},
+			{ID: 12, Species: "Tyrannosaurus", Name: "stan", Diet: das.CarnivoreCode, Cage: 2},
+		}, nil
+	}).Times(1)
+
+	res := execGraphQL(t, ah, `{ cages { id dinosaurs { name species { diet } } } }`, nil)
+	if len(res.Errors) != 0 {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+	var data struct {
+		Cages []struct {
+			ID        int
+			Dinosaurs []struct {
+				Name    string
+				Species struct{ Diet string }
+			}
+		}
+	}
+	json.Unmarshal(res.Data, &data)
+	if len(data.Cages) != 3 || len(data.Cages[0].Dinosaurs) != 2 || len(data.Cages[1].Dinosaurs) != 1 || len(data.Cages[2].Dinosaurs) != 0 {
+		t.Errorf("unexpected cages %s", res.Data)
+	}
+	if data.Cages[1].Dinosaurs[0].Name != "stan" || data.Cages[1].Dinosaurs[0].Species.Diet != das.CarnivoreCode {
+		t.Errorf("unexpected dinosaur %+v", data.Cages[1].Dinosaurs[0])
+	}
+}
+
+func TestGraphQLSpeciesDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap,
+		das.Species{Name: "dodo", Diet: das.HerbivoreCode},
+		das.Species{Name: "velociraptor", Diet: das.CarnivoreCode, Period: "cretaceous", WeightKg: 15.5, DangerLevel: 4, MaxPerCage: 2, Habitat: "scrub"},
+	)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+
+	res := execGraphQL(t, ah, `{ species { name period weightKg dangerLevel maxPerCage habitat } }`, nil)
+	if len(res.Errors) != 0 {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+	var data struct {
+		Species []struct {
+			Name        string
+			Period      *string
+			WeightKg    *float64
+			DangerLevel *int
+			MaxPerCage  *int
+			Habitat     *string
+		}
+	}
+	json.Unmarshal(res.Data, &data)
+	if len(data.Species) != 2 {
+		t.Fatalf("expected two species got %s", res.Data)
+	}
+	// unset details are null
+	dodo, raptor := data.Species[0], data.Species[1]
+	if dodo.Period != nil || dodo.WeightKg != nil || dodo.MaxPerCage != nil {
+		t.Errorf("expected no details for %s got %s", dodo.Name, res.Data)
+	}
+	if raptor.Period == nil || *raptor.Period != "cretaceous" || *raptor.WeightKg != 15.5 || *raptor.DangerLevel != 4 || *raptor.MaxPerCage != 2 || *raptor.Habitat != "scrub" {
+		t.Errorf("expected the details of %s got %s", raptor.Name, res.Data)
+	}
+}
+
+func TestGraphQLDinosaurCages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+
+	mockDap.EXPECT().GetDinosaurs(gomock.Any()).Return([]das.Dinosaur{
+		{ID: 10, Name: "rex", Cage: 1},
+		{ID: 11, Name: "sue", Cage: 2},
+		{ID: 12, Name: "stan", Cage: 1},
+	}, nil)
+	mockDap.EXPECT().GetCagesByID(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ids []int) ([]das.Cage, error) {
+		if got := sortedIDs(ids); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+			t.Errorf("expected a single batch of distinct cages got %v", ids)
+		}
+		return []das.Cage{{ID: 1, Status: das.StatusActive}, {ID: 2, Status: das.StatusDown}}, nil
+	}).Times(1)
+
+	res := execGraphQL(t, ah, `{ dinosaurs { name cage { id status } } }`, nil)
+	if len(res.Errors) != 0 {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+	if !strings.Contains(string(res.Data), `{"name":"sue","cage":{"id":2,"status":"DOWN"}}`) {
+		t.Errorf("unexpected dinosaurs %s", res.Data)
+	}
+}
+
+func TestGraphQLSetCageStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetCage(gomock.Any(), 5).Return(das.Cage{ID: 5, Status: das.StatusActive, Version: 2}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), das.Cage{ID: 5, Status: das.StatusMaintenance, Version: 2}).Return(das.Cage{ID: 5, Status: das.StatusMaintenance, Version: 3}, nil)
+	tx.EXPECT().AddStatusTransition(gomock.Any(), gomock.Any()).Return(nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageStatusChanged, 5, gomock.Any()).Return(nil)
+
+	query := `mutation ($id: Int!, $version: Int!) { setCageStatus(id: $id, status: "MAINTENANCE", version: $version, reason: "fence") { status version } }`
+	res := execGraphQL(t, ah, query, map[string]any{"id": 5, "version": 2})
+	if len(res.Errors) != 0 || string(res.Data) != `{"setCageStatus":{"status":"MAINTENANCE","version":3}}` {
+		t.Errorf("unexpected response %s %+v", res.Data, res.Errors)
+	}
+
+	tx.EXPECT().GetCage(gomock.Any(), 5).Return(das.Cage{ID: 5, Status: das.StatusMaintenance, Version: 3}, nil)
+	res = execGraphQL(t, ah, query, map[string]any{"id": 5, "version": 2})
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, das.ErrVersionMismatch.Error()) {
+		t.Errorf("expected version mismatch error got %+v", res.Errors)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"dinocage/das"
+	pb "dinocage/dinocagepb"
+	"dinocage/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const DefaultGrpcEndpoint = ":9000"
+
+// grpc park service sharing the data access provider and species of the rest handlers
+type ParkServer struct {
+	pb.UnimplementedParkServer
+	ah *AppHandlers
+}
+
+func NewParkServer(appHandlers *AppHandlers) *ParkServer {
+	return &ParkServer{ah: appHandlers}
+}
+
+// map data access errors to a grpc status falling back to the given default code
+func GrpcError(err error, def codes.Code) error {
+	code := def
+	switch {
+	case errors.Is(err, das.ErrCageNotFound), errors.Is(err, das.ErrDinosaurNotFound):
+		code = codes.NotFound
+	case errors.Is(err, das.ErrVersionMismatch):
+		code = codes.Aborted
+	case errors.Is(err, das.ErrIllegalTransition), errors.Is(err, das.ErrCageNotEmpty), errors.Is(err, das.ErrNoCapacity),
+		errors.Is(err, service.ErrDietMismatch), errors.Is(err, service.ErrNotAdmitting), errors.Is(err, service.ErrSpeciesInUse):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, service.ErrUnknownSpecies):
+		code = codes.InvalidArgument
+	}
+	// field problems travel as bad request details so clients see every one
+	var ve *service.ValidationError
+	if errors.As(err, &ve) {
+		br := &errdetails.BadRequest{}
+		for _, f := range ve.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		if st, derr := status.New(code, err.Error()).WithDetails(br); derr == nil {
+			return st.Err()
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+func speciesToPb(s das.Species) *pb.Species {
+	return &pb.Species{
+		Name:        s.Name,
+		Diet:        s.Diet,
+		Period:      s.Period,
+		WeightKg:    s.WeightKg,
+		DangerLevel: int32(s.DangerLevel),
+		MaxPerCage:  int32(s.MaxPerCage),
+		Habitat:     s.Habitat,
+	}
+}
+
+func speciesFromPb(s *pb.Species) das.Species {
+	return das.Species{
+		Name:        s.GetName(),
+		Diet:        s.GetDiet(),
+		Period:      s.GetPeriod(),
+		WeightKg:    s.GetWeightKg(),
+		DangerLevel: int(s.GetDangerLevel()),
+		MaxPerCage:  int(s.GetMaxPerCage()),
+		Habitat:     s.GetHabitat(),
+	}
+}
+
+func cageToPb(c das.Cage) *pb.Cage {
+	return &pb.Cage{
+		Id:       uint32(c.ID),
+		Status:   c.Status,
+		Capacity: int32(c.Capacity),
+		Count:    int32(c.Count),
+		Kind:     c.Kind,
+		Version:  int32(c.Version),
+	}
+}
+
+func dinosaurToPb(d das.Dinosaur) *pb.Dinosaur {
+	return &pb.Dinosaur{
+		Id:      uint32(d.ID),
+		Species: d.Species,
+		Name:    d.Name,
+		Diet:    d.Diet,
+		Cage:    uint32(d.Cage),
+		Version: int32(d.Version),
+	}
+}
+
+func dinosaurFromPb(d *pb.Dinosaur) das.Dinosaur {
+	return das.Dinosaur{
+		Species: d.GetSpecies(),
+		Name:    d.GetName(),
+		Diet:    d.GetDiet(),
+	}
+}
+
+func eventToPb(e Event) (*pb.Event, error) {
+	ev := &pb.Event{
+		Id:   e.ID,
+		Type: e.Type,
+		Cage: int32(e.Cage),
+		Time: timestamppb.New(e.Time),
+	}
+	if e.Data != nil {
+		b, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		ev.Data = string(b)
+	}
+	return ev, nil
+}
+
+func (ps *ParkServer) AddSpecies(ctx context.Context, req *pb.Species) (*pb.Species, error) {
+	species, err := ps.ah.park.AddSpecies(ctx, speciesFromPb(req))
+	if err != nil {
+		return nil, GrpcError(err, codes.Internal)
+	}
+	return speciesToPb(species), nil
+}
+
+func (ps *ParkServer) ListSpecies(req *pb.ListSpeciesRequest, stream pb.Park_ListSpeciesServer) error {
+	species, err := ps.ah.park.ListSpecies(stream.Context())
+	if err != nil {
+		return GrpcError(err, codes.Internal)
+	}
+	for _, s := range species {
+		err := stream.Send(speciesToPb(s))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ps *ParkServer) AddCage(ctx context.Context, req *pb.AddCageRequest) (*pb.Cage, error) {
+	capacity := int(req.GetCapacity())
+	if capacity == 0 {
+		capacity = das.CageCapacity
+	}
+	cage, err := ps.ah.park.AddCage(ctx, req.GetDiet(), capacity)
+	if err != nil {
+		return nil, GrpcError(err, codes.Internal)
+	}
+	return cageToPb(cage), nil
+}
+
+func (ps *ParkServer) GetCage(ctx context.Context, req *pb.GetCageRequest) (*pb.Cage, error) {
+	cage, err := ps.ah.dap.GetCage(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, GrpcError(err, codes.Internal)
+	}
+	return cageToPb(cage), nil
+}
+
+func (ps *ParkServer) ListCages(req *pb.ListCagesRequest, stream pb.Park_ListCagesServer) error {
+	var filter []string
+	if s := req.GetStatus(); len(s) != 0 {
+		if !das.ValidStatus(s) {
+			return status.Errorf(codes.InvalidArgument, "invalid status %s", s)
+		}
+		filter = append(filter, s)
+	}
+	cages, err := ps.ah.dap.GetCages(stream.Context(), filter...)
+	if err != nil {
+		return GrpcError(err, codes.Internal)
+	}
+	for _, c := range cages {
+		if err := stream.Send(cageToPb(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ps *ParkServer) SetCageStatus(ctx context.Context, req *pb.SetCageStatusRequest) (*pb.Cage, error) {
+	if !das.ValidStatus(req.GetStatus()) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status %s", req.GetStatus())
+	}
+	if req.GetVersion() < 1 {
+		return nil, status.Error(codes.FailedPrecondition, "the current cage version is required")
+	}
+	change := das.StatusChange{
+		Status:  req.GetStatus(),
+		Reason:  req.GetReason(),
+		Actor:   req.GetActor(),
+		Version: int(req.GetVersion()),
+	}
+	cageID := int(req.GetId())
+	cage, err := ps.ah.park.SetCageStatus(ctx, cageID, change)
+	if err != nil {
+		return nil, GrpcError(fmt.Errorf("unable to set cage %d status to %s : %w", cageID, req.GetStatus(), err), codes.Internal)
+	}
+	return cageToPb(cage), nil
+}
+
+func (ps *ParkServer) AddDinosaur(ctx context.Context, req *pb.AddDinosaurRequest) (*pb.AddDinosaurResponse, error) {
+	opts := ps.ah.park.Placement()
+	if s := req.GetStrategy(); len(s) != 0 {
+		if !das.ValidStrategy(s) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown placement strategy %s", s)
+		}
+		opts.Strategy = s
+	}
+	if req.AutoCreate != nil {
+		opts.AutoCreate = req.GetAutoCreate()
+	}
+	dino := dinosaurFromPb(req.GetDinosaur())
+	if _, err := ps.ah.park.AddDinosaur(ctx, dino, opts); err != nil {
+		return nil, GrpcError(err, codes.FailedPrecondition)
+	}
+	return &pb.AddDinosaurResponse{}, nil
+}
+
+func (ps *ParkServer) PlaceDinosaur(ctx context.Context, req *pb.PlaceDinosaurRequest) (*pb.AddDinosaurResponse, error) {
+	dino := dinosaurFromPb(req.GetDinosaur())
+	if _, err := ps.ah.park.PlaceDinosaur(ctx, int(req.GetCage()), dino); err != nil {
+		return nil, GrpcError(err, codes.FailedPrecondition)
+	}
+	return &pb.AddDinosaurResponse{}, nil
+}
+
+func (ps *ParkServer) GetDinosaur(ctx context.Context, req *pb.GetDinosaurRequest) (*pb.Dinosaur, error) {
+	dino, err := ps.ah.dap.GetDinosaur(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, GrpcError(err, codes.Internal)
+	}
+	return dinosaurToPb(dino), nil
+}
+
+func (ps *ParkServer) ListDinosaurs(req *pb.ListDinosaursRequest, stream pb.Park_ListDinosaursServer) error {
+	var dinos []das.Dinosaur
+	var err error
+	switch {
+	case req.GetCage() != 0:
+		dinos, err = ps.ah.dap.GetDinosaursForCage(stream.Context(), int(req.GetCage()))
+	case len(req.GetSpecies()) != 0:
+		dinos, err = ps.ah.dap.GetDinosaurs(stream.Context(), req.GetSpecies())
+	default:
+		dinos, err = ps.ah.dap.GetDinosaurs(stream.Context())
+	}
+	if err != nil {
+		return GrpcError(err, codes.Internal)
+	}
+	for _, d := range dinos {
+		if len(req.GetSpecies()) != 0 && !strings.EqualFold(d.Species, req.GetSpecies()) {
+			continue
+		}
+		if err := stream.Send(dinosaurToPb(d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stream buffered events after the last seen id followed by new events
+// the stream ends if the client falls behind and must resume
+func (ps *ParkServer) Watch(req *pb.WatchRequest, stream pb.Park_WatchServer) error {
+	hub := ps.ah.events
+	if hub == nil {
+		return status.Error(codes.Unimplemented, "event streaming not supported")
+	}
+	filter := EventFilter{Cage: int(req.GetCage())}
+	for _, t := range req.GetTypes() {
+		if !das.ValidEventType(t) {
+			return status.Errorf(codes.InvalidArgument, "unknown event type %s", t)
+		}
+		if filter.Types == nil {
+			filter.Types = make(map[string]bool)
+		}
+		filter.Types[t] = true
+	}
+	backlog, sub := hub.Subscribe(req.GetLastEventId(), filter)
+	defer hub.Unsubscribe(sub)
+	send := func(e Event) error {
+		ev, err := eventToPb(e)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		return stream.Send(ev)
+	}
+	for _, e := range backlog {
+		if err := send(e); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-hub.done:
+			return nil
+		case e, ok := <-sub.ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind resume from the last event id")
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// create grpc server and serve until the context is done
+func StartGrpcServer(ctx context.Context, listenAddr string, appHandlers *AppHandlers) error {
+	lis, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return err
+	}
+	return ServeGrpc(ctx, lis, appHandlers)
+}
+
+// give each call a database session so it reads its own writes
+func databaseSession(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(das.WithSession(ctx), req)
+}
+
+// serve the park service on an existing listener
+func ServeGrpc(ctx context.Context, lis net.Listener, appHandlers *AppHandlers) error {
+	server := grpc.NewServer(grpc.UnaryInterceptor(databaseSession))
+	pb.RegisterParkServer(server, NewParkServer(appHandlers))
+
+	// prepare for shutdown initiated from context
+	go func() {
+		<-ctx.Done()
+		// end any watch streams so they do not hold up shutdown
+		if appHandlers.events != nil {
+			appHandlers.events.Close()
+		}
+		server.GracefulStop()
+	}()
+	log.Printf("Starting grpc serv on %s", lis.Addr())
+	return server.Serve(lis)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"dinocage/das"
+	pb "dinocage/dinocagepb"
+	"dinocage/mocks"
+
+	gomock "github.com/golang/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve the park service in memory returning a connected client
+func grpcClient(t *testing.T, ah *AppHandlers) pb.ParkClient {
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ServeGrpc(ctx, lis, ah)
+		close(done)
+	}()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to dial grpc server %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		<-done
+	})
+	return pb.NewParkClient(conn)
+}
+
+func TestGrpcGetCageNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	mockDap.EXPECT().GetCage(gomock.Any(), 7).Return(das.Cage{}, das.ErrCageNotFound)
+
+	client := grpcClient(t, &AppHandlers{dap: mockDap, events: NewEventHub(DefaultEventBuffer)})
+	_, err := client.GetCage(context.Background(), &pb.GetCageRequest{Id: 7})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected not found got %v", err)
+	}
+}
+
+func TestGrpcListCages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	mockDap.EXPECT().GetCages(gomock.Any(), das.StatusActive).Return([]das.Cage{
+		{ID: 1, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode, Version: 2},
+		{ID: 2, Status: das.StatusActive, Capacity: 4, Kind: das.HerbivoreCode, Version: 1},
+	}, nil)
+
+	client := grpcClient(t, &AppHandlers{dap: mockDap, events: NewEventHub(DefaultEventBuffer)})
+	stream, err := client.ListCages(context.Background(), &pb.ListCagesRequest{Status: das.StatusActive})
+	if err != nil {
+		t.Fatalf("list cages failed %v", err)
+	}
+	var ids []uint32
+	for {
+		cage, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("list cages stream failed %v", err)
+		}
+		ids = append(ids, cage.Id)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("unexpected cages %v", ids)
+	}
+
+	// stream errors are reported on the first receive
+	stream, err = client.ListCages(context.Background(), &pb.ListCagesRequest{Status: "OPEN"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected invalid status to fail got %v", err)
+	}
+}
+
+func TestGrpcAddDinosaur(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, das.Species{Name: "tyrannosaurus", Diet: das.CarnivoreCode})
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+	ah.events = NewEventHub(DefaultEventBuffer)
+
+	// no cage has room so one is created
+	tx := mockWork(ctrl, mockDap)
+	created := das.Cage{ID: 8, Status: das.StatusActive, Capacity: das.CageCapacity, Kind: das.CarnivoreCode, Version: 1}
+	rex := das.Dinosaur{Species: "tyrannosaurus", Name: "rex", Diet: das.CarnivoreCode, Cage: 8}
+	tx.EXPECT().FindCages(gomock.Any(), das.StatusActive, das.CarnivoreCode).Return(nil, nil)
+	tx.EXPECT().InsertCage(gomock.Any(), gomock.Any()).Return(created, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageCreated, 8, created).Return(nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(created, nil)
+	tx.EXPECT().InsertDinosaur(gomock.Any(), rex).Return(rex, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoAdded, 8, rex).Return(nil)
+
+	client := grpcClient(t, ah)
+	autoCreate := true
+	_, err := client.AddDinosaur(context.Background(), &pb.AddDinosaurRequest{
+		Dinosaur:   &pb.Dinosaur{Species: "Tyrannosaurus", Name: "rex", Diet: das.CarnivoreCode},
+		AutoCreate: &autoCreate,
+	})
+	if err != nil {
+		t.Errorf("add dinosaur failed %v", err)
+	}
+
+	_, err = client.AddDinosaur(context.Background(), &pb.AddDinosaurRequest{Dinosaur: &pb.Dinosaur{Species: "dodo"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected unknown species to be invalid got %v", err)
+	}
+}
+
+func TestGrpcSetCageStatusVersionMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+	ah.events = NewEventHub(DefaultEventBuffer)
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetCage(gomock.Any(), 4).Return(das.Cage{ID: 4, Status: das.StatusActive, Version: 5}, nil)
+
+	client := grpcClient(t, ah)
+	_, err := client.SetCageStatus(context.Background(), &pb.SetCageStatusRequest{Id: 4, Status: das.StatusDown, Version: 3, Reason: "repairs"})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("expected aborted got %v", err)
+	}
+}
+
+func TestGrpcWatch(t *testing.T) {
+	hub := NewEventHub(DefaultEventBuffer)
+	deliver(hub, 1, das.EventCageCreated, 1, nil)
+	deliver(hub, 2, das.EventDinoPlaced, 2, map[string]string{"name": "rex"})
+
+	client := grpcClient(t, &AppHandlers{events: hub})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &pb.WatchRequest{Cage: 2})
+	if err != nil {
+		t.Fatalf("watch failed %v", err)
+	}
+	e, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("watch stream failed %v", err)
+	}
+	if e.Id != 2 || e.Data != `{"name":"rex"}` {
+		t.Errorf("unexpected backlog event %+v", e)
+	}
+
+	// the backlog is sent after subscribing so live events follow
+	deliver(hub, 3, das.EventCageStatusChanged, 1, nil)
+	deliver(hub, 4, das.EventCageStatusChanged, 2, nil)
+	e, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("watch stream failed %v", err)
+	}
+	if e.Id != 4 || e.Type != das.EventCageStatusChanged {
+		t.Errorf("unexpected live event %+v", e)
+	}
+}
+
+func TestGrpcAddSpeciesKeepsDetails(t *testing.T) {
+	client := grpcClient(t, NewAppHandlers(sqliteProvider(t), das.DefaultPlacement()))
+	ctx := context.Background()
+
+	raptor := &pb.Species{Name: "velociraptor", Diet: das.CarnivoreCode, Period: "cretaceous", WeightKg: 15.5, DangerLevel: 4, MaxPerCage: 2, Habitat: "scrub"}
+	if _, err := client.AddSpecies(ctx, raptor); err != nil {
+		t.Fatalf("add species failed %v", err)
+	}
+	// adding it again with only a name and diet keeps the stored details
+	added, err := client.AddSpecies(ctx, &pb.Species{Name: "Velociraptor", Diet: das.CarnivoreCode, DangerLevel: 5})
+	if err != nil {
+		t.Fatalf("add species again failed %v", err)
+	}
+	if added.GetPeriod() != "cretaceous" || added.GetWeightKg() != 15.5 || added.GetDangerLevel() != 5 || added.GetMaxPerCage() != 2 || added.GetHabitat() != "scrub" {
+		t.Errorf("expected the stored details kept got %v", added)
+	}
+	stream, err := client.ListSpecies(ctx, &pb.ListSpeciesRequest{})
+	if err != nil {
+		t.Fatalf("list species failed %v", err)
+	}
+	listed, err := stream.Recv()
+	if err != nil || listed.GetMaxPerCage() != 2 || listed.GetDangerLevel() != 5 {
+		t.Errorf("expected the listed species to have its details got %v %v", listed, err)
+	}
+}
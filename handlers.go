@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	. "dinocage/das"
+	"dinocage/service"
 
 	"github.com/gorilla/mux"
 )
@@ -21,23 +25,18 @@ var (
 // Core application data structure
 type AppHandlers struct {
 	dap        DataAccessProvider
-	speciesMap *GenMap[string, string]
+	park       *service.ParkService
+	idempotent *IdempotencyStore
+	events     *EventHub
+	cache      *CachedProvider
 }
 
-// check species against in memory species list
-func (ah AppHandlers) CheckSpecies(speciesName string) bool {
-	_, ok := ah.speciesMap.Load(speciesName)
-	return ok
-}
-
-// add species to in memory list
-func (ah AppHandlers) NewSpecies(name, diet string) bool {
-	diet = strings.ToUpper(diet)
-	if diet != HerbivoreCode && diet != CarnivoreCode {
-		return false
+// create handlers applying park rules through a service over the data access provider
+func NewAppHandlers(dap DataAccessProvider, placement PlacementOptions) *AppHandlers {
+	return &AppHandlers{
+		dap:  dap,
+		park: service.NewParkService(dap, dap, placement),
 	}
-	ah.speciesMap.Store(strings.ToLower(name), diet)
-	return true
 }
 
 // write message back to client utility
@@ -58,31 +57,124 @@ func WriteOk(w http.ResponseWriter) {
 	}
 }
 
+// map data access errors to an http status falling back to the given default
+func StatusForError(err error, def int) int {
+	switch {
+	case errors.Is(err, ErrCageNotFound), errors.Is(err, ErrDinosaurNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrIllegalTransition), errors.Is(err, ErrCageNotEmpty), errors.Is(err, ErrNoCapacity),
+		errors.Is(err, service.ErrDietMismatch), errors.Is(err, service.ErrNotAdmitting), errors.Is(err, service.ErrSpeciesInUse):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, service.ErrUnknownSpecies):
+		return http.StatusBadRequest
+	default:
+		return def
+	}
+}
+
+// format a resource version as an entity tag
+func ETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// read the version required by the If-Match header
+// writes 428 if the header is missing or 400 if it is not a version etag
+func IfMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	tag := r.Header.Get("If-Match")
+	if len(tag) == 0 {
+		WriteMsg(w, http.StatusPreconditionRequired, "If-Match header with the resource etag is required")
+		return 0, false
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`))
+	if err != nil || version < 1 {
+		WriteMsg(w, http.StatusBadRequest, "If-Match header is not a valid etag")
+		return 0, false
+	}
+	return version, true
+}
+
+// write a single resource with its etag
+func WriteVersioned(w http.ResponseWriter, version int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	w.Header().Set("ETag", ETag(version))
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// hit and miss counts of the caches in front of the data access provider
+func (ah AppHandlers) CacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]CacheStats{}
+	if ah.cache != nil {
+		for name, s := range ah.cache.Stats() {
+			stats[name] = s
+		}
+	}
+	b, err := json.Marshal(stats)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
 // app server health responder
 func (ah AppHandlers) healthcheck(w http.ResponseWriter, r *http.Request) {
 	WriteOk(w)
 }
 
+// resolve placement options from the server defaults and any request overrides
+func (ah AppHandlers) PlacementFor(r *http.Request) (PlacementOptions, error) {
+	opts := ah.park.Placement()
+	if strategy := r.URL.Query().Get("strategy"); len(strategy) != 0 {
+		if !ValidStrategy(strategy) {
+			return opts, fmt.Errorf("unknown placement strategy %s", strategy)
+		}
+		opts.Strategy = strategy
+	}
+	if autoCreate := r.URL.Query().Get("auto_create"); len(autoCreate) != 0 {
+		v, err := strconv.ParseBool(autoCreate)
+		if err != nil {
+			return opts, fmt.Errorf("bad auto_create parameter must be a boolean")
+		}
+		opts.AutoCreate = v
+	}
+	return opts, nil
+}
+
 // persist a new dinosaur to database - assigning to an open or new cage
 func (ah AppHandlers) AddDinosaur(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ah.addDinosaur(w, r); ok {
+		WriteOk(w)
+	}
+}
+
+// add the dinosaur in the request body with the requested placement
+// writes the error and returns false on failure
+func (ah AppHandlers) addDinosaur(w http.ResponseWriter, r *http.Request) (Dinosaur, bool) {
+	opts, err := ah.PlacementFor(r)
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, err.Error())
+		return Dinosaur{}, false
+	}
 	// read payload
 	dino := Dinosaur{}
-	err := json.NewDecoder(r.Body).Decode(&dino)
+	defer r.Body.Close()
+	err = DecodeJSON(w, r, &dino)
 	if err != nil {
-		WriteMsg(w, http.StatusBadRequest, "bad payload "+err.Error())
-		return
+		WriteError(w, err, http.StatusBadRequest)
+		return Dinosaur{}, false
 	}
-	if !ah.CheckSpecies(strings.ToLower(dino.Species)) {
-		WriteMsg(w, http.StatusBadRequest, "unknown species "+dino.Species)
-		return
-	}
-	defer r.Body.Close()
-	err = ah.dap.AddDinosaur(r.Context(), dino)
+	dino, err = ah.park.AddDinosaur(r.Context(), dino, opts)
 	if err != nil {
-		WriteMsg(w, http.StatusUnprocessableEntity, err.Error())
-		return
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return Dinosaur{}, false
 	}
-	WriteOk(w)
+	return dino, true
 }
 
 // create a new cage of the given dietary type
@@ -98,23 +190,11 @@ func (ah AppHandlers) AddCage(w http.ResponseWriter, r *http.Request) {
 			WriteMsg(w, http.StatusBadRequest, "bad capacity parameter must be an integer")
 			return
 		}
-		if cap < 1 {
-			WriteMsg(w, http.StatusBadRequest, "bad capacity parameter value must be > 0")
-			return
-		}
-	}
-	var id int
-	switch kind {
-	case "H":
-		id, err = ah.dap.NewCage(r.Context(), cap, HerbivoreCode)
-	case "C":
-		id, err = ah.dap.NewCage(r.Context(), cap, CarnivoreCode)
-	default:
-		WriteMsg(w, http.StatusBadRequest, "diet must be H (herbivore) V (carnivore)")
-		return
 	}
+	// the diet and capacity are checked together by the park rules
+	cage, err := ah.park.AddCage(r.Context(), kind, cap)
 	if err != nil {
-		WriteMsg(w, http.StatusUnprocessableEntity, err.Error())
+		WriteError(w, err, http.StatusUnprocessableEntity)
 		return
 	}
 
@@ -122,7 +202,7 @@ func (ah AppHandlers) AddCage(w http.ResponseWriter, r *http.Request) {
 	v := struct {
 		ID int `json:"id"`
 	}{
-		ID: id,
+		ID: cage.ID,
 	}
 	b, _ := json.Marshal(v)
 	WriteMsg(w, http.StatusOK, string(b))
@@ -175,25 +255,32 @@ func (ah AppHandlers) GetCageDinosaurs(w http.ResponseWriter, r *http.Request) {
 }
 
 // add new species handler
+// returns the species as stored, names are kept in lower case
 func (ah AppHandlers) AddSpecies(w http.ResponseWriter, r *http.Request) {
 	var species Species
-	err := json.NewDecoder(r.Body).Decode(&species)
+	defer r.Body.Close()
+	err := DecodeJSON(w, r, &species)
 	if err != nil {
-		WriteMsg(w, http.StatusUnprocessableEntity, "bad request for add species")
+		WriteError(w, err, http.StatusBadRequest)
 		return
 	}
 	log.Printf("adding %s %s", species.Name, species.Diet)
-	ah.NewSpecies(species.Name, species.Diet)
-	WriteOk(w)
+	species, err = ah.park.AddSpecies(r.Context(), species)
+	if err != nil {
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	b, _ := json.Marshal(species)
+	WriteMsg(w, http.StatusOK, string(b))
 }
 
 // list species handler
 func (ah AppHandlers) ListSpecies(w http.ResponseWriter, r *http.Request) {
-	var species []Species
-	ah.speciesMap.Range(func(name, diet string) bool {
-		species = append(species, Species{Name: name, Diet: diet})
-		return true
-	})
+	species, err := ah.park.ListSpecies(r.Context())
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
 	b, err := json.Marshal(species)
 	if err != nil {
 		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
@@ -202,7 +289,55 @@ func (ah AppHandlers) ListSpecies(w http.ResponseWriter, r *http.Request) {
 	WriteMsg(w, http.StatusOK, string(b))
 }
 
+// replace the details of a species handler
+// the diet of a species with dinosaurs in the park cannot be changed
+func (ah AppHandlers) UpdateSpecies(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	var species Species
+	defer r.Body.Close()
+	err := DecodeJSON(w, r, &species)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if len(species.Name) == 0 {
+		species.Name = name
+	}
+	if !strings.EqualFold(strings.TrimSpace(species.Name), name) {
+		WriteMsg(w, http.StatusBadRequest, "species name does not match the path")
+		return
+	}
+	species, err = ah.park.UpdateSpecies(r.Context(), species)
+	if errors.Is(err, service.ErrUnknownSpecies) {
+		WriteMsg(w, http.StatusNotFound, "unknown species "+name)
+		return
+	}
+	if err != nil {
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	b, _ := json.Marshal(species)
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// remove a species handler
+// refused while dinosaurs of the species are in the park
+func (ah AppHandlers) DeleteSpecies(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	err := ah.park.DeleteSpecies(r.Context(), name)
+	if errors.Is(err, service.ErrUnknownSpecies) {
+		WriteMsg(w, http.StatusNotFound, "unknown species "+name)
+		return
+	}
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), "unable to delete species : "+err.Error())
+		return
+	}
+	WriteOk(w)
+}
+
 // set the status of a specified cage handler
+// an optional payload may supply the reason and actor for the change
 func (ah AppHandlers) SetCageStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	status := vars["status"]
@@ -220,20 +355,522 @@ func (ah AppHandlers) SetCageStatus(w http.ResponseWriter, r *http.Request) {
 		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
 		return
 	}
-	err = ah.dap.SetCageStatus(r.Context(), cageID, status)
+	version, ok := IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	var change StatusChange
+	if r.Body != nil {
+		defer r.Body.Close()
+		err = DecodeJSON(w, r, &change)
+		if err != nil && err != io.EOF {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	change.Status = status
+	change.Version = version
+	_, err = ah.park.SetCageStatus(r.Context(), cageID, change)
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), fmt.Sprintf("unable to set cage %d status to %s : %v", cageID, status, err))
+		return
+	}
+	WriteOk(w)
+}
+
+// list the status transitions recorded for a cage
+func (ah AppHandlers) GetCageStatusHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cageID, err := strconv.Atoi(vars["cageid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
+		return
+	}
+	history, err := ah.dap.GetCageStatusHistory(r.Context(), cageID)
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	b, err := json.Marshal(history)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// parse an optional boolean query parameter
+func QueryBool(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if len(v) == 0 {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// relocate all dinosaurs out of a cage handler
+// supports dry_run, create_cages and power_down query parameters
+func (ah AppHandlers) EvacuateCage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cageID, err := strconv.Atoi(vars["cageid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
+		return
+	}
+	var opts EvacuateOptions
+	for name, dst := range map[string]*bool{"dry_run": &opts.DryRun, "create_cages": &opts.CreateCages, "power_down": &opts.PowerDown} {
+		*dst, err = QueryBool(r, name)
+		if err != nil {
+			WriteMsg(w, http.StatusBadRequest, fmt.Sprintf("bad %s parameter must be a boolean", name))
+			return
+		}
+	}
+	opts.Reason = r.URL.Query().Get("reason")
+	opts.Actor = r.URL.Query().Get("actor")
+	plan, err := ah.park.EvacuateCage(r.Context(), cageID, opts)
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), fmt.Sprintf("unable to evacuate cage %d : %v", cageID, err))
+		return
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// plan a rebalance of cage occupancy handler
+// GET returns the plan only while POST applies it
+func (ah AppHandlers) RebalanceCages(w http.ResponseWriter, r *http.Request) {
+	opts := RebalanceOptions{
+		Mode:   r.URL.Query().Get("mode"),
+		DryRun: r.Method != http.MethodPost,
+	}
+	if len(opts.Mode) == 0 {
+		opts.Mode = RebalanceConsolidate
+	}
+	if !ValidRebalanceMode(opts.Mode) {
+		WriteMsg(w, http.StatusBadRequest, "mode must be consolidate or spread")
+		return
+	}
+	if opts.Mode == RebalanceSpread {
+		target, err := strconv.ParseFloat(r.URL.Query().Get("target"), 64)
+		if err != nil || target <= 0 || target > 1 {
+			WriteMsg(w, http.StatusBadRequest, "bad target parameter must be a number > 0 and <= 1")
+			return
+		}
+		opts.Target = target
+	}
+	plan, err := ah.park.RebalanceCages(r.Context(), opts)
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), "unable to rebalance cages : "+err.Error())
+		return
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// report returned from a bulk import
+type ImportReport struct {
+	Mode     string         `json:"mode"`
+	Imported int            `json:"imported"`
+	Failed   int            `json:"failed"`
+	Results  []ImportResult `json:"results"`
+}
+
+// bulk import species, cages and dinosaurs from csv or json lines handler
+// mode=atomic (default) rejects the whole import on any bad row while
+// mode=best_effort imports the good rows and reports the rest
+func (ah AppHandlers) Import(w http.ResponseWriter, r *http.Request) {
+	format, ok := ImportFormat(r.URL.Query().Get("format"), r.Header.Get("Content-Type"))
+	if !ok {
+		WriteMsg(w, http.StatusUnsupportedMediaType, "import format must be csv or jsonl")
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if len(mode) == 0 {
+		mode = "atomic"
+	}
+	if mode != "atomic" && mode != "best_effort" {
+		WriteMsg(w, http.StatusBadRequest, "mode must be atomic or best_effort")
+		return
+	}
+	opts := ImportOptions{BestEffort: mode == "best_effort"}
+	var err error
+	opts.Placement, err = ah.PlacementFor(r)
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer r.Body.Close()
+	records, err := ParseImport(http.MaxBytesReader(w, r.Body, MaxImportBytes), format)
+	if err != nil {
+		WriteError(w, fmt.Errorf("bad import payload %w", err), http.StatusBadRequest)
+		return
+	}
+
+	report := ImportReport{Mode: mode}
+	status := http.StatusOK
+	report.Results, err = ah.park.Import(r.Context(), records, opts)
+	if errors.Is(err, service.ErrImportRejected) {
+		// check every row again so the report names each problem and not just the first
+		status = http.StatusUnprocessableEntity
+		report.Results, err = ah.ValidateImport(r.Context(), records, opts.Placement)
+	}
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	for _, res := range report.Results {
+		if res.OK {
+			report.Imported++
+		} else {
+			report.Failed++
+		}
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, status, string(b))
+}
+
+// content types for each snapshot format
+var snapshotContentTypes = map[string]string{
+	FormatJSON:  "application/json",
+	FormatJSONL: "application/x-ndjson",
+	FormatCSV:   "text/csv",
+}
+
+// export a consistent snapshot of the park handler
+func (ah AppHandlers) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = FormatJSON
+	}
+	contentType, ok := snapshotContentTypes[format]
+	if !ok {
+		WriteMsg(w, http.StatusBadRequest, "format must be json, jsonl or csv")
+		return
+	}
+	snap, err := ah.dap.Snapshot(r.Context())
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=park-%s.%s", snap.TakenAt.Format("20060102T150405Z"), format))
+	w.WriteHeader(http.StatusOK)
+	err = WriteSnapshot(w, NewParkSnapshot(snap), format)
+	if err != nil {
+		log.Printf("unable to write export : %v", err)
+	}
+}
+
+// restore a snapshot into an empty database handler
+func (ah AppHandlers) Restore(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = FormatJSON
+	}
+	if _, ok := snapshotContentTypes[format]; !ok {
+		WriteMsg(w, http.StatusBadRequest, "format must be json, jsonl or csv")
+		return
+	}
+	defer r.Body.Close()
+	ps, err := ReadSnapshot(http.MaxBytesReader(w, r.Body, MaxImportBytes), format)
+	if err != nil {
+		WriteError(w, fmt.Errorf("bad snapshot %w", err), http.StatusBadRequest)
+		return
+	}
+	err = ah.park.Restore(r.Context(), Snapshot{Species: ps.Species, Cages: ps.Cages, Dinosaurs: ps.Dinosaurs})
+	if errors.Is(err, ErrNotEmpty) {
+		WriteMsg(w, http.StatusConflict, "restore requires an empty database")
+		return
+	}
+	if errors.Is(err, service.ErrInvalid) {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	WriteOk(w)
+}
+
+// single cage handler
+func (ah AppHandlers) GetCage(w http.ResponseWriter, r *http.Request) {
+	cageID, err := strconv.Atoi(mux.Vars(r)["cageid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
+		return
+	}
+	cage, err := ah.dap.GetCage(r.Context(), cageID)
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), err.Error())
+		return
+	}
+	WriteVersioned(w, cage.Version, cage)
+}
+
+// change cage capacity handler requiring a matching etag
+func (ah AppHandlers) PatchCage(w http.ResponseWriter, r *http.Request) {
+	cageID, err := strconv.Atoi(mux.Vars(r)["cageid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
+		return
+	}
+	version, ok := IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	var patch struct {
+		Capacity int `json:"capacity"`
+	}
+	defer r.Body.Close()
+	err = DecodeJSON(w, r, &patch)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	cage, err := ah.park.ResizeCage(r.Context(), cageID, version, patch.Capacity)
+	if err != nil {
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	WriteVersioned(w, cage.Version, cage)
+}
+
+// remove an empty cage handler requiring a matching etag
+func (ah AppHandlers) DeleteCage(w http.ResponseWriter, r *http.Request) {
+	cageID, err := strconv.Atoi(mux.Vars(r)["cageid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
+		return
+	}
+	version, ok := IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	err = ah.park.DeleteCage(r.Context(), cageID, version)
 	if err != nil {
-		WriteMsg(w, http.StatusUnprocessableEntity, fmt.Sprintf("unable to set cage %d status to %s check that the cage is empty or powered down", cageID, status))
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), err.Error())
 		return
 	}
 	WriteOk(w)
 }
 
+// single dinosaur handler
+func (ah AppHandlers) GetDinosaur(w http.ResponseWriter, r *http.Request) {
+	dinoID, err := strconv.Atoi(mux.Vars(r)["dinoid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "dinoid is not a valid value")
+		return
+	}
+	dino, err := ah.dap.GetDinosaur(r.Context(), dinoID)
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), err.Error())
+		return
+	}
+	WriteVersioned(w, dino.Version, dino)
+}
+
+// rename a dinosaur handler requiring a matching etag
+func (ah AppHandlers) PatchDinosaur(w http.ResponseWriter, r *http.Request) {
+	dinoID, err := strconv.Atoi(mux.Vars(r)["dinoid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "dinoid is not a valid value")
+		return
+	}
+	version, ok := IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	var patch struct {
+		Name string `json:"name"`
+	}
+	defer r.Body.Close()
+	err = DecodeJSON(w, r, &patch)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	dino, err := ah.park.RenameDinosaur(r.Context(), dinoID, version, patch.Name)
+	if err != nil {
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	WriteVersioned(w, dino.Version, dino)
+}
+
+// remove a dinosaur handler requiring a matching etag
+func (ah AppHandlers) DeleteDinosaur(w http.ResponseWriter, r *http.Request) {
+	dinoID, err := strconv.Atoi(mux.Vars(r)["dinoid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "dinoid is not a valid value")
+		return
+	}
+	version, ok := IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	err = ah.park.DeleteDinosaur(r.Context(), dinoID, version)
+	if err != nil {
+		WriteMsg(w, StatusForError(err, http.StatusUnprocessableEntity), err.Error())
+		return
+	}
+	WriteOk(w)
+}
+
+// move a dinosaur to another cage handler requiring a matching etag
+func (ah AppHandlers) MoveDinosaur(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	dinoID, err := strconv.Atoi(vars["dinoid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "dinoid is not a valid value")
+		return
+	}
+	cageID, err := strconv.Atoi(vars["cageid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "cageid is not a valid value")
+		return
+	}
+	ah.moveDinosaur(w, r, dinoID, cageID)
+}
+
+// move a dinosaur at the If-Match version writing it back with its new etag
+func (ah AppHandlers) moveDinosaur(w http.ResponseWriter, r *http.Request, dinoID, cageID int) {
+	version, ok := IfMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	dino, err := ah.park.MoveDinosaur(r.Context(), dinoID, version, cageID)
+	if err != nil {
+		WriteError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+	WriteVersioned(w, dino.Version, dino)
+}
+
+// register a webhook subscription handler
+func (ah AppHandlers) AddWebhook(w http.ResponseWriter, r *http.Request) {
+	var wh Webhook
+	defer r.Body.Close()
+	err := DecodeJSON(w, r, &wh)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	var ve service.ValidationError
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		ve.Add("url", "must be an absolute http or https url")
+	}
+	if len(wh.Secret) == 0 {
+		ve.Add("secret", "is required for signing deliveries")
+	}
+	for _, e := range wh.Events {
+		if !ValidEventType(e) {
+			ve.Add("events", "has unknown event type %q", e)
+		}
+	}
+	if err := ve.Err(); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	id, err := ah.dap.AddWebhook(r.Context(), wh)
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	v := struct {
+		ID int `json:"id"`
+	}{
+		ID: id,
+	}
+	b, _ := json.Marshal(v)
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// list webhook subscriptions handler - secrets are not returned
+func (ah AppHandlers) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := ah.dap.ListWebhooks(r.Context())
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	b, err := json.Marshal(hooks)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
+// remove a webhook subscription handler
+func (ah AppHandlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["webhookid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "webhookid is not a valid value")
+		return
+	}
+	err = ah.dap.DeleteWebhook(r.Context(), id)
+	if errors.Is(err, ErrWebhookNotFound) {
+		WriteMsg(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	WriteOk(w)
+}
+
+// delivery log for a webhook handler with an optional status filter
+func (ah AppHandlers) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["webhookid"])
+	if err != nil {
+		WriteMsg(w, http.StatusBadRequest, "webhookid is not a valid value")
+		return
+	}
+	ah.writeDeliveries(w, r, id, strings.ToUpper(r.URL.Query().Get("status")))
+}
+
+// deliveries that exhausted their retries across all webhooks handler
+func (ah AppHandlers) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ah.writeDeliveries(w, r, 0, DeliveryDead)
+}
+
+func (ah AppHandlers) writeDeliveries(w http.ResponseWriter, r *http.Request, webhookID int, status string) {
+	deliveries, err := ah.dap.ListDeliveries(r.Context(), webhookID, status)
+	if err != nil {
+		WriteMsg(w, http.StatusUnprocessableEntity, "database error : "+err.Error())
+		return
+	}
+	b, err := json.Marshal(deliveries)
+	if err != nil {
+		WriteMsg(w, http.StatusInternalServerError, "marshal error : "+err.Error())
+		return
+	}
+	WriteMsg(w, http.StatusOK, string(b))
+}
+
 // list dinosaur handler
 func (ah AppHandlers) GetDinosaurs(w http.ResponseWriter, r *http.Request) {
 	species := r.URL.Query().Get("species")
-	var err error
 	var dinos []Dinosaur
-	if len(species) != 0 && ah.CheckSpecies(species) {
+	_, err := ah.park.Species(r.Context(), species)
+	if err == nil {
 		dinos, err = ah.dap.GetDinosaurs(r.Context(), species)
 	} else {
 		dinos, err = ah.dap.GetDinosaurs(r.Context())
@@ -264,34 +901,73 @@ func (ah AppHandlers) AddDinoToCage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var dino Dinosaur
-	err = json.NewDecoder(r.Body).Decode(&dino)
+	defer r.Body.Close()
+	err = DecodeJSON(w, r, &dino)
 	if err != nil {
-		WriteMsg(w, http.StatusBadRequest, "bad dino data "+err.Error())
+		WriteError(w, err, http.StatusBadRequest)
 		return
 	}
-
-	defer r.Body.Close()
-	err = ah.dap.PlaceDinosaurInCage(r.Context(), cageID, dino)
+	_, err = ah.park.PlaceDinosaur(r.Context(), cageID, dino)
 	if err != nil {
-		WriteMsg(w, http.StatusBadRequest, err.Error())
+		WriteError(w, err, http.StatusBadRequest)
 		return
 	}
 	WriteOk(w)
 }
 
+// an api version registering its routes on a router under its prefix
+type APIVersion struct {
+	Prefix   string
+	Register func(r *mux.Router, appHandlers *AppHandlers)
+}
+
+var APIVersions = []APIVersion{
+	{Prefix: "/v1", Register: RegisterV1},
+	{Prefix: "/v2", Register: RegisterV2},
+}
+
+// give each request a database session so it reads its own writes
+func DatabaseSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithSession(r.Context())))
+	})
+}
+
+// create mux with every route validated against the api description
+func NewRouter(appHandlers *AppHandlers) (*mux.Router, error) {
+	doc, err := LoadOpenAPI()
+	if err != nil {
+		return nil, fmt.Errorf("invalid api description : %w", err)
+	}
+	validator, err := NewRequestValidator(doc)
+	if err != nil {
+		return nil, err
+	}
+	r := mux.NewRouter()
+	for _, v := range APIVersions {
+		v.Register(r.PathPrefix(v.Prefix).Subrouter(), appHandlers)
+	}
+	r.Handle("/graphql", NewGraphQLHandler(appHandlers)).Methods("POST")
+	r.Use(DatabaseSession)
+	// limit bodies before anything reads them
+	r.Use(LimitBody)
+	// validate before idempotency so rejected requests are not recorded against a key
+	r.Use(validator.Middleware)
+	if appHandlers.idempotent != nil {
+		r.Use(appHandlers.idempotent.Middleware)
+	}
+	return r, nil
+}
+
 // create mux and start server
 func StartServer(ctx context.Context, listenAddr string, appHandlers *AppHandlers) error {
-	r := mux.NewRouter()
-	r.HandleFunc("/v1/healthcheck", appHandlers.healthcheck).Methods("GET")
-	r.HandleFunc("/v1/dino/add", appHandlers.AddDinosaur).Methods("POST")
-	r.HandleFunc("/v1/dino/list", appHandlers.GetDinosaurs).Methods("GET")
-	r.HandleFunc("/v1/cages", appHandlers.GetCages).Methods("GET")
-	r.HandleFunc("/v1/cage/{diet}/add", appHandlers.AddCage).Methods("POST")
-	r.HandleFunc("/v1/cage/{cageid}/list_dinosaurs", appHandlers.GetCageDinosaurs).Methods("GET")
-	r.HandleFunc("/v1/cage/{cageid}/status/{status}", appHandlers.SetCageStatus).Methods("POST")
-	r.HandleFunc("/v1/cage/{cageid}/add_dino", appHandlers.AddDinoToCage).Methods("POST")
-	r.HandleFunc("/v1/species/add", appHandlers.AddSpecies).Methods("POST")
-	r.HandleFunc("/v1/species/list", appHandlers.ListSpecies).Methods("GET")
+	r, err := NewRouter(appHandlers)
+	if err != nil {
+		return err
+	}
+	if appHandlers.idempotent != nil {
+		go appHandlers.idempotent.Janitor(ctx)
+	}
 
 	server := http.Server{
 		Addr:    listenAddr,
@@ -301,6 +977,10 @@ func StartServer(ctx context.Context, listenAddr string, appHandlers *AppHandler
 	// prepare for shutdown initiated from context
 	go func() {
 		<-ctx.Done()
+		// end any event streams so they do not hold up shutdown
+		if appHandlers.events != nil {
+			appHandlers.events.Close()
+		}
 		// received context done
 		// shutdown server
 		if err := server.Shutdown(context.Background()); err != nil {
@@ -308,6 +988,6 @@ func StartServer(ctx context.Context, listenAddr string, appHandlers *AppHandler
 		}
 	}()
 	log.Printf("Starting serv on %s", listenAddr)
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	return err
 }
@@ -7,14 +7,92 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	. "dinocage/das"
 	"dinocage/mocks"
 
 	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
 )
 
+// run every unit of work against a mocked transaction
+func mockWork(ctrl *gomock.Controller, mockDap *mocks.MockDataAccessProvider) *mocks.MockTx {
+	tx := mocks.NewMockTx(ctrl)
+	mockDap.EXPECT().Atomic(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(Tx) error) error {
+			return fn(tx)
+		},
+	).AnyTimes()
+	// species reads and writes in a unit of work go to the provider
+	tx.EXPECT().GetSpecies(gomock.Any(), gomock.Any()).DoAndReturn(mockDap.GetSpecies).AnyTimes()
+	tx.EXPECT().ListSpecies(gomock.Any()).DoAndReturn(mockDap.ListSpecies).AnyTimes()
+	tx.EXPECT().SaveSpecies(gomock.Any(), gomock.Any()).DoAndReturn(mockDap.SaveSpecies).AnyTimes()
+	tx.EXPECT().RemoveSpecies(gomock.Any(), gomock.Any()).DoAndReturn(mockDap.RemoveSpecies).AnyTimes()
+	return tx
+}
+
+// a species table served by the mocked provider
+type speciesTable struct {
+	mu   sync.Mutex
+	rows map[string]Species
+}
+
+func stubSpecies(mockDap *mocks.MockDataAccessProvider, species ...Species) *speciesTable {
+	st := &speciesTable{rows: make(map[string]Species)}
+	for _, s := range species {
+		st.rows[s.Name] = s
+	}
+	mockDap.EXPECT().GetSpecies(gomock.Any(), gomock.Any()).DoAndReturn(st.GetSpecies).AnyTimes()
+	mockDap.EXPECT().ListSpecies(gomock.Any()).DoAndReturn(st.ListSpecies).AnyTimes()
+	mockDap.EXPECT().SaveSpecies(gomock.Any(), gomock.Any()).DoAndReturn(st.SaveSpecies).AnyTimes()
+	mockDap.EXPECT().RemoveSpecies(gomock.Any(), gomock.Any()).DoAndReturn(st.RemoveSpecies).AnyTimes()
+	return st
+}
+
+func (st *speciesTable) GetSpecies(ctx context.Context, name string) (Species, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.rows[name]
+	if !ok {
+		return s, fmt.Errorf("%s : %w", name, ErrSpeciesNotFound)
+	}
+	return s, nil
+}
+
+func (st *speciesTable) ListSpecies(ctx context.Context) ([]Species, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var species []Species
+	for _, s := range st.rows {
+		species = append(species, s)
+	}
+	slices.SortFunc(species, func(a, b Species) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return species, nil
+}
+
+func (st *speciesTable) SaveSpecies(ctx context.Context, s Species) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.rows[s.Name] = s
+	return nil
+}
+
+func (st *speciesTable) RemoveSpecies(ctx context.Context, name string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.rows[name]; !ok {
+		return fmt.Errorf("%s : %w", name, ErrSpeciesNotFound)
+	}
+	delete(st.rows, name)
+	return nil
+}
+
 func Closer(da DataAccessProvider) {
 	da.Close()
 }
@@ -79,24 +157,23 @@ func TestAddDino(t *testing.T) {
 	}
 	w := httptest.NewRecorder()
 
-	speciesMap := &GenMap[string, string]{}
-	speciesMap.Store("tyrannosaurus", "C")
-	speciesMap.Range(func(species, diet string) bool {
-		t.Logf("%s : %s", species, diet)
-		return true
-	})
-	ah := &AppHandlers{dap: mockDap, speciesMap: speciesMap}
-
-	// prepare the data access call
+	stubSpecies(mockDap, Species{Name: "tyrannosaurus", Diet: "C"})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
 
+	// prepare the data access calls
+	tx := mockWork(ctrl, mockDap)
+	cage := Cage{ID: 1, Status: StatusActive, Capacity: CageCapacity, Kind: "C", Version: 1}
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "C").Return([]Cage{cage}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(cage, nil)
 	var dino Dinosaur
-	mockDap.EXPECT().AddDinosaur(gomock.Any(), gomock.AssignableToTypeOf(dino)).DoAndReturn(
-		func(v interface{}, arg Dinosaur) error {
+	tx.EXPECT().InsertDinosaur(gomock.Any(), gomock.AssignableToTypeOf(dino)).DoAndReturn(
+		func(v interface{}, arg Dinosaur) (Dinosaur, error) {
 			dino = arg
-			t.Logf("TestAddDino::.AddDinosaur received Dino : %+v", dino)
-			return nil
+			t.Logf("TestAddDino::.InsertDinosaur received Dino : %+v", dino)
+			return arg, nil
 		},
 	)
+	tx.EXPECT().RecordEvent(gomock.Any(), EventDinoAdded, 1, gomock.Any()).Return(nil)
 
 	ah.AddDinosaur(w, r)
 
@@ -104,6 +181,9 @@ func TestAddDino(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("TestAddDino did not return success but gave %v", resp.StatusCode)
 	}
+	if dino.Species != "tyrannosaurus" || dino.Name != "Barnie" || dino.Cage != 1 {
+		t.Errorf("TestAddDino stored unexpected dino %+v", dino)
+	}
 
 }
 
@@ -132,25 +212,415 @@ func TestErrorAddDino(t *testing.T) {
 	}
 	w := httptest.NewRecorder()
 
-	speciesMap := &GenMap[string, string]{}
-	speciesMap.Store("tyrannosaurus", "C")
-	ah := &AppHandlers{dap: mockDap, speciesMap: speciesMap}
+	stubSpecies(mockDap, Species{Name: "tyrannosaurus", Diet: "C"})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
 
 	// prepare the data access call
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "C").Return(nil, fmt.Errorf("Test Error"))
 
-	var dino Dinosaur
-	mockDap.EXPECT().AddDinosaur(gomock.Any(), gomock.AssignableToTypeOf(dino)).DoAndReturn(
-		func(v interface{}, arg Dinosaur) error {
-			dino = arg
-			t.Logf("TestAddDino:AddDinosaur received Dino : %+v", dino)
-			return fmt.Errorf("Test Error")
+	ah.AddDinosaur(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("TestAddDino:AddDinosaur did not return %v but gave %v", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+func TestSetCageStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	payload := `{"reason":"fence inspection","actor":"muldoon"}`
+	r, err := http.NewRequestWithContext(context.Background(), "POST", "http://localhost:8000/v1/cage/3/status/MAINTENANCE", strings.NewReader(payload))
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"cageid": "3", "status": StatusMaintenance})
+	r.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	tx := mockWork(ctrl, mockDap)
+	cage := Cage{ID: 3, Status: StatusActive, Capacity: CageCapacity, Kind: "H", Version: 4}
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(cage, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(cage, nil)
+	var st StatusTransition
+	tx.EXPECT().AddStatusTransition(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(v interface{}, arg StatusTransition) error {
+			st = arg
+			return nil
 		},
 	)
+	tx.EXPECT().RecordEvent(gomock.Any(), EventCageStatusChanged, 3, gomock.Any()).Return(nil)
+
+	ah.SetCageStatus(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("TestSetCageStatus did not return success but gave %v", resp.StatusCode)
+	}
+	if st.From != StatusActive || st.To != StatusMaintenance || st.Reason != "fence inspection" || st.Actor != "muldoon" {
+		t.Errorf("TestSetCageStatus recorded unexpected transition %+v", st)
+	}
+}
+
+func TestSetCageStatusIllegalTransition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	r, err := http.NewRequestWithContext(context.Background(), "POST", "http://localhost:8000/v1/cage/3/status/ACTIVE", nil)
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"cageid": "3", "status": StatusActive})
+	r.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(Cage{ID: 3, Status: StatusDecommissioned, Kind: "H", Version: 1}, nil)
+
+	ah.SetCageStatus(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("TestSetCageStatusIllegalTransition did not return %v but gave %v", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestEvacuateCageDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap)
+
+	r, err := http.NewRequestWithContext(context.Background(), "POST", "http://localhost:8000/v1/cage/4/evacuate?dry_run=true&create_cages=1", nil)
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"cageid": "4"})
+	w := httptest.NewRecorder()
+
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	// a dry run plans from the locked rows without writing any
+	tx := mockWork(ctrl, mockDap)
+	from := Cage{ID: 4, Status: StatusActive, Capacity: 4, Count: 1, Kind: "C", Version: 2}
+	to := Cage{ID: 6, Status: StatusActive, Capacity: 4, Kind: "C", Version: 1}
+	tx.EXPECT().GetCage(gomock.Any(), 4).Return(from, nil)
+	tx.EXPECT().GetDinosaursForCage(gomock.Any(), 4).Return([]Dinosaur{{ID: 11, Species: "velociraptor", Name: "blue", Diet: "C", Cage: 4}}, nil)
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "C").Return([]Cage{from, to}, nil)
+	tx.EXPECT().GetDinosaursForCages(gomock.Any(), []int{4, 6}).Return(nil, nil)
+
+	ah.EvacuateCage(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("TestEvacuateCageDryRun did not return success but gave %v", resp.StatusCode)
+	}
+	var plan EvacuationPlan
+	err = json.NewDecoder(resp.Body).Decode(&plan)
+	if err != nil {
+		t.Fatalf("TestEvacuateCageDryRun unable to decode plan %v", err)
+	}
+	if len(plan.Relocations) != 1 || plan.Relocations[0].To != 6 {
+		t.Errorf("TestEvacuateCageDryRun unexpected plan %+v", plan)
+	}
+}
+
+func TestAddDinoNoCapacity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	payload := `{"species":"tyrannosaurus","name":"rexy","diet":"C"}`
+	r, err := http.NewRequestWithContext(context.Background(), "POST", "http://localhost:8000/v1/dino/add?strategy=best-fit&auto_create=false", strings.NewReader(payload))
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	w := httptest.NewRecorder()
+
+	stubSpecies(mockDap, Species{Name: "tyrannosaurus", Diet: "C"})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	tx := mockWork(ctrl, mockDap)
+	full := Cage{ID: 2, Status: StatusActive, Capacity: 1, Count: 1, Kind: "C", Version: 3}
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "C").Return([]Cage{full}, nil)
 
 	ah.AddDinosaur(w, r)
 
 	resp := w.Result()
-	if resp.StatusCode != http.StatusUnprocessableEntity {
-		t.Errorf("TestAddDino:AddDinosaur did not return %v but gave %v", http.StatusUnprocessableEntity, resp.StatusCode)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("TestAddDinoNoCapacity did not return %v but gave %v", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestImportBestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	payload := `{"type":"dinosaur","species":"tyrannosaurus","name":"rexy"}
+{"type":"dinosaur","species":"mosasaurus","name":"mo"}
+`
+	r, err := http.NewRequestWithContext(context.Background(), "POST", "http://localhost:8000/v1/import?mode=best_effort", strings.NewReader(payload))
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	stubSpecies(mockDap, Species{Name: "tyrannosaurus", Diet: "C"})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	// each row is placed in its own savepoint
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().Savepoint(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func() error) error {
+		return fn()
+	}).Times(2)
+	cage := Cage{ID: 2, Status: StatusActive, Capacity: 2, Kind: "C", Version: 1}
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "C").Return([]Cage{cage}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(cage, nil)
+	tx.EXPECT().InsertDinosaur(gomock.Any(), gomock.Any()).Return(Dinosaur{ID: 42, Species: "tyrannosaurus", Name: "rexy", Diet: "C", Cage: 2, Version: 1}, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), EventDinoAdded, 2, gomock.Any()).Return(nil)
+
+	ah.Import(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("TestImportBestEffort did not return success but gave %v", resp.StatusCode)
+	}
+	var report ImportReport
+	err = json.NewDecoder(resp.Body).Decode(&report)
+	if err != nil {
+		t.Fatalf("TestImportBestEffort unable to decode report %v", err)
+	}
+	if report.Imported != 1 || report.Failed != 1 || report.Results[1].Row != 2 || report.Results[1].OK {
+		t.Errorf("TestImportBestEffort unexpected report %+v", report)
+	}
+}
+
+func TestSetCageStatusRequiresIfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	r, err := http.NewRequestWithContext(context.Background(), "POST", "http://localhost:8000/v1/cage/3/status/DOWN", nil)
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"cageid": "3", "status": StatusDown})
+	w := httptest.NewRecorder()
+
+	ah := &AppHandlers{dap: mockDap}
+	ah.SetCageStatus(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusPreconditionRequired {
+		t.Errorf("TestSetCageStatusRequiresIfMatch did not return %v but gave %v", http.StatusPreconditionRequired, resp.StatusCode)
+	}
+}
+
+func TestGetCageETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	r, err := http.NewRequestWithContext(context.Background(), "GET", "http://localhost:8000/v1/cage/5", nil)
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"cageid": "5"})
+	w := httptest.NewRecorder()
+
+	ah := &AppHandlers{dap: mockDap}
+	mockDap.EXPECT().GetCage(gomock.Any(), 5).Return(Cage{ID: 5, Status: StatusActive, Capacity: 10, Kind: "H", Version: 9}, nil)
+
+	ah.GetCage(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != `"9"` {
+		t.Errorf("TestGetCageETag gave %v with etag %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+func TestPatchCageVersionMoved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+
+	r, err := http.NewRequestWithContext(context.Background(), "PATCH", "http://localhost:8000/v1/cage/5", strings.NewReader(`{"capacity":12}`))
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"cageid": "5"})
+	r.Header.Set("If-Match", `"8"`)
+	w := httptest.NewRecorder()
+
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetCage(gomock.Any(), 5).Return(Cage{ID: 5, Status: StatusActive, Capacity: 10, Kind: "H", Version: 9}, nil)
+
+	ah.PatchCage(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("TestPatchCageVersionMoved did not return %v but gave %v", http.StatusPreconditionFailed, resp.StatusCode)
+	}
+}
+
+func TestUpdateSpecies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, Species{Name: "velociraptor", Diet: CarnivoreCode})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	payload := `{"diet":"C","period":"Cretaceous","weight_kg":15,"danger_level":5,"max_per_cage":3}`
+	r, err := http.NewRequestWithContext(context.Background(), "PUT", "http://localhost:8000/v1/species/velociraptor", strings.NewReader(payload))
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"name": "velociraptor"})
+	w := httptest.NewRecorder()
+
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().RecordEvent(gomock.Any(), EventSpeciesUpdated, 0, gomock.Any()).Return(nil)
+
+	ah.UpdateSpecies(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("TestUpdateSpecies did not return success but gave %v", resp.StatusCode)
+	}
+	want := Species{Name: "velociraptor", Diet: CarnivoreCode, Period: PeriodCretaceous, WeightKg: 15, DangerLevel: 5, MaxPerCage: 3}
+	if s, _ := ah.park.Species(context.Background(), "velociraptor"); s != want {
+		t.Errorf("TestUpdateSpecies expected %+v got %+v", want, s)
+	}
+}
+
+func TestUpdateSpeciesDietInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, Species{Name: "velociraptor", Diet: CarnivoreCode})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	r, err := http.NewRequestWithContext(context.Background(), "PUT", "http://localhost:8000/v1/species/velociraptor", strings.NewReader(`{"name":"velociraptor","diet":"H"}`))
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"name": "velociraptor"})
+	w := httptest.NewRecorder()
+
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetDinosaursForSpecies(gomock.Any(), []string{"velociraptor"}).Return([]Dinosaur{{ID: 1, Species: "velociraptor"}}, nil)
+
+	ah.UpdateSpecies(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("TestUpdateSpeciesDietInUse did not return %v but gave %v", http.StatusConflict, resp.StatusCode)
+	}
+	if s, _ := ah.park.Species(context.Background(), "velociraptor"); s.Diet != CarnivoreCode {
+		t.Errorf("TestUpdateSpeciesDietInUse changed the diet to %s", s.Diet)
+	}
+}
+
+func TestDeleteSpecies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap, Species{Name: "dodo", Diet: HerbivoreCode})
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	r, err := http.NewRequestWithContext(context.Background(), "DELETE", "http://localhost:8000/v1/species/dodo", nil)
+	if err != nil {
+		t.Errorf("NewRequest failed with %v", err)
+	}
+	r = mux.SetURLVars(r, map[string]string{"name": "dodo"})
+	w := httptest.NewRecorder()
+
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetDinosaursForSpecies(gomock.Any(), []string{"dodo"}).Return(nil, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), EventSpeciesRemoved, 0, gomock.Any()).Return(nil)
+
+	ah.DeleteSpecies(w, r)
+
+	if resp := w.Result(); resp.StatusCode != http.StatusOK {
+		t.Errorf("TestDeleteSpecies did not return success but gave %v", resp.StatusCode)
+	}
+
+	// a second delete finds nothing
+	w = httptest.NewRecorder()
+	ah.DeleteSpecies(w, r)
+
+	if resp := w.Result(); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("TestDeleteSpecies did not return %v but gave %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestRebalanceCages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	stubSpecies(mockDap)
+	ah := NewAppHandlers(mockDap, DefaultPlacement())
+
+	// a GET only plans while a POST applies the moves
+	tx := mockWork(ctrl, mockDap)
+	cages := []Cage{
+		{ID: 2, Status: StatusActive, Capacity: 4, Count: 1, Kind: "C", Version: 1},
+		{ID: 3, Status: StatusActive, Capacity: 4, Count: 3, Kind: "C", Version: 1},
+	}
+	dinos := []Dinosaur{{ID: 1, Cage: 2}, {ID: 4, Cage: 3}, {ID: 5, Cage: 3}, {ID: 6, Cage: 3}}
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "C").Return(cages, nil).Times(2)
+	tx.EXPECT().FindCages(gomock.Any(), StatusActive, "H").Return(nil, nil).Times(2)
+	tx.EXPECT().GetDinosaursForCages(gomock.Any(), []int{2, 3}).Return(dinos, nil).Times(2)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(Cage{}, nil).Times(2)
+	tx.EXPECT().SaveDinosaur(gomock.Any(), Dinosaur{ID: 6, Cage: 2}).Return(Dinosaur{ID: 6, Cage: 2, Version: 1}, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), EventDinoPlaced, 2, Relocation{Dinosaur: 6, From: 3, To: 2}).Return(nil)
+	for _, tc := range []struct {
+		method, url string
+		dryRun      bool
+	}{
+		{"GET", "/v1/plans/rebalance", true},
+		{"POST", "/v1/plans/rebalance?mode=spread&target=0.5", false},
+	} {
+		w := httptest.NewRecorder()
+		ah.RebalanceCages(w, httptest.NewRequest(tc.method, tc.url, nil))
+		var plan RebalancePlan
+		if err := json.Unmarshal(w.Body.Bytes(), &plan); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("TestRebalanceCages %s %s gave %v %s", tc.method, tc.url, w.Code, w.Body.String())
+		}
+		if plan.DryRun != tc.dryRun || len(plan.Relocations) != 1 {
+			t.Errorf("TestRebalanceCages %s %s returned %+v", tc.method, tc.url, plan)
+		}
+	}
+
+	// bad modes and targets never reach the service
+	for _, url := range []string{"/v1/plans/rebalance?mode=shuffle", "/v1/plans/rebalance?mode=spread", "/v1/plans/rebalance?mode=spread&target=1.5"} {
+		w := httptest.NewRecorder()
+		ah.RebalanceCages(w, httptest.NewRequest("POST", url, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("TestRebalanceCages %s did not return %v but gave %v", url, http.StatusBadRequest, w.Code)
+		}
 	}
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyHeader        = "Idempotency-Key"
+	IdempotencyReplayHeader  = "Idempotent-Replayed"
+	DefaultIdempotencyWindow = 24 * time.Hour
+	// most keys held before the oldest completed responses are evicted
+	DefaultIdempotencyEntries = 10000
+)
+
+// a response recorded against an idempotency key
+type idempotentResponse struct {
+	bodyHash string
+	done     bool
+	status   int
+	header   http.Header
+	body     []byte
+	expires  time.Time
+}
+
+// in memory store of responses to mutating requests keyed on
+// the idempotency key, method and path
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*idempotentResponse
+	// most entries held at once, requests with a new key are refused with
+	// 503 when every held entry is still in progress
+	MaxEntries int
+}
+
+func NewIdempotencyStore(window time.Duration) *IdempotencyStore {
+	if window <= 0 {
+		window = DefaultIdempotencyWindow
+	}
+	return &IdempotencyStore{window: window, entries: make(map[string]*idempotentResponse), MaxEntries: DefaultIdempotencyEntries}
+}
+
+// records a handler response so it can be replayed
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// reserve a key returning any existing entry
+// a nil entry means the store is full of requests still in progress
+func (is *IdempotencyStore) reserve(key, bodyHash string) (*idempotentResponse, bool) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	now := time.Now()
+	if e, ok := is.entries[key]; ok && now.Before(e.expires) {
+		return e, false
+	}
+	if is.MaxEntries > 0 && len(is.entries) >= is.MaxEntries && !is.evictLocked(now) {
+		return nil, false
+	}
+	e := &idempotentResponse{bodyHash: bodyHash, expires: now.Add(is.window)}
+	is.entries[key] = e
+	return e, true
+}
+
+// make room for one entry by dropping expired entries or failing that the
+// completed entry closest to expiry, the caller holds the lock
+func (is *IdempotencyStore) evictLocked(now time.Time) bool {
+	oldest := ""
+	for k, e := range is.entries {
+		if now.After(e.expires) {
+			delete(is.entries, k)
+		} else if e.done && (len(oldest) == 0 || e.expires.Before(is.entries[oldest].expires)) {
+			oldest = k
+		}
+	}
+	if len(is.entries) < is.MaxEntries {
+		return true
+	}
+	if len(oldest) == 0 {
+		return false
+	}
+	delete(is.entries, oldest)
+	return true
+}
+
+// drop a reservation so the request can be retried
+func (is *IdempotencyStore) release(key string) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	delete(is.entries, key)
+}
+
+func (is *IdempotencyStore) complete(key string, rw *recordingWriter) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	e, ok := is.entries[key]
+	if !ok {
+		return
+	}
+	// failed server side requests may be retried with the same key
+	if rw.status >= http.StatusInternalServerError {
+		delete(is.entries, key)
+		return
+	}
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	e.done = true
+	e.status = rw.status
+	e.header = rw.Header().Clone()
+	e.body = rw.body.Bytes()
+}
+
+// drop expired entries
+func (is *IdempotencyStore) purge() {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	now := time.Now()
+	for k, e := range is.entries {
+		if now.After(e.expires) {
+			delete(is.entries, k)
+		}
+	}
+}
+
+// periodically purge expired entries until the context is done
+func (is *IdempotencyStore) Janitor(ctx context.Context) {
+	ticker := time.NewTicker(is.window / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			is.purge()
+		}
+	}
+}
+
+// mux middleware honoring the Idempotency-Key header on mutating requests
+// a replay returns the original response while reuse of a key with a
+// different payload returns 422
+func (is *IdempotencyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idemKey := r.Header.Get(IdempotencyHeader)
+		if len(idemKey) == 0 || !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var body []byte
+		if r.Body != nil {
+			var err error
+			// the largest payload any route accepts, routes limit themselves further
+			body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, MaxImportBytes))
+			if err != nil {
+				WriteError(w, err, http.StatusBadRequest)
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+		key := idemKey + " " + r.Method + " " + r.URL.RequestURI()
+
+		e, fresh := is.reserve(key, bodyHash)
+		if e == nil {
+			w.Header().Set("Retry-After", "1")
+			WriteMsg(w, http.StatusServiceUnavailable, "too many requests in progress")
+			return
+		}
+		if !fresh {
+			is.mu.Lock()
+			hash, done, status, header, saved := e.bodyHash, e.done, e.status, e.header, e.body
+			is.mu.Unlock()
+			switch {
+			case hash != bodyHash:
+				WriteMsg(w, http.StatusUnprocessableEntity, "idempotency key reused with a different payload")
+			case !done:
+				WriteMsg(w, http.StatusConflict, "request with this idempotency key is in progress")
+			default:
+				for k, v := range header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(IdempotencyReplayHeader, "true")
+				WriteMsg(w, status, string(saved))
+			}
+			return
+		}
+		rw := &recordingWriter{ResponseWriter: w}
+		defer func() {
+			// a panicking handler must not leave the key in progress forever
+			if p := recover(); p != nil {
+				is.release(key)
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		is.complete(key, rw)
+	})
+}
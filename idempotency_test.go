@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		WriteMsg(w, http.StatusOK, `{"id":7}`)
+	})
+	store := NewIdempotencyStore(time.Minute)
+	h := store.Middleware(handler)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "http://localhost:8000/v1/cage/H/add", strings.NewReader(body))
+		r.Header.Set(IdempotencyHeader, "abc")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send(`{}`)
+	second := send(`{}`)
+	if calls != 1 {
+		t.Errorf("expected handler to be called once got %d", calls)
+	}
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Errorf("replay gave %d %q expected %d %q", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get(IdempotencyReplayHeader) != "true" {
+		t.Errorf("expected replay header on second response")
+	}
+
+	third := send(`{"cap":3}`)
+	if third.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reuse with different payload gave %d expected %d", third.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyIgnoresReads(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		WriteOk(w)
+	})
+	h := NewIdempotencyStore(time.Minute).Middleware(handler)
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "http://localhost:8000/v1/cages", nil)
+		r.Header.Set(IdempotencyHeader, "abc")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if calls != 2 {
+		t.Errorf("expected GET requests to bypass the store got %d calls", calls)
+	}
+}
+
+func TestIdempotencyBounds(t *testing.T) {
+	store := NewIdempotencyStore(time.Minute)
+	store.MaxEntries = 2
+	block := make(chan struct{})
+	panicked := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panic":
+			if !panicked {
+				panicked = true
+				panic(http.ErrAbortHandler)
+			}
+		case "/block":
+			<-block
+		}
+		WriteOk(w)
+	})
+	h := store.Middleware(handler)
+	send := func(key, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "http://localhost:8000"+path, strings.NewReader(body))
+		r.Header.Set(IdempotencyHeader, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// a panic releases the key so a retry is not stuck in progress
+	func() {
+		defer func() { recover() }()
+		send("p", "/panic", "")
+	}()
+	if w := send("p", "/panic", ""); w.Code != http.StatusOK {
+		t.Errorf("retry after panic gave %d %s", w.Code, w.Body.String())
+	}
+
+	// completed entries are evicted to make room
+	send("a", "/ok", "")
+	send("b", "/ok", "")
+	if w := send("c", "/ok", ""); w.Code != http.StatusOK {
+		t.Errorf("full store of completed entries gave %d", w.Code)
+	}
+	store.mu.Lock()
+	n := len(store.entries)
+	store.mu.Unlock()
+	if n > store.MaxEntries {
+		t.Errorf("store holds %d entries expected at most %d", n, store.MaxEntries)
+	}
+
+	// a store full of requests in progress refuses new keys
+	done := make(chan struct{})
+	for _, k := range []string{"x", "y"} {
+		go func(k string) {
+			send(k, "/block", "")
+			done <- struct{}{}
+		}(k)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		inProgress := 0
+		for _, e := range store.entries {
+			if !e.done {
+				inProgress++
+			}
+		}
+		store.mu.Unlock()
+		if inProgress == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if w := send("z", "/ok", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("full store of requests in progress gave %d", w.Code)
+	}
+	close(block)
+	<-done
+	<-done
+
+	// the body read is limited
+	if w := send("big", "/ok", strings.Repeat(" ", MaxImportBytes+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body gave %d", w.Code)
+	}
+}
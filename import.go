@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"dinocage/das"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// work out the import format from an explicit parameter or the content type
+func ImportFormat(param, contentType string) (string, bool) {
+	switch strings.ToLower(param) {
+	case FormatCSV:
+		return FormatCSV, true
+	case FormatJSONL, "ndjson":
+		return FormatJSONL, true
+	case "":
+	default:
+		return "", false
+	}
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return FormatCSV, true
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		return FormatJSONL, true
+	default:
+		return "", false
+	}
+}
+
+// read import records in either csv or json lines format
+// csv input must start with a header naming the columns
+func ParseImport(r io.Reader, format string) ([]das.ImportRecord, error) {
+	if format == FormatCSV {
+		return parseImportCSV(r)
+	}
+	var records []das.ImportRecord
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var rec das.ImportRecord
+		err := json.Unmarshal(b, &rec)
+		if err != nil {
+			return records, fmt.Errorf("line %d : %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+func parseImportCSV(r io.Reader) ([]das.ImportRecord, error) {
+	var records []das.ImportRecord
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return records, fmt.Errorf("unable to read csv header : %v", err)
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		var rec das.ImportRecord
+		for i, col := range header {
+			if i >= len(row) {
+				break
+			}
+			v := strings.TrimSpace(row[i])
+			switch strings.ToLower(strings.TrimSpace(col)) {
+			case "type":
+				rec.Type = v
+			case "id":
+				rec.ID, err = atoiOrZero(v)
+			case "name":
+				rec.Name = v
+			case "species":
+				rec.Species = v
+			case "diet":
+				rec.Diet = v
+			case "kind":
+				rec.Kind = v
+			case "status":
+				rec.Status = v
+			case "capacity":
+				rec.Capacity, err = atoiOrZero(v)
+			case "cage":
+				rec.Cage, err = atoiOrZero(v)
+			default:
+				return records, fmt.Errorf("unknown csv column %s", col)
+			}
+			if err != nil {
+				line, _ := cr.FieldPos(i)
+				return records, fmt.Errorf("line %d column %s : %v", line, col, err)
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+func atoiOrZero(v string) (int, error) {
+	if len(v) == 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// check import records by applying them in a unit of work that is rolled back
+// so every row is held to the same rules as the import itself, species rows
+// apply to later rows and rows that would have been imported are reported
+// as not attempted
+func (ah AppHandlers) ValidateImport(ctx context.Context, records []das.ImportRecord, placement das.PlacementOptions) ([]das.ImportResult, error) {
+	results, err := ah.park.Import(ctx, records, das.ImportOptions{ValidateOnly: true, Placement: placement})
+	for i := range results {
+		if results[i].OK {
+			results[i].OK = false
+			results[i].Error = "not attempted"
+		}
+	}
+	return results, err
+}
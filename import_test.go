@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dinocage/das"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	payload := "type,name,species,diet,kind,capacity,cage\n" +
+		"species,Dilophosaurus,,C,,,\n" +
+		"cage,,,,C,5,\n" +
+		"dinosaur,spitter,dilophosaurus,,,,3\n"
+	records, err := ParseImport(strings.NewReader(payload), FormatCSV)
+	if err != nil {
+		t.Fatalf("ParseImport failed with %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records got %d", len(records))
+	}
+	if records[1].Capacity != 5 || records[2].Cage != 3 || records[2].Species != "dilophosaurus" {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestParseImportJSONL(t *testing.T) {
+	payload := `{"type":"cage","kind":"H","capacity":10}
+
+{"type":"dinosaur","species":"triceratops","name":"sarah"}
+`
+	records, err := ParseImport(strings.NewReader(payload), FormatJSONL)
+	if err != nil {
+		t.Fatalf("ParseImport failed with %v", err)
+	}
+	if len(records) != 2 || records[0].Capacity != 10 || records[1].Name != "sarah" {
+		t.Errorf("unexpected records %+v", records)
+	}
+	_, err = ParseImport(strings.NewReader("{bad"), FormatJSONL)
+	if err == nil {
+		t.Errorf("expected error for bad json line")
+	}
+}
+
+// a provider over a new sqlite database
+func sqliteProvider(t *testing.T) das.DataAccessProvider {
+	dap, err := das.ConnectSQLite(filepath.Join(t.TempDir(), "dinocage.db"), schema)
+	if err != nil {
+		t.Fatalf("unable to open sqlite %v", err)
+	}
+	t.Cleanup(dap.Close)
+	return dap
+}
+
+func TestValidateImport(t *testing.T) {
+	ctx := context.Background()
+	dap := sqliteProvider(t)
+	ah := NewAppHandlers(dap, das.DefaultPlacement())
+	if _, err := ah.park.AddSpecies(ctx, das.Species{Name: "triceratops", Diet: das.HerbivoreCode}); err != nil {
+		t.Fatalf("add species failed %v", err)
+	}
+	clock, _ := dap.EventClock(ctx)
+
+	records := []das.ImportRecord{
+		{Type: "Species", Name: "Dilophosaurus", Diet: "c"},
+		{Type: "cage", Kind: "C", Capacity: 1},
+		{Type: "dinosaur", Species: "dilophosaurus", Name: "spitter"},
+		{Type: "dinosaur", Species: "dilophosaurus", Name: "spat"},
+		{Type: "dinosaur", Species: "triceratops", Name: "sarah", Diet: "C"},
+		{Type: "cage", Kind: "H", Capacity: 0},
+		{Type: "dinosaur", Species: "mosasaurus", Name: "mo"},
+		{Type: "fence"},
+	}
+	results, err := ah.ValidateImport(ctx, records, das.PlacementOptions{Strategy: das.DefaultStrategy})
+	if err != nil {
+		t.Fatalf("validate import failed %v", err)
+	}
+	// the second dilophosaurus finds the imported cage already full
+	for i, ok := range []bool{true, true, true, false, false, false, false, false} {
+		res := results[i]
+		if res.OK || (res.Error == "not attempted") != ok || res.ID != 0 {
+			t.Errorf("row %d expected valid %v got %+v", i+1, ok, res)
+		}
+	}
+	if results[0].Type != das.ImportSpecies {
+		t.Errorf("expected the row type in lower case got %q", results[0].Type)
+	}
+
+	// nothing is kept
+	if cages, _ := dap.GetCages(ctx); len(cages) != 0 {
+		t.Errorf("expected no cages got %+v", cages)
+	}
+	if _, err := dap.GetSpecies(ctx, "dilophosaurus"); !errors.Is(err, das.ErrSpeciesNotFound) {
+		t.Errorf("expected the imported species rolled back got %v", err)
+	}
+	if after, _ := dap.EventClock(ctx); after != clock {
+		t.Errorf("expected no events got clock %d after %d", after, clock)
+	}
+}
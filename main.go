@@ -2,49 +2,158 @@ package main
 
 import (
 	"context"
+	_ "embed"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"dinocage/das"
 )
 
 const (
+	EnvDBDriver     = "ENV_DB_DRIVER"
 	EnvDBHost       = "ENV_DB_HOST"
 	EnvDBPort       = "ENV_DB_PORT"
 	EnvDBName       = "ENV_DB_NAME"
 	EnvDBUsr        = "ENV_DB_USR"
 	EnvDBPass       = "ENV_DB_PWD"
+	EnvDBReplicas   = "ENV_DB_REPLICAS"
 	EnvSvrEndpoint  = "ENV_SVR_ENDPOINT"
+	EnvGrpcEndpoint = "ENV_GRPC_ENDPOINT"
+	EnvStrategy     = "ENV_PLACEMENT_STRATEGY"
+	EnvAutoCreate   = "ENV_AUTO_CREATE_CAGES"
+	EnvIdemWindow   = "ENV_IDEMPOTENCY_WINDOW"
+	EnvCacheTTL     = "ENV_CACHE_TTL"
+	EnvCacheSize    = "ENV_CACHE_SIZE"
+	EnvSpeciesWatch = "ENV_SPECIES_WATCH_INTERVAL"
 	DefaultEndpoint = ":8000"
 )
 
+// database drivers, for sqlite the database name is the path of its file
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// the schema shared by every database driver
+//
+//go:embed dataset/init.sql
+var schema string
+
 type EnvParams struct {
 	ServerEndpoint string
+	GrpcEndpoint   string
+	DbDriver       string
 	DbHost         string
 	DbPort         string
 	DbName         string
 	DbUser         string
 	DbPass         string
+	DbReplicas     []string
+	Placement      das.PlacementOptions
+	IdemWindow     time.Duration
+	CacheTTL       time.Duration
+	CacheSize      int
+	SpeciesWatch   time.Duration
 }
 
 // extract params from env - should implement defaults
 func InitConfigFromEnv() EnvParams {
 	var ep EnvParams
+	ep.DbDriver = os.Getenv(EnvDBDriver)
+	if len(ep.DbDriver) == 0 {
+		ep.DbDriver = DriverPostgres
+	}
 	ep.DbHost = os.Getenv(EnvDBHost)
 	ep.DbPort = os.Getenv(EnvDBPort)
 	ep.DbName = os.Getenv(EnvDBName)
 	ep.DbUser = os.Getenv(EnvDBUsr)
 	ep.DbPass = os.Getenv(EnvDBPass)
+	// comma separated host:port of postgres replicas or paths of sqlite copies
+	for _, replica := range strings.Split(os.Getenv(EnvDBReplicas), ",") {
+		if replica = strings.TrimSpace(replica); len(replica) != 0 {
+			ep.DbReplicas = append(ep.DbReplicas, replica)
+		}
+	}
 	ep.ServerEndpoint = os.Getenv(EnvSvrEndpoint)
 	if len(ep.ServerEndpoint) == 0 {
 		ep.ServerEndpoint = DefaultEndpoint
 	}
+	ep.GrpcEndpoint = os.Getenv(EnvGrpcEndpoint)
+	if len(ep.GrpcEndpoint) == 0 {
+		ep.GrpcEndpoint = DefaultGrpcEndpoint
+	}
+	ep.Placement = das.DefaultPlacement()
+	if strategy := os.Getenv(EnvStrategy); len(strategy) != 0 {
+		if das.ValidStrategy(strategy) {
+			ep.Placement.Strategy = strategy
+		} else {
+			log.Printf("ignoring unknown placement strategy %s", strategy)
+		}
+	}
+	if autoCreate, err := strconv.ParseBool(os.Getenv(EnvAutoCreate)); err == nil {
+		ep.Placement.AutoCreate = autoCreate
+	}
+	ep.IdemWindow = DefaultIdempotencyWindow
+	if window := os.Getenv(EnvIdemWindow); len(window) != 0 {
+		d, err := time.ParseDuration(window)
+		if err == nil && d > 0 {
+			ep.IdemWindow = d
+		} else {
+			log.Printf("ignoring bad idempotency window %s", window)
+		}
+	}
+	// a zero ttl turns the cage cache off
+	ep.CacheTTL = DefaultCacheTTL
+	if ttl := os.Getenv(EnvCacheTTL); len(ttl) != 0 {
+		d, err := time.ParseDuration(ttl)
+		if err == nil && d >= 0 {
+			ep.CacheTTL = d
+		} else {
+			log.Printf("ignoring bad cache ttl %s", ttl)
+		}
+	}
+	ep.CacheSize = DefaultCacheSize
+	if size := os.Getenv(EnvCacheSize); len(size) != 0 {
+		n, err := strconv.Atoi(size)
+		if err == nil && n > 0 {
+			ep.CacheSize = n
+		} else {
+			log.Printf("ignoring bad cache size %s", size)
+		}
+	}
+	// a zero interval only reloads species on SIGHUP
+	ep.SpeciesWatch = DefaultSpeciesInterval
+	if watch := os.Getenv(EnvSpeciesWatch); len(watch) != 0 {
+		d, err := time.ParseDuration(watch)
+		if err == nil && d >= 0 {
+			ep.SpeciesWatch = d
+		} else {
+			log.Printf("ignoring bad species watch interval %s", watch)
+		}
+	}
 	return ep
 }
 
+// connect to the database of the configured driver
+func ConnectDB(ep EnvParams) (das.DataAccessProvider, error) {
+	switch ep.DbDriver {
+	case DriverPostgres:
+		return das.Connect(ep.DbHost, ep.DbPort, ep.DbUser, ep.DbPass, ep.DbName, ep.DbReplicas...)
+	case DriverSQLite:
+		return das.ConnectSQLite(ep.DbName, schema, ep.DbReplicas...)
+	default:
+		return nil, fmt.Errorf("unknown database driver %s", ep.DbDriver)
+	}
+}
+
 var speciesFilename *string = flag.String("sf", "species.json", "species reference file")
 
 func main() {
@@ -53,41 +162,104 @@ func main() {
 	envCfg := InitConfigFromEnv()
 	log.Printf("cfg : %+v\n", envCfg)
 
-	// read static species list
-	// this should be a persistent mutable repository (database or other)
+	// read the species reference file, it seeds an empty species table and
+	// later changes to it are applied by the reloader
 	speciesMap, err := ReadSpecies(*speciesFilename)
 
 	if err != nil {
-		// TODO provide an api to update species in a repo
 		log.Fatalf("unable to load a species map : %v", err)
 		return
 	}
 
 	// connect to database
-	dap, err := das.Connect(envCfg.DbHost, envCfg.DbPort, envCfg.DbUser, envCfg.DbPass, envCfg.DbName)
+	dap, err := ConnectDB(envCfg)
 	if err != nil {
 		log.Printf("unable to connect to database : %v", err)
 		return
 	}
 	ctx, cancel := context.WithCancel(context.Background())
+	var cache *CachedProvider
+	if envCfg.CacheTTL > 0 {
+		cache = NewCachedProvider(dap, envCfg.CacheSize, envCfg.CacheTTL)
+		dap = cache
+	}
+	appHandlers := NewAppHandlers(dap, envCfg.Placement)
+	var seed []das.Species
+	for _, e := range SortedEntries(speciesMap) {
+		seed = append(seed, e.Value)
+	}
+	seeded, err := appHandlers.park.SeedSpecies(ctx, seed)
+	if err != nil {
+		log.Printf("unable to seed species : %v", err)
+		cancel()
+		dap.Close()
+		return
+	}
+	if seeded != 0 {
+		log.Printf("seeded %d species from %s", seeded, *speciesFilename)
+	}
+	appHandlers.cache = cache
+	appHandlers.idempotent = NewIdempotencyStore(envCfg.IdemWindow)
+	appHandlers.events = NewEventHub(DefaultEventBuffer)
+	species := NewSpeciesReloader(*speciesFilename, appHandlers.park, speciesMap)
+	species.Interval = envCfg.SpeciesWatch
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	webhooks := NewWebhookDispatcher(dap)
+	outbox := NewOutboxDispatcher(dap, LogSink{}, webhooks)
+
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(6)
+	go func() {
+		// reload species when the file changes or on SIGHUP until shutdown
+		species.Run(ctx, hup)
+		wg.Done()
+	}()
+	if cache != nil {
+		wg.Add(1)
+		go func() {
+			// clear cached cages on changes made by any instance until shutdown
+			cache.Follow(ctx)
+			wg.Done()
+		}()
+	}
+	go func() {
+		// stream the events committed by any instance until shutdown
+		appHandlers.events.Follow(ctx, dap)
+		wg.Done()
+	}()
+	go func() {
+		// deliver webhooks in background until shutdown
+		webhooks.Run(ctx)
+		wg.Done()
+	}()
+	go func() {
+		// drain the event outbox in background until shutdown
+		outbox.Run(ctx)
+		wg.Done()
+	}()
 	go func() {
 		// start server in background
-		err := StartServer(ctx, envCfg.ServerEndpoint, &AppHandlers{dap: dap, speciesMap: speciesMap})
+		err := StartServer(ctx, envCfg.ServerEndpoint, appHandlers)
 		log.Printf("server returned %v - shutting down", err)
 		wg.Done()
 	}()
+	go func() {
+		// start grpc server beside the http server
+		err := StartGrpcServer(ctx, envCfg.GrpcEndpoint, appHandlers)
+		log.Printf("grpc server returned %v - shutting down", err)
+		wg.Done()
+	}()
 
 	// keep server alive until
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	<-c
 	// try to shutdown gracefully
 
 	// cancel context to initiate server shutdown
 	cancel()
-	// wait for http server to shudown
+	// wait for http and grpc servers to shudown
 	wg.Wait()
 	// close database
 	dap.Close()
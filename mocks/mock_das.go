@@ -6,12 +6,1115 @@ package mocks
 
 import (
 	context "context"
+	sql "database/sql"
 	das "dinocage/das"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
 
+// MockWebhookStore is a mock of WebhookStore interface.
+type MockWebhookStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookStoreMockRecorder
+}
+
+// MockWebhookStoreMockRecorder is the mock recorder for MockWebhookStore.
+type MockWebhookStoreMockRecorder struct {
+	mock *MockWebhookStore
+}
+
+// NewMockWebhookStore creates a new mock instance.
+func NewMockWebhookStore(ctrl *gomock.Controller) *MockWebhookStore {
+	mock := &MockWebhookStore{ctrl: ctrl}
+	mock.recorder = &MockWebhookStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookStore) EXPECT() *MockWebhookStoreMockRecorder {
+	return m.recorder
+}
+
+// AddDelivery mocks base method.
+func (m *MockWebhookStore) AddDelivery(ctx context.Context, d das.Delivery) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDelivery", ctx, d)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDelivery indicates an expected call of AddDelivery.
+func (mr *MockWebhookStoreMockRecorder) AddDelivery(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDelivery", reflect.TypeOf((*MockWebhookStore)(nil).AddDelivery), ctx, d)
+}
+
+// AddWebhook mocks base method.
+func (m *MockWebhookStore) AddWebhook(ctx context.Context, wh das.Webhook) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWebhook", ctx, wh)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWebhook indicates an expected call of AddWebhook.
+func (mr *MockWebhookStoreMockRecorder) AddWebhook(ctx, wh interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWebhook", reflect.TypeOf((*MockWebhookStore)(nil).AddWebhook), ctx, wh)
+}
+
+// ClaimDelivery mocks base method.
+func (m *MockWebhookStore) ClaimDelivery(ctx context.Context, id int, lease time.Duration) (das.Delivery, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDelivery", ctx, id, lease)
+	ret0, _ := ret[0].(das.Delivery)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimDelivery indicates an expected call of ClaimDelivery.
+func (mr *MockWebhookStoreMockRecorder) ClaimDelivery(ctx, id, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDelivery", reflect.TypeOf((*MockWebhookStore)(nil).ClaimDelivery), ctx, id, lease)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhookStore) DeleteWebhook(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookStoreMockRecorder) DeleteWebhook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhookStore)(nil).DeleteWebhook), ctx, id)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockWebhookStore) ListDeliveries(ctx context.Context, webhookID int, status string) ([]das.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", ctx, webhookID, status)
+	ret0, _ := ret[0].([]das.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockWebhookStoreMockRecorder) ListDeliveries(ctx, webhookID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockWebhookStore)(nil).ListDeliveries), ctx, webhookID, status)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockWebhookStore) ListWebhooks(ctx context.Context) ([]das.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx)
+	ret0, _ := ret[0].([]das.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockWebhookStoreMockRecorder) ListWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockWebhookStore)(nil).ListWebhooks), ctx)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockWebhookStore) UpdateDelivery(ctx context.Context, d das.Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockWebhookStoreMockRecorder) UpdateDelivery(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockWebhookStore)(nil).UpdateDelivery), ctx, d)
+}
+
+// MockOutboxStore is a mock of OutboxStore interface.
+type MockOutboxStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxStoreMockRecorder
+}
+
+// MockOutboxStoreMockRecorder is the mock recorder for MockOutboxStore.
+type MockOutboxStoreMockRecorder struct {
+	mock *MockOutboxStore
+}
+
+// NewMockOutboxStore creates a new mock instance.
+func NewMockOutboxStore(ctrl *gomock.Controller) *MockOutboxStore {
+	mock := &MockOutboxStore{ctrl: ctrl}
+	mock.recorder = &MockOutboxStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxStore) EXPECT() *MockOutboxStoreMockRecorder {
+	return m.recorder
+}
+
+// CommittedEvents mocks base method.
+func (m *MockOutboxStore) CommittedEvents(ctx context.Context, after das.EventCursor, limit int) ([]das.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommittedEvents", ctx, after, limit)
+	ret0, _ := ret[0].([]das.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommittedEvents indicates an expected call of CommittedEvents.
+func (mr *MockOutboxStoreMockRecorder) CommittedEvents(ctx, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommittedEvents", reflect.TypeOf((*MockOutboxStore)(nil).CommittedEvents), ctx, after, limit)
+}
+
+// EventClock mocks base method.
+func (m *MockOutboxStore) EventClock(ctx context.Context) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EventClock", ctx)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EventClock indicates an expected call of EventClock.
+func (mr *MockOutboxStoreMockRecorder) EventClock(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EventClock", reflect.TypeOf((*MockOutboxStore)(nil).EventClock), ctx)
+}
+
+// MarkDispatched mocks base method.
+func (m *MockOutboxStore) MarkDispatched(ctx context.Context, id uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDispatched", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDispatched indicates an expected call of MarkDispatched.
+func (mr *MockOutboxStoreMockRecorder) MarkDispatched(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDispatched", reflect.TypeOf((*MockOutboxStore)(nil).MarkDispatched), ctx, id)
+}
+
+// PendingEvents mocks base method.
+func (m *MockOutboxStore) PendingEvents(ctx context.Context, limit int) ([]das.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingEvents", ctx, limit)
+	ret0, _ := ret[0].([]das.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingEvents indicates an expected call of PendingEvents.
+func (mr *MockOutboxStoreMockRecorder) PendingEvents(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingEvents", reflect.TypeOf((*MockOutboxStore)(nil).PendingEvents), ctx, limit)
+}
+
+// PruneDispatched mocks base method.
+func (m *MockOutboxStore) PruneDispatched(ctx context.Context, age time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneDispatched", ctx, age)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneDispatched indicates an expected call of PruneDispatched.
+func (mr *MockOutboxStoreMockRecorder) PruneDispatched(ctx, age interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneDispatched", reflect.TypeOf((*MockOutboxStore)(nil).PruneDispatched), ctx, age)
+}
+
+// RecordEvent mocks base method.
+func (m *MockOutboxStore) RecordEvent(ctx context.Context, kind string, cage int, data any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordEvent", ctx, kind, cage, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordEvent indicates an expected call of RecordEvent.
+func (mr *MockOutboxStoreMockRecorder) RecordEvent(ctx, kind, cage, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordEvent", reflect.TypeOf((*MockOutboxStore)(nil).RecordEvent), ctx, kind, cage, data)
+}
+
+// MockCageRepo is a mock of CageRepo interface.
+type MockCageRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockCageRepoMockRecorder
+}
+
+// MockCageRepoMockRecorder is the mock recorder for MockCageRepo.
+type MockCageRepoMockRecorder struct {
+	mock *MockCageRepo
+}
+
+// NewMockCageRepo creates a new mock instance.
+func NewMockCageRepo(ctrl *gomock.Controller) *MockCageRepo {
+	mock := &MockCageRepo{ctrl: ctrl}
+	mock.recorder = &MockCageRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCageRepo) EXPECT() *MockCageRepoMockRecorder {
+	return m.recorder
+}
+
+// AddStatusTransition mocks base method.
+func (m *MockCageRepo) AddStatusTransition(ctx context.Context, st das.StatusTransition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddStatusTransition", ctx, st)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddStatusTransition indicates an expected call of AddStatusTransition.
+func (mr *MockCageRepoMockRecorder) AddStatusTransition(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddStatusTransition", reflect.TypeOf((*MockCageRepo)(nil).AddStatusTransition), ctx, st)
+}
+
+// FindCages mocks base method.
+func (m *MockCageRepo) FindCages(ctx context.Context, status string, kind string) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCages", ctx, status, kind)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCages indicates an expected call of FindCages.
+func (mr *MockCageRepoMockRecorder) FindCages(ctx, status, kind interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCages", reflect.TypeOf((*MockCageRepo)(nil).FindCages), ctx, status, kind)
+}
+
+// GetCage mocks base method.
+func (m *MockCageRepo) GetCage(ctx context.Context, cageID int) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCage", ctx, cageID)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCage indicates an expected call of GetCage.
+func (mr *MockCageRepoMockRecorder) GetCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCage", reflect.TypeOf((*MockCageRepo)(nil).GetCage), ctx, cageID)
+}
+
+// GetCageStatusHistory mocks base method.
+func (m *MockCageRepo) GetCageStatusHistory(ctx context.Context, cageID int) ([]das.StatusTransition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCageStatusHistory", ctx, cageID)
+	ret0, _ := ret[0].([]das.StatusTransition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCageStatusHistory indicates an expected call of GetCageStatusHistory.
+func (mr *MockCageRepoMockRecorder) GetCageStatusHistory(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCageStatusHistory", reflect.TypeOf((*MockCageRepo)(nil).GetCageStatusHistory), ctx, cageID)
+}
+
+// GetCages mocks base method.
+func (m *MockCageRepo) GetCages(ctx context.Context, optStatus ...string) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range optStatus {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCages", varargs...)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCages indicates an expected call of GetCages.
+func (mr *MockCageRepoMockRecorder) GetCages(ctx interface{}, optStatus ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, optStatus...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCages", reflect.TypeOf((*MockCageRepo)(nil).GetCages), varargs...)
+}
+
+// GetCagesByID mocks base method.
+func (m *MockCageRepo) GetCagesByID(ctx context.Context, cageIDs []int) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCagesByID", ctx, cageIDs)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCagesByID indicates an expected call of GetCagesByID.
+func (mr *MockCageRepoMockRecorder) GetCagesByID(ctx, cageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCagesByID", reflect.TypeOf((*MockCageRepo)(nil).GetCagesByID), ctx, cageIDs)
+}
+
+// InsertCage mocks base method.
+func (m *MockCageRepo) InsertCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertCage", ctx, cage)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertCage indicates an expected call of InsertCage.
+func (mr *MockCageRepoMockRecorder) InsertCage(ctx, cage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCage", reflect.TypeOf((*MockCageRepo)(nil).InsertCage), ctx, cage)
+}
+
+// RemoveCage mocks base method.
+func (m *MockCageRepo) RemoveCage(ctx context.Context, cageID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCage", ctx, cageID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCage indicates an expected call of RemoveCage.
+func (mr *MockCageRepoMockRecorder) RemoveCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCage", reflect.TypeOf((*MockCageRepo)(nil).RemoveCage), ctx, cageID)
+}
+
+// SaveCage mocks base method.
+func (m *MockCageRepo) SaveCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCage", ctx, cage)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveCage indicates an expected call of SaveCage.
+func (mr *MockCageRepoMockRecorder) SaveCage(ctx, cage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCage", reflect.TypeOf((*MockCageRepo)(nil).SaveCage), ctx, cage)
+}
+
+// MockDinoRepo is a mock of DinoRepo interface.
+type MockDinoRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockDinoRepoMockRecorder
+}
+
+// MockDinoRepoMockRecorder is the mock recorder for MockDinoRepo.
+type MockDinoRepoMockRecorder struct {
+	mock *MockDinoRepo
+}
+
+// NewMockDinoRepo creates a new mock instance.
+func NewMockDinoRepo(ctrl *gomock.Controller) *MockDinoRepo {
+	mock := &MockDinoRepo{ctrl: ctrl}
+	mock.recorder = &MockDinoRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDinoRepo) EXPECT() *MockDinoRepoMockRecorder {
+	return m.recorder
+}
+
+// GetDinosaur mocks base method.
+func (m *MockDinoRepo) GetDinosaur(ctx context.Context, dinoID int) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaur", ctx, dinoID)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaur indicates an expected call of GetDinosaur.
+func (mr *MockDinoRepoMockRecorder) GetDinosaur(ctx, dinoID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaur", reflect.TypeOf((*MockDinoRepo)(nil).GetDinosaur), ctx, dinoID)
+}
+
+// GetDinosaurs mocks base method.
+func (m *MockDinoRepo) GetDinosaurs(ctx context.Context, opts ...string) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetDinosaurs", varargs...)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaurs indicates an expected call of GetDinosaurs.
+func (mr *MockDinoRepoMockRecorder) GetDinosaurs(ctx interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaurs", reflect.TypeOf((*MockDinoRepo)(nil).GetDinosaurs), varargs...)
+}
+
+// GetDinosaursForCage mocks base method.
+func (m *MockDinoRepo) GetDinosaursForCage(ctx context.Context, cageID int) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForCage", ctx, cageID)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForCage indicates an expected call of GetDinosaursForCage.
+func (mr *MockDinoRepoMockRecorder) GetDinosaursForCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForCage", reflect.TypeOf((*MockDinoRepo)(nil).GetDinosaursForCage), ctx, cageID)
+}
+
+// GetDinosaursForCages mocks base method.
+func (m *MockDinoRepo) GetDinosaursForCages(ctx context.Context, cageIDs []int) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForCages", ctx, cageIDs)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForCages indicates an expected call of GetDinosaursForCages.
+func (mr *MockDinoRepoMockRecorder) GetDinosaursForCages(ctx, cageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForCages", reflect.TypeOf((*MockDinoRepo)(nil).GetDinosaursForCages), ctx, cageIDs)
+}
+
+// GetDinosaursForSpecies mocks base method.
+func (m *MockDinoRepo) GetDinosaursForSpecies(ctx context.Context, species []string) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForSpecies", ctx, species)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForSpecies indicates an expected call of GetDinosaursForSpecies.
+func (mr *MockDinoRepoMockRecorder) GetDinosaursForSpecies(ctx, species interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForSpecies", reflect.TypeOf((*MockDinoRepo)(nil).GetDinosaursForSpecies), ctx, species)
+}
+
+// InsertDinosaur mocks base method.
+func (m *MockDinoRepo) InsertDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDinosaur", ctx, d)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertDinosaur indicates an expected call of InsertDinosaur.
+func (mr *MockDinoRepoMockRecorder) InsertDinosaur(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDinosaur", reflect.TypeOf((*MockDinoRepo)(nil).InsertDinosaur), ctx, d)
+}
+
+// RemoveDinosaur mocks base method.
+func (m *MockDinoRepo) RemoveDinosaur(ctx context.Context, dinoID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveDinosaur", ctx, dinoID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveDinosaur indicates an expected call of RemoveDinosaur.
+func (mr *MockDinoRepoMockRecorder) RemoveDinosaur(ctx, dinoID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveDinosaur", reflect.TypeOf((*MockDinoRepo)(nil).RemoveDinosaur), ctx, dinoID)
+}
+
+// SaveDinosaur mocks base method.
+func (m *MockDinoRepo) SaveDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDinosaur", ctx, d)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDinosaur indicates an expected call of SaveDinosaur.
+func (mr *MockDinoRepoMockRecorder) SaveDinosaur(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDinosaur", reflect.TypeOf((*MockDinoRepo)(nil).SaveDinosaur), ctx, d)
+}
+
+// MockSpeciesRepo is a mock of SpeciesRepo interface.
+type MockSpeciesRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockSpeciesRepoMockRecorder
+}
+
+// MockSpeciesRepoMockRecorder is the mock recorder for MockSpeciesRepo.
+type MockSpeciesRepoMockRecorder struct {
+	mock *MockSpeciesRepo
+}
+
+// NewMockSpeciesRepo creates a new mock instance.
+func NewMockSpeciesRepo(ctrl *gomock.Controller) *MockSpeciesRepo {
+	mock := &MockSpeciesRepo{ctrl: ctrl}
+	mock.recorder = &MockSpeciesRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSpeciesRepo) EXPECT() *MockSpeciesRepoMockRecorder {
+	return m.recorder
+}
+
+// GetSpecies mocks base method.
+func (m *MockSpeciesRepo) GetSpecies(ctx context.Context, name string) (das.Species, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpecies", ctx, name)
+	ret0, _ := ret[0].(das.Species)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpecies indicates an expected call of GetSpecies.
+func (mr *MockSpeciesRepoMockRecorder) GetSpecies(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpecies", reflect.TypeOf((*MockSpeciesRepo)(nil).GetSpecies), ctx, name)
+}
+
+// ListSpecies mocks base method.
+func (m *MockSpeciesRepo) ListSpecies(ctx context.Context) ([]das.Species, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSpecies", ctx)
+	ret0, _ := ret[0].([]das.Species)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSpecies indicates an expected call of ListSpecies.
+func (mr *MockSpeciesRepoMockRecorder) ListSpecies(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpecies", reflect.TypeOf((*MockSpeciesRepo)(nil).ListSpecies), ctx)
+}
+
+// RemoveSpecies mocks base method.
+func (m *MockSpeciesRepo) RemoveSpecies(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveSpecies", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveSpecies indicates an expected call of RemoveSpecies.
+func (mr *MockSpeciesRepoMockRecorder) RemoveSpecies(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSpecies", reflect.TypeOf((*MockSpeciesRepo)(nil).RemoveSpecies), ctx, name)
+}
+
+// SaveSpecies mocks base method.
+func (m *MockSpeciesRepo) SaveSpecies(ctx context.Context, s das.Species) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSpecies", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSpecies indicates an expected call of SaveSpecies.
+func (mr *MockSpeciesRepoMockRecorder) SaveSpecies(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSpecies", reflect.TypeOf((*MockSpeciesRepo)(nil).SaveSpecies), ctx, s)
+}
+
+// MockTx is a mock of Tx interface.
+type MockTx struct {
+	ctrl     *gomock.Controller
+	recorder *MockTxMockRecorder
+}
+
+// MockTxMockRecorder is the mock recorder for MockTx.
+type MockTxMockRecorder struct {
+	mock *MockTx
+}
+
+// NewMockTx creates a new mock instance.
+func NewMockTx(ctrl *gomock.Controller) *MockTx {
+	mock := &MockTx{ctrl: ctrl}
+	mock.recorder = &MockTxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTx) EXPECT() *MockTxMockRecorder {
+	return m.recorder
+}
+
+// AddStatusTransition mocks base method.
+func (m *MockTx) AddStatusTransition(ctx context.Context, st das.StatusTransition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddStatusTransition", ctx, st)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddStatusTransition indicates an expected call of AddStatusTransition.
+func (mr *MockTxMockRecorder) AddStatusTransition(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddStatusTransition", reflect.TypeOf((*MockTx)(nil).AddStatusTransition), ctx, st)
+}
+
+// CheckEmpty mocks base method.
+func (m *MockTx) CheckEmpty(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckEmpty", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckEmpty indicates an expected call of CheckEmpty.
+func (mr *MockTxMockRecorder) CheckEmpty(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckEmpty", reflect.TypeOf((*MockTx)(nil).CheckEmpty), ctx)
+}
+
+// FindCages mocks base method.
+func (m *MockTx) FindCages(ctx context.Context, status string, kind string) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCages", ctx, status, kind)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCages indicates an expected call of FindCages.
+func (mr *MockTxMockRecorder) FindCages(ctx, status, kind interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCages", reflect.TypeOf((*MockTx)(nil).FindCages), ctx, status, kind)
+}
+
+// GetCage mocks base method.
+func (m *MockTx) GetCage(ctx context.Context, cageID int) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCage", ctx, cageID)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCage indicates an expected call of GetCage.
+func (mr *MockTxMockRecorder) GetCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCage", reflect.TypeOf((*MockTx)(nil).GetCage), ctx, cageID)
+}
+
+// GetCageStatusHistory mocks base method.
+func (m *MockTx) GetCageStatusHistory(ctx context.Context, cageID int) ([]das.StatusTransition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCageStatusHistory", ctx, cageID)
+	ret0, _ := ret[0].([]das.StatusTransition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCageStatusHistory indicates an expected call of GetCageStatusHistory.
+func (mr *MockTxMockRecorder) GetCageStatusHistory(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCageStatusHistory", reflect.TypeOf((*MockTx)(nil).GetCageStatusHistory), ctx, cageID)
+}
+
+// GetCages mocks base method.
+func (m *MockTx) GetCages(ctx context.Context, optStatus ...string) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range optStatus {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetCages", varargs...)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCages indicates an expected call of GetCages.
+func (mr *MockTxMockRecorder) GetCages(ctx interface{}, optStatus ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, optStatus...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCages", reflect.TypeOf((*MockTx)(nil).GetCages), varargs...)
+}
+
+// GetCagesByID mocks base method.
+func (m *MockTx) GetCagesByID(ctx context.Context, cageIDs []int) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCagesByID", ctx, cageIDs)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCagesByID indicates an expected call of GetCagesByID.
+func (mr *MockTxMockRecorder) GetCagesByID(ctx, cageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCagesByID", reflect.TypeOf((*MockTx)(nil).GetCagesByID), ctx, cageIDs)
+}
+
+// GetDinosaur mocks base method.
+func (m *MockTx) GetDinosaur(ctx context.Context, dinoID int) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaur", ctx, dinoID)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaur indicates an expected call of GetDinosaur.
+func (mr *MockTxMockRecorder) GetDinosaur(ctx, dinoID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaur", reflect.TypeOf((*MockTx)(nil).GetDinosaur), ctx, dinoID)
+}
+
+// GetDinosaurs mocks base method.
+func (m *MockTx) GetDinosaurs(ctx context.Context, opts ...string) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetDinosaurs", varargs...)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaurs indicates an expected call of GetDinosaurs.
+func (mr *MockTxMockRecorder) GetDinosaurs(ctx interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaurs", reflect.TypeOf((*MockTx)(nil).GetDinosaurs), varargs...)
+}
+
+// GetDinosaursForCage mocks base method.
+func (m *MockTx) GetDinosaursForCage(ctx context.Context, cageID int) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForCage", ctx, cageID)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForCage indicates an expected call of GetDinosaursForCage.
+func (mr *MockTxMockRecorder) GetDinosaursForCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForCage", reflect.TypeOf((*MockTx)(nil).GetDinosaursForCage), ctx, cageID)
+}
+
+// GetDinosaursForCages mocks base method.
+func (m *MockTx) GetDinosaursForCages(ctx context.Context, cageIDs []int) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForCages", ctx, cageIDs)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForCages indicates an expected call of GetDinosaursForCages.
+func (mr *MockTxMockRecorder) GetDinosaursForCages(ctx, cageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForCages", reflect.TypeOf((*MockTx)(nil).GetDinosaursForCages), ctx, cageIDs)
+}
+
+// GetDinosaursForSpecies mocks base method.
+func (m *MockTx) GetDinosaursForSpecies(ctx context.Context, species []string) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForSpecies", ctx, species)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForSpecies indicates an expected call of GetDinosaursForSpecies.
+func (mr *MockTxMockRecorder) GetDinosaursForSpecies(ctx, species interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForSpecies", reflect.TypeOf((*MockTx)(nil).GetDinosaursForSpecies), ctx, species)
+}
+
+// GetSpecies mocks base method.
+func (m *MockTx) GetSpecies(ctx context.Context, name string) (das.Species, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpecies", ctx, name)
+	ret0, _ := ret[0].(das.Species)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpecies indicates an expected call of GetSpecies.
+func (mr *MockTxMockRecorder) GetSpecies(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpecies", reflect.TypeOf((*MockTx)(nil).GetSpecies), ctx, name)
+}
+
+// InsertCage mocks base method.
+func (m *MockTx) InsertCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertCage", ctx, cage)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertCage indicates an expected call of InsertCage.
+func (mr *MockTxMockRecorder) InsertCage(ctx, cage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCage", reflect.TypeOf((*MockTx)(nil).InsertCage), ctx, cage)
+}
+
+// InsertDinosaur mocks base method.
+func (m *MockTx) InsertDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDinosaur", ctx, d)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertDinosaur indicates an expected call of InsertDinosaur.
+func (mr *MockTxMockRecorder) InsertDinosaur(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDinosaur", reflect.TypeOf((*MockTx)(nil).InsertDinosaur), ctx, d)
+}
+
+// ListSpecies mocks base method.
+func (m *MockTx) ListSpecies(ctx context.Context) ([]das.Species, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSpecies", ctx)
+	ret0, _ := ret[0].([]das.Species)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSpecies indicates an expected call of ListSpecies.
+func (mr *MockTxMockRecorder) ListSpecies(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpecies", reflect.TypeOf((*MockTx)(nil).ListSpecies), ctx)
+}
+
+// LoadSnapshot mocks base method.
+func (m *MockTx) LoadSnapshot(ctx context.Context, snap das.Snapshot) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadSnapshot", ctx, snap)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LoadSnapshot indicates an expected call of LoadSnapshot.
+func (mr *MockTxMockRecorder) LoadSnapshot(ctx, snap interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadSnapshot", reflect.TypeOf((*MockTx)(nil).LoadSnapshot), ctx, snap)
+}
+
+// RecordEvent mocks base method.
+func (m *MockTx) RecordEvent(ctx context.Context, kind string, cage int, data any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordEvent", ctx, kind, cage, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordEvent indicates an expected call of RecordEvent.
+func (mr *MockTxMockRecorder) RecordEvent(ctx, kind, cage, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordEvent", reflect.TypeOf((*MockTx)(nil).RecordEvent), ctx, kind, cage, data)
+}
+
+// RemoveCage mocks base method.
+func (m *MockTx) RemoveCage(ctx context.Context, cageID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCage", ctx, cageID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCage indicates an expected call of RemoveCage.
+func (mr *MockTxMockRecorder) RemoveCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCage", reflect.TypeOf((*MockTx)(nil).RemoveCage), ctx, cageID)
+}
+
+// RemoveDinosaur mocks base method.
+func (m *MockTx) RemoveDinosaur(ctx context.Context, dinoID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveDinosaur", ctx, dinoID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveDinosaur indicates an expected call of RemoveDinosaur.
+func (mr *MockTxMockRecorder) RemoveDinosaur(ctx, dinoID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveDinosaur", reflect.TypeOf((*MockTx)(nil).RemoveDinosaur), ctx, dinoID)
+}
+
+// RemoveSpecies mocks base method.
+func (m *MockTx) RemoveSpecies(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveSpecies", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveSpecies indicates an expected call of RemoveSpecies.
+func (mr *MockTxMockRecorder) RemoveSpecies(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSpecies", reflect.TypeOf((*MockTx)(nil).RemoveSpecies), ctx, name)
+}
+
+// SaveCage mocks base method.
+func (m *MockTx) SaveCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCage", ctx, cage)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveCage indicates an expected call of SaveCage.
+func (mr *MockTxMockRecorder) SaveCage(ctx, cage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCage", reflect.TypeOf((*MockTx)(nil).SaveCage), ctx, cage)
+}
+
+// SaveDinosaur mocks base method.
+func (m *MockTx) SaveDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDinosaur", ctx, d)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDinosaur indicates an expected call of SaveDinosaur.
+func (mr *MockTxMockRecorder) SaveDinosaur(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDinosaur", reflect.TypeOf((*MockTx)(nil).SaveDinosaur), ctx, d)
+}
+
+// SaveSpecies mocks base method.
+func (m *MockTx) SaveSpecies(ctx context.Context, s das.Species) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSpecies", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSpecies indicates an expected call of SaveSpecies.
+func (mr *MockTxMockRecorder) SaveSpecies(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSpecies", reflect.TypeOf((*MockTx)(nil).SaveSpecies), ctx, s)
+}
+
+// Savepoint mocks base method.
+func (m *MockTx) Savepoint(ctx context.Context, fn func() error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Savepoint", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Savepoint indicates an expected call of Savepoint.
+func (mr *MockTxMockRecorder) Savepoint(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Savepoint", reflect.TypeOf((*MockTx)(nil).Savepoint), ctx, fn)
+}
+
+// MockUnitOfWork is a mock of UnitOfWork interface.
+type MockUnitOfWork struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnitOfWorkMockRecorder
+}
+
+// MockUnitOfWorkMockRecorder is the mock recorder for MockUnitOfWork.
+type MockUnitOfWorkMockRecorder struct {
+	mock *MockUnitOfWork
+}
+
+// NewMockUnitOfWork creates a new mock instance.
+func NewMockUnitOfWork(ctrl *gomock.Controller) *MockUnitOfWork {
+	mock := &MockUnitOfWork{ctrl: ctrl}
+	mock.recorder = &MockUnitOfWorkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnitOfWork) EXPECT() *MockUnitOfWorkMockRecorder {
+	return m.recorder
+}
+
+// Atomic mocks base method.
+func (m *MockUnitOfWork) Atomic(ctx context.Context, fn func(tx das.Tx) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Atomic", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Atomic indicates an expected call of Atomic.
+func (mr *MockUnitOfWorkMockRecorder) Atomic(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Atomic", reflect.TypeOf((*MockUnitOfWork)(nil).Atomic), ctx, fn)
+}
+
+// MockBulkStore is a mock of BulkStore interface.
+type MockBulkStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBulkStoreMockRecorder
+}
+
+// MockBulkStoreMockRecorder is the mock recorder for MockBulkStore.
+type MockBulkStoreMockRecorder struct {
+	mock *MockBulkStore
+}
+
+// NewMockBulkStore creates a new mock instance.
+func NewMockBulkStore(ctrl *gomock.Controller) *MockBulkStore {
+	mock := &MockBulkStore{ctrl: ctrl}
+	mock.recorder = &MockBulkStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBulkStore) EXPECT() *MockBulkStoreMockRecorder {
+	return m.recorder
+}
+
+// Snapshot mocks base method.
+func (m *MockBulkStore) Snapshot(ctx context.Context) (das.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx)
+	ret0, _ := ret[0].(das.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockBulkStoreMockRecorder) Snapshot(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockBulkStore)(nil).Snapshot), ctx)
+}
+
 // MockDataAccessProvider is a mock of DataAccessProvider interface.
 type MockDataAccessProvider struct {
 	ctrl     *gomock.Controller
@@ -30,23 +1133,83 @@ func NewMockDataAccessProvider(ctrl *gomock.Controller) *MockDataAccessProvider
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockDataAccessProvider) EXPECT() *MockDataAccessProviderMockRecorder {
-	return m.recorder
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataAccessProvider) EXPECT() *MockDataAccessProviderMockRecorder {
+	return m.recorder
+}
+
+// AddDelivery mocks base method.
+func (m *MockDataAccessProvider) AddDelivery(ctx context.Context, d das.Delivery) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDelivery", ctx, d)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDelivery indicates an expected call of AddDelivery.
+func (mr *MockDataAccessProviderMockRecorder) AddDelivery(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDelivery", reflect.TypeOf((*MockDataAccessProvider)(nil).AddDelivery), ctx, d)
+}
+
+// AddStatusTransition mocks base method.
+func (m *MockDataAccessProvider) AddStatusTransition(ctx context.Context, st das.StatusTransition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddStatusTransition", ctx, st)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddStatusTransition indicates an expected call of AddStatusTransition.
+func (mr *MockDataAccessProviderMockRecorder) AddStatusTransition(ctx, st interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddStatusTransition", reflect.TypeOf((*MockDataAccessProvider)(nil).AddStatusTransition), ctx, st)
+}
+
+// AddWebhook mocks base method.
+func (m *MockDataAccessProvider) AddWebhook(ctx context.Context, wh das.Webhook) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWebhook", ctx, wh)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddWebhook indicates an expected call of AddWebhook.
+func (mr *MockDataAccessProviderMockRecorder) AddWebhook(ctx, wh interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWebhook", reflect.TypeOf((*MockDataAccessProvider)(nil).AddWebhook), ctx, wh)
 }
 
-// AddDinosaur mocks base method.
-func (m *MockDataAccessProvider) AddDinosaur(ctx context.Context, d das.Dinosaur) error {
+// Atomic mocks base method.
+func (m *MockDataAccessProvider) Atomic(ctx context.Context, fn func(tx das.Tx) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddDinosaur", ctx, d)
+	ret := m.ctrl.Call(m, "Atomic", ctx, fn)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// AddDinosaur indicates an expected call of AddDinosaur.
-func (mr *MockDataAccessProviderMockRecorder) AddDinosaur(ctx, d interface{}) *gomock.Call {
+// Atomic indicates an expected call of Atomic.
+func (mr *MockDataAccessProviderMockRecorder) Atomic(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Atomic", reflect.TypeOf((*MockDataAccessProvider)(nil).Atomic), ctx, fn)
+}
+
+// ClaimDelivery mocks base method.
+func (m *MockDataAccessProvider) ClaimDelivery(ctx context.Context, id int, lease time.Duration) (das.Delivery, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDelivery", ctx, id, lease)
+	ret0, _ := ret[0].(das.Delivery)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimDelivery indicates an expected call of ClaimDelivery.
+func (mr *MockDataAccessProviderMockRecorder) ClaimDelivery(ctx, id, lease interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDinosaur", reflect.TypeOf((*MockDataAccessProvider)(nil).AddDinosaur), ctx, d)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDelivery", reflect.TypeOf((*MockDataAccessProvider)(nil).ClaimDelivery), ctx, id, lease)
 }
 
 // Close mocks base method.
@@ -61,6 +1224,95 @@ func (mr *MockDataAccessProviderMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockDataAccessProvider)(nil).Close))
 }
 
+// CommittedEvents mocks base method.
+func (m *MockDataAccessProvider) CommittedEvents(ctx context.Context, after das.EventCursor, limit int) ([]das.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommittedEvents", ctx, after, limit)
+	ret0, _ := ret[0].([]das.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommittedEvents indicates an expected call of CommittedEvents.
+func (mr *MockDataAccessProviderMockRecorder) CommittedEvents(ctx, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommittedEvents", reflect.TypeOf((*MockDataAccessProvider)(nil).CommittedEvents), ctx, after, limit)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockDataAccessProvider) DeleteWebhook(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockDataAccessProviderMockRecorder) DeleteWebhook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockDataAccessProvider)(nil).DeleteWebhook), ctx, id)
+}
+
+// EventClock mocks base method.
+func (m *MockDataAccessProvider) EventClock(ctx context.Context) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EventClock", ctx)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EventClock indicates an expected call of EventClock.
+func (mr *MockDataAccessProviderMockRecorder) EventClock(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EventClock", reflect.TypeOf((*MockDataAccessProvider)(nil).EventClock), ctx)
+}
+
+// FindCages mocks base method.
+func (m *MockDataAccessProvider) FindCages(ctx context.Context, status string, kind string) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCages", ctx, status, kind)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCages indicates an expected call of FindCages.
+func (mr *MockDataAccessProviderMockRecorder) FindCages(ctx, status, kind interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCages", reflect.TypeOf((*MockDataAccessProvider)(nil).FindCages), ctx, status, kind)
+}
+
+// GetCage mocks base method.
+func (m *MockDataAccessProvider) GetCage(ctx context.Context, cageID int) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCage", ctx, cageID)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCage indicates an expected call of GetCage.
+func (mr *MockDataAccessProviderMockRecorder) GetCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCage", reflect.TypeOf((*MockDataAccessProvider)(nil).GetCage), ctx, cageID)
+}
+
+// GetCageStatusHistory mocks base method.
+func (m *MockDataAccessProvider) GetCageStatusHistory(ctx context.Context, cageID int) ([]das.StatusTransition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCageStatusHistory", ctx, cageID)
+	ret0, _ := ret[0].([]das.StatusTransition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCageStatusHistory indicates an expected call of GetCageStatusHistory.
+func (mr *MockDataAccessProviderMockRecorder) GetCageStatusHistory(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCageStatusHistory", reflect.TypeOf((*MockDataAccessProvider)(nil).GetCageStatusHistory), ctx, cageID)
+}
+
 // GetCages mocks base method.
 func (m *MockDataAccessProvider) GetCages(ctx context.Context, optStatus ...string) ([]das.Cage, error) {
 	m.ctrl.T.Helper()
@@ -81,6 +1333,36 @@ func (mr *MockDataAccessProviderMockRecorder) GetCages(ctx interface{}, optStatu
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCages", reflect.TypeOf((*MockDataAccessProvider)(nil).GetCages), varargs...)
 }
 
+// GetCagesByID mocks base method.
+func (m *MockDataAccessProvider) GetCagesByID(ctx context.Context, cageIDs []int) ([]das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCagesByID", ctx, cageIDs)
+	ret0, _ := ret[0].([]das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCagesByID indicates an expected call of GetCagesByID.
+func (mr *MockDataAccessProviderMockRecorder) GetCagesByID(ctx, cageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCagesByID", reflect.TypeOf((*MockDataAccessProvider)(nil).GetCagesByID), ctx, cageIDs)
+}
+
+// GetDinosaur mocks base method.
+func (m *MockDataAccessProvider) GetDinosaur(ctx context.Context, dinoID int) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaur", ctx, dinoID)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaur indicates an expected call of GetDinosaur.
+func (mr *MockDataAccessProviderMockRecorder) GetDinosaur(ctx, dinoID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaur", reflect.TypeOf((*MockDataAccessProvider)(nil).GetDinosaur), ctx, dinoID)
+}
+
 // GetDinosaurs mocks base method.
 func (m *MockDataAccessProvider) GetDinosaurs(ctx context.Context, opts ...string) ([]das.Dinosaur, error) {
 	m.ctrl.T.Helper()
@@ -116,45 +1398,377 @@ func (mr *MockDataAccessProviderMockRecorder) GetDinosaursForCage(ctx, cageID in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForCage", reflect.TypeOf((*MockDataAccessProvider)(nil).GetDinosaursForCage), ctx, cageID)
 }
 
-// NewCage mocks base method.
-func (m *MockDataAccessProvider) NewCage(ctx context.Context, cap int, kind string) (int, error) {
+// GetDinosaursForCages mocks base method.
+func (m *MockDataAccessProvider) GetDinosaursForCages(ctx context.Context, cageIDs []int) ([]das.Dinosaur, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewCage", ctx, cap, kind)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "GetDinosaursForCages", ctx, cageIDs)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForCages indicates an expected call of GetDinosaursForCages.
+func (mr *MockDataAccessProviderMockRecorder) GetDinosaursForCages(ctx, cageIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForCages", reflect.TypeOf((*MockDataAccessProvider)(nil).GetDinosaursForCages), ctx, cageIDs)
+}
+
+// GetDinosaursForSpecies mocks base method.
+func (m *MockDataAccessProvider) GetDinosaursForSpecies(ctx context.Context, species []string) ([]das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDinosaursForSpecies", ctx, species)
+	ret0, _ := ret[0].([]das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDinosaursForSpecies indicates an expected call of GetDinosaursForSpecies.
+func (mr *MockDataAccessProviderMockRecorder) GetDinosaursForSpecies(ctx, species interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDinosaursForSpecies", reflect.TypeOf((*MockDataAccessProvider)(nil).GetDinosaursForSpecies), ctx, species)
+}
+
+// GetSpecies mocks base method.
+func (m *MockDataAccessProvider) GetSpecies(ctx context.Context, name string) (das.Species, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpecies", ctx, name)
+	ret0, _ := ret[0].(das.Species)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpecies indicates an expected call of GetSpecies.
+func (mr *MockDataAccessProviderMockRecorder) GetSpecies(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpecies", reflect.TypeOf((*MockDataAccessProvider)(nil).GetSpecies), ctx, name)
+}
+
+// InsertCage mocks base method.
+func (m *MockDataAccessProvider) InsertCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertCage", ctx, cage)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertCage indicates an expected call of InsertCage.
+func (mr *MockDataAccessProviderMockRecorder) InsertCage(ctx, cage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCage", reflect.TypeOf((*MockDataAccessProvider)(nil).InsertCage), ctx, cage)
+}
+
+// InsertDinosaur mocks base method.
+func (m *MockDataAccessProvider) InsertDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDinosaur", ctx, d)
+	ret0, _ := ret[0].(das.Dinosaur)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertDinosaur indicates an expected call of InsertDinosaur.
+func (mr *MockDataAccessProviderMockRecorder) InsertDinosaur(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDinosaur", reflect.TypeOf((*MockDataAccessProvider)(nil).InsertDinosaur), ctx, d)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockDataAccessProvider) ListDeliveries(ctx context.Context, webhookID int, status string) ([]das.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", ctx, webhookID, status)
+	ret0, _ := ret[0].([]das.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockDataAccessProviderMockRecorder) ListDeliveries(ctx, webhookID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockDataAccessProvider)(nil).ListDeliveries), ctx, webhookID, status)
+}
+
+// ListSpecies mocks base method.
+func (m *MockDataAccessProvider) ListSpecies(ctx context.Context) ([]das.Species, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSpecies", ctx)
+	ret0, _ := ret[0].([]das.Species)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSpecies indicates an expected call of ListSpecies.
+func (mr *MockDataAccessProviderMockRecorder) ListSpecies(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpecies", reflect.TypeOf((*MockDataAccessProvider)(nil).ListSpecies), ctx)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockDataAccessProvider) ListWebhooks(ctx context.Context) ([]das.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx)
+	ret0, _ := ret[0].([]das.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockDataAccessProviderMockRecorder) ListWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockDataAccessProvider)(nil).ListWebhooks), ctx)
+}
+
+// MarkDispatched mocks base method.
+func (m *MockDataAccessProvider) MarkDispatched(ctx context.Context, id uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDispatched", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDispatched indicates an expected call of MarkDispatched.
+func (mr *MockDataAccessProviderMockRecorder) MarkDispatched(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDispatched", reflect.TypeOf((*MockDataAccessProvider)(nil).MarkDispatched), ctx, id)
+}
+
+// PendingEvents mocks base method.
+func (m *MockDataAccessProvider) PendingEvents(ctx context.Context, limit int) ([]das.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingEvents", ctx, limit)
+	ret0, _ := ret[0].([]das.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingEvents indicates an expected call of PendingEvents.
+func (mr *MockDataAccessProviderMockRecorder) PendingEvents(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingEvents", reflect.TypeOf((*MockDataAccessProvider)(nil).PendingEvents), ctx, limit)
+}
+
+// PruneDispatched mocks base method.
+func (m *MockDataAccessProvider) PruneDispatched(ctx context.Context, age time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneDispatched", ctx, age)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneDispatched indicates an expected call of PruneDispatched.
+func (mr *MockDataAccessProviderMockRecorder) PruneDispatched(ctx, age interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneDispatched", reflect.TypeOf((*MockDataAccessProvider)(nil).PruneDispatched), ctx, age)
+}
+
+// RecordEvent mocks base method.
+func (m *MockDataAccessProvider) RecordEvent(ctx context.Context, kind string, cage int, data any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordEvent", ctx, kind, cage, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordEvent indicates an expected call of RecordEvent.
+func (mr *MockDataAccessProviderMockRecorder) RecordEvent(ctx, kind, cage, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordEvent", reflect.TypeOf((*MockDataAccessProvider)(nil).RecordEvent), ctx, kind, cage, data)
+}
+
+// RemoveCage mocks base method.
+func (m *MockDataAccessProvider) RemoveCage(ctx context.Context, cageID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCage", ctx, cageID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCage indicates an expected call of RemoveCage.
+func (mr *MockDataAccessProviderMockRecorder) RemoveCage(ctx, cageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCage", reflect.TypeOf((*MockDataAccessProvider)(nil).RemoveCage), ctx, cageID)
+}
+
+// RemoveDinosaur mocks base method.
+func (m *MockDataAccessProvider) RemoveDinosaur(ctx context.Context, dinoID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveDinosaur", ctx, dinoID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveDinosaur indicates an expected call of RemoveDinosaur.
+func (mr *MockDataAccessProviderMockRecorder) RemoveDinosaur(ctx, dinoID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveDinosaur", reflect.TypeOf((*MockDataAccessProvider)(nil).RemoveDinosaur), ctx, dinoID)
+}
+
+// RemoveSpecies mocks base method.
+func (m *MockDataAccessProvider) RemoveSpecies(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveSpecies", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveSpecies indicates an expected call of RemoveSpecies.
+func (mr *MockDataAccessProviderMockRecorder) RemoveSpecies(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSpecies", reflect.TypeOf((*MockDataAccessProvider)(nil).RemoveSpecies), ctx, name)
+}
+
+// SaveCage mocks base method.
+func (m *MockDataAccessProvider) SaveCage(ctx context.Context, cage das.Cage) (das.Cage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCage", ctx, cage)
+	ret0, _ := ret[0].(das.Cage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveCage indicates an expected call of SaveCage.
+func (mr *MockDataAccessProviderMockRecorder) SaveCage(ctx, cage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCage", reflect.TypeOf((*MockDataAccessProvider)(nil).SaveCage), ctx, cage)
+}
+
+// SaveDinosaur mocks base method.
+func (m *MockDataAccessProvider) SaveDinosaur(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDinosaur", ctx, d)
+	ret0, _ := ret[0].(das.Dinosaur)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// NewCage indicates an expected call of NewCage.
-func (mr *MockDataAccessProviderMockRecorder) NewCage(ctx, cap, kind interface{}) *gomock.Call {
+// SaveDinosaur indicates an expected call of SaveDinosaur.
+func (mr *MockDataAccessProviderMockRecorder) SaveDinosaur(ctx, d interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewCage", reflect.TypeOf((*MockDataAccessProvider)(nil).NewCage), ctx, cap, kind)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDinosaur", reflect.TypeOf((*MockDataAccessProvider)(nil).SaveDinosaur), ctx, d)
 }
 
-// PlaceDinosaurInCage mocks base method.
-func (m *MockDataAccessProvider) PlaceDinosaurInCage(ctx context.Context, cageID int, d das.Dinosaur) error {
+// SaveSpecies mocks base method.
+func (m *MockDataAccessProvider) SaveSpecies(ctx context.Context, s das.Species) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PlaceDinosaurInCage", ctx, cageID, d)
+	ret := m.ctrl.Call(m, "SaveSpecies", ctx, s)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// PlaceDinosaurInCage indicates an expected call of PlaceDinosaurInCage.
-func (mr *MockDataAccessProviderMockRecorder) PlaceDinosaurInCage(ctx, cageID, d interface{}) *gomock.Call {
+// SaveSpecies indicates an expected call of SaveSpecies.
+func (mr *MockDataAccessProviderMockRecorder) SaveSpecies(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSpecies", reflect.TypeOf((*MockDataAccessProvider)(nil).SaveSpecies), ctx, s)
+}
+
+// Snapshot mocks base method.
+func (m *MockDataAccessProvider) Snapshot(ctx context.Context) (das.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx)
+	ret0, _ := ret[0].(das.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockDataAccessProviderMockRecorder) Snapshot(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceDinosaurInCage", reflect.TypeOf((*MockDataAccessProvider)(nil).PlaceDinosaurInCage), ctx, cageID, d)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockDataAccessProvider)(nil).Snapshot), ctx)
 }
 
-// SetCageStatus mocks base method.
-func (m *MockDataAccessProvider) SetCageStatus(ctx context.Context, cageID int, status string) error {
+// UpdateDelivery mocks base method.
+func (m *MockDataAccessProvider) UpdateDelivery(ctx context.Context, d das.Delivery) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetCageStatus", ctx, cageID, status)
+	ret := m.ctrl.Call(m, "UpdateDelivery", ctx, d)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetCageStatus indicates an expected call of SetCageStatus.
-func (mr *MockDataAccessProviderMockRecorder) SetCageStatus(ctx, cageID, status interface{}) *gomock.Call {
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockDataAccessProviderMockRecorder) UpdateDelivery(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockDataAccessProvider)(nil).UpdateDelivery), ctx, d)
+}
+
+// Mockqueryer is a mock of queryer interface.
+type Mockqueryer struct {
+	ctrl     *gomock.Controller
+	recorder *MockqueryerMockRecorder
+}
+
+// MockqueryerMockRecorder is the mock recorder for Mockqueryer.
+type MockqueryerMockRecorder struct {
+	mock *Mockqueryer
+}
+
+// NewMockqueryer creates a new mock instance.
+func NewMockqueryer(ctrl *gomock.Controller) *Mockqueryer {
+	mock := &Mockqueryer{ctrl: ctrl}
+	mock.recorder = &MockqueryerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockqueryer) EXPECT() *MockqueryerMockRecorder {
+	return m.recorder
+}
+
+// ExecContext mocks base method.
+func (m *Mockqueryer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecContext", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecContext indicates an expected call of ExecContext.
+func (mr *MockqueryerMockRecorder) ExecContext(ctx, query interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*Mockqueryer)(nil).ExecContext), varargs...)
+}
+
+// QueryContext mocks base method.
+func (m *Mockqueryer) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryContext", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryContext indicates an expected call of QueryContext.
+func (mr *MockqueryerMockRecorder) QueryContext(ctx, query interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*Mockqueryer)(nil).QueryContext), varargs...)
+}
+
+// QueryRowContext mocks base method.
+func (m *Mockqueryer) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockqueryerMockRecorder) QueryRowContext(ctx, query interface{}, args ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCageStatus", reflect.TypeOf((*MockDataAccessProvider)(nil).SetCageStatus), ctx, cageID, status)
+	varargs := append([]interface{}{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*Mockqueryer)(nil).QueryRowContext), varargs...)
 }
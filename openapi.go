@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"dinocage/service"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+func init() {
+	// json lines bodies are validated as opaque strings
+	openapi3filter.RegisterBodyDecoder("application/x-ndjson", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("application/jsonl", openapi3filter.FileBodyDecoder)
+}
+
+// load and check the embedded api description
+func LoadOpenAPI() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+	err = doc.Validate(context.Background())
+	return doc, err
+}
+
+// api description handler
+func (ah AppHandlers) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	WriteMsg(w, http.StatusOK, string(openAPISpec))
+}
+
+// api documentation page handler
+func (ah AppHandlers) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	WriteMsg(w, http.StatusOK, string(docsPage))
+}
+
+// validates request parameters and bodies against the api description
+type RequestValidator struct {
+	router  routers.Router
+	options *openapi3filter.Options
+}
+
+func NewRequestValidator(doc *openapi3.T) (*RequestValidator, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	options := &openapi3filter.Options{SkipSettingDefaults: true, MultiError: true}
+	options.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		return err.Reason
+	})
+	return &RequestValidator{router: router, options: options}, nil
+}
+
+// the media type to assume for a body sent without a usable content type
+// clients such as curl -d send form encoding by default
+func defaultContentType(op *openapi3.Operation, contentType string) (string, bool) {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return "", false
+	}
+	content := op.RequestBody.Value.Content
+	if len(content) != 1 {
+		return "", false
+	}
+	if len(contentType) != 0 && !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return "", false
+	}
+	for mediaType := range content {
+		return mediaType, true
+	}
+	return "", false
+}
+
+// reject requests that do not match the api description with 400
+// routes that are not described are passed through unchecked
+func (rv *RequestValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, params, err := rv.router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if mediaType, ok := defaultContentType(route.Operation, r.Header.Get("Content-Type")); ok {
+			r.Header.Set("Content-Type", mediaType)
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options:    rv.options,
+		}
+		err = openapi3filter.ValidateRequest(r.Context(), input)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, tooLarge, http.StatusBadRequest)
+			return
+		}
+		if ve := bodyFieldErrors(err); ve != nil {
+			WriteError(w, ve, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			WriteMsg(w, http.StatusBadRequest, "invalid request : "+err.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// the problems with a request body as field errors
+// nil unless every problem found is with a field of the body
+func bodyFieldErrors(err error) *service.ValidationError {
+	var all openapi3.MultiError
+	if !errors.As(err, &all) {
+		return nil
+	}
+	var ve service.ValidationError
+	for _, e := range all {
+		var re *openapi3filter.RequestError
+		if !errors.As(e, &re) || re.RequestBody == nil {
+			return nil
+		}
+		var schemaErrs openapi3.MultiError
+		if !errors.As(re.Err, &schemaErrs) {
+			schemaErrs = openapi3.MultiError{re.Err}
+		}
+		for _, se := range schemaErrs {
+			var schemaErr *openapi3.SchemaError
+			if !errors.As(se, &schemaErr) {
+				return nil
+			}
+			field := strings.Join(schemaErr.JSONPointer(), ".")
+			if _, rest, ok := strings.Cut(schemaErr.Reason, `property "`); ok && len(field) == 0 {
+				// unknown properties are reported against the object and named in the reason
+				field, _, _ = strings.Cut(rest, `"`)
+			}
+			ve.Add(field, "%s", schemaErr.Reason)
+		}
+	}
+	if len(ve.Fields) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(ve.Fields, func(a, b service.FieldError) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return &ve
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"dinocage/das"
+	"dinocage/mocks"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+// strip the regular expressions from mux path variables
+var muxVarPattern = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	doc, err := LoadOpenAPI()
+	if err != nil {
+		t.Fatalf("invalid api description %v", err)
+	}
+	r, err := NewRouter(&AppHandlers{})
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+	routed := make(map[string]bool)
+	err = r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// version prefixes only hold the routes below them
+		if route.GetHandler() == nil {
+			return nil
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		path := muxVarPattern.ReplaceAllString(tmpl, "{$1}")
+		item := doc.Paths.Value(path)
+		for _, m := range methods {
+			routed[m+" "+path] = true
+			if item == nil || item.GetOperation(m) == nil {
+				t.Errorf("route %s %s is missing from the api description", m, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to walk routes %v", err)
+	}
+	for path, item := range doc.Paths.Map() {
+		for m := range item.Operations() {
+			if !routed[m+" "+path] {
+				t.Errorf("described operation %s %s has no route", m, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	r, err := NewRouter(NewAppHandlers(mockDap, das.DefaultPlacement()))
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+
+	tests := []struct {
+		method, url, contentType, body string
+		status                         int
+		msg                            string
+	}{
+		{"POST", "/v1/cage/V/add", "", "", http.StatusBadRequest, `parameter "diet"`},
+		{"POST", "/v1/cage/C/add?cap=0", "", "", http.StatusBadRequest, `parameter "cap"`},
+		{"GET", "/v1/cages?status=OPEN", "", "", http.StatusBadRequest, `parameter "status"`},
+		{"PATCH", "/v1/cage/3", "application/json", `{"capacity":"big"}`, http.StatusBadRequest, `"field":"capacity"`},
+		{"POST", "/v1/species/add", "application/json", `{"diet":"H"}`, http.StatusBadRequest, "name"},
+		{"POST", "/v1/import", "application/xml", `<dino/>`, http.StatusBadRequest, "Content-Type"},
+		{"POST", "/v1/cage/3/evacuate?dry_run=maybe", "", "", http.StatusBadRequest, `parameter "dry_run"`},
+		// bodies sent by curl -d are treated as the only declared media type
+		{"PATCH", "/v1/cage/3", "application/x-www-form-urlencoded", `{"capacity":0}`, http.StatusBadRequest, `"field":"capacity"`},
+		// every problem with a body is listed at once
+		{"POST", "/v1/dino/add", "application/json", `{"species":"","diet":"V","cage":2}`, http.StatusBadRequest, `[{"field":"cage","message":"property \"cage\" is unsupported"},{"field":"diet"`},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		if len(tc.contentType) != 0 {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.msg) {
+			t.Errorf("%s %s expected %d %q got %d %s", tc.method, tc.url, tc.status, tc.msg, w.Code, w.Body.String())
+		}
+	}
+
+	// valid requests reach the handler
+	mockDap.EXPECT().GetCages(gomock.Any(), das.StatusActive).Return([]das.Cage{}, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/v1/cages?status=ACTIVE", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("valid request failed with %d %s", w.Code, w.Body.String())
+	}
+	tx := mockWork(ctrl, mockDap)
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(das.Cage{ID: 3, Status: das.StatusActive, Capacity: 4, Version: 1}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).Return(das.Cage{ID: 3, Capacity: 8, Version: 2}, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageResized, 3, gomock.Any()).Return(nil)
+	req := httptest.NewRequest("PATCH", "/v1/cage/3", strings.NewReader(`{"capacity":8}`))
+	req.Header.Set("If-Match", `"1"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("valid patch failed with %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	r, err := NewRouter(&AppHandlers{})
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+	for url, ct := range map[string]string{"/v1/openapi.json": "application/json", "/v1/docs": "text/html"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), ct) {
+			t.Errorf("%s returned %d %s", url, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
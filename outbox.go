@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"dinocage/das"
+)
+
+const (
+	DefaultOutboxInterval  = 250 * time.Millisecond
+	DefaultOutboxRetention = 24 * time.Hour
+	outboxBatchSize        = 100
+	outboxPruneInterval    = time.Hour
+)
+
+// writes each event to the server log
+type LogSink struct{}
+
+func (LogSink) Deliver(ctx context.Context, e Event) error {
+	log.Printf("event %d %s cage %d : %s", e.ID, e.Type, e.Cage, e.Data)
+	return nil
+}
+
+// drains the transactional outbox in order to the registered sinks
+// an event is only marked dispatched once every sink has accepted it so
+// delivery is at least once and a sink may see an event again after a restart
+// dispatched events are removed once older than the retention, zero keeps them
+type OutboxDispatcher struct {
+	store     das.OutboxStore
+	sinks     []EventSink
+	Interval  time.Duration
+	Retention time.Duration
+}
+
+func NewOutboxDispatcher(store das.OutboxStore, sinks ...EventSink) *OutboxDispatcher {
+	return &OutboxDispatcher{store: store, sinks: sinks, Interval: DefaultOutboxInterval, Retention: DefaultOutboxRetention}
+}
+
+// poll the outbox until the context is done pruning it every hour
+func (od *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(od.Interval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		err := od.Drain(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("outbox dispatch stopped : %v", err)
+		}
+		if od.Retention > 0 && time.Since(pruned) >= outboxPruneInterval {
+			pruned = time.Now()
+			num, err := od.store.PruneDispatched(ctx, od.Retention)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("outbox prune failed : %v", err)
+			}
+			if num != 0 {
+				log.Printf("pruned %d dispatched events from the outbox", num)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch all pending events stopping at the first failure
+// so that ordering is preserved
+func (od *OutboxDispatcher) Drain(ctx context.Context) error {
+	for {
+		pending, err := od.store.PendingEvents(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, oe := range pending {
+			e := Event{ID: oe.ID, Type: oe.Type, Cage: oe.Cage, Time: oe.CreatedAt, Data: oe.Payload}
+			for _, sink := range od.sinks {
+				err = sink.Deliver(ctx, e)
+				if err != nil {
+					return err
+				}
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err = od.store.MarkDispatched(ctx, oe.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if len(pending) < outboxBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"dinocage/das"
+)
+
+// in memory outbox for dispatcher tests
+type memOutbox struct {
+	mu         sync.Mutex
+	events     []das.OutboxEvent
+	dispatched map[uint64]bool
+	last       uint64
+}
+
+func (mo *memOutbox) RecordEvent(ctx context.Context, kind string, cage int, data any) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	mo.last++
+	mo.events = append(mo.events, das.OutboxEvent{ID: mo.last, Type: kind, Cage: cage, Payload: payload, Tick: mo.last})
+	return nil
+}
+
+func (mo *memOutbox) PendingEvents(ctx context.Context, limit int) ([]das.OutboxEvent, error) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	var pending []das.OutboxEvent
+	for _, e := range mo.events {
+		if !mo.dispatched[e.ID] && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (mo *memOutbox) MarkDispatched(ctx context.Context, id uint64) error {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	if mo.dispatched == nil {
+		mo.dispatched = make(map[uint64]bool)
+	}
+	mo.dispatched[id] = true
+	return nil
+}
+
+func (mo *memOutbox) PruneDispatched(ctx context.Context, age time.Duration) (int64, error) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	var kept []das.OutboxEvent
+	for _, e := range mo.events {
+		if !mo.dispatched[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	num := int64(len(mo.events) - len(kept))
+	mo.events = kept
+	return num, nil
+}
+
+func (mo *memOutbox) CommittedEvents(ctx context.Context, after das.EventCursor, limit int) ([]das.OutboxEvent, error) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	var committed []das.OutboxEvent
+	for _, e := range mo.events {
+		if e.Tick > after.Tick && len(committed) < limit {
+			committed = append(committed, e)
+		}
+	}
+	return committed, nil
+}
+
+func (mo *memOutbox) EventClock(ctx context.Context) (uint64, error) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	return mo.last, nil
+}
+
+// records the ids it receives and optionally kills the dispatcher on one of them
+type recordingSink struct {
+	ids    []uint64
+	killOn uint64
+	kill   context.CancelFunc
+}
+
+func (rs *recordingSink) Deliver(ctx context.Context, e Event) error {
+	rs.ids = append(rs.ids, e.ID)
+	if e.ID == rs.killOn && rs.kill != nil {
+		rs.kill()
+		rs.kill = nil
+	}
+	return nil
+}
+
+type failingSink struct{}
+
+func (failingSink) Deliver(ctx context.Context, e Event) error {
+	return fmt.Errorf("sink unavailable")
+}
+
+func TestOutboxDispatcherKilledMidFlight(t *testing.T) {
+	store := &memOutbox{}
+	for i := 1; i <= 5; i++ {
+		store.RecordEvent(context.Background(), das.EventDinoAdded, i, nil)
+	}
+
+	ctx, kill := context.WithCancel(context.Background())
+	sink := &recordingSink{killOn: 3, kill: kill}
+	err := NewOutboxDispatcher(store, sink).Drain(ctx)
+	if err == nil {
+		t.Fatalf("expected the killed dispatcher to stop with an error")
+	}
+
+	// a new dispatcher picks up from the event that was in flight
+	err = NewOutboxDispatcher(store, sink).Drain(context.Background())
+	if err != nil {
+		t.Fatalf("Drain failed with %v", err)
+	}
+	want := []uint64{1, 2, 3, 3, 4, 5}
+	if !reflect.DeepEqual(sink.ids, want) {
+		t.Errorf("expected deliveries %v got %v", want, sink.ids)
+	}
+	pending, _ := store.PendingEvents(context.Background(), 10)
+	if len(pending) != 0 {
+		t.Errorf("expected the outbox to be drained got %d pending", len(pending))
+	}
+}
+
+func TestOutboxDispatcherStopsOnSinkFailure(t *testing.T) {
+	store := &memOutbox{}
+	store.RecordEvent(context.Background(), das.EventCageCreated, 1, nil)
+	store.RecordEvent(context.Background(), das.EventCageCreated, 2, nil)
+
+	sink := &recordingSink{}
+	err := NewOutboxDispatcher(store, sink, failingSink{}).Drain(context.Background())
+	if err == nil {
+		t.Fatalf("expected sink failure to stop the dispatcher")
+	}
+	// ordering is kept by not moving past the failed event
+	if !reflect.DeepEqual(sink.ids, []uint64{1}) {
+		t.Errorf("expected only event 1 attempted got %v", sink.ids)
+	}
+	pending, _ := store.PendingEvents(context.Background(), 10)
+	if len(pending) != 2 {
+		t.Errorf("expected both events still pending got %d", len(pending))
+	}
+}
+
+func TestOutboxDispatcherPrunes(t *testing.T) {
+	store := &memOutbox{}
+	store.RecordEvent(context.Background(), das.EventCageCreated, 1, nil)
+	store.RecordEvent(context.Background(), das.EventCageCreated, 2, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	od := NewOutboxDispatcher(store, &recordingSink{})
+	od.Interval = time.Millisecond
+	done := make(chan struct{})
+	go func() {
+		od.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		left := len(store.events)
+		store.mu.Unlock()
+		if left == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if tick, _ := store.EventClock(context.Background()); len(store.events) != 0 || tick != 2 {
+		t.Errorf("expected both dispatched events pruned got %+v", store.events)
+	}
+}
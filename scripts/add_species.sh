@@ -1,4 +0,0 @@
-#!/bin/sh
-
-curl -X POST -d '{"name":"sauropoda", "diet":"H"}' http://localhost:8000/v1/species/add
-
@@ -0,0 +1,43 @@
+#!/bin/sh
+
+IMPORT_FILE="import.jsonl"
+IMPORT_MODE="atomic"
+
+usage_message() {
+	echo "import.sh -f <csv or jsonl file> -m <atomic|best_effort>"
+}
+
+if [[ $# -eq 0 ]]; then
+	usage_message
+	exit
+fi
+
+while [[ $# -gt 0 ]]; do
+	case $1 in
+		-f)
+			IMPORT_FILE="$2"
+			shift
+			shift
+			;;
+		-m)
+			IMPORT_MODE="$2"
+			shift
+			shift
+			;;
+		-h)
+			usage_message
+			exit
+			;;
+	esac
+done
+
+case $IMPORT_FILE in
+	*.csv)
+		CONTENT_TYPE="text/csv"
+		;;
+	*)
+		CONTENT_TYPE="application/x-ndjson"
+		;;
+esac
+
+curl -X POST -H "Content-Type: ${CONTENT_TYPE}" --data-binary @${IMPORT_FILE} "http://localhost:8000/v1/import?mode=${IMPORT_MODE}" | jq
@@ -1,4 +0,0 @@
-#!/bin/sh
-
-curl -vv http://localhost:8000/v1/cages | jq
-
@@ -1,4 +0,0 @@
-#!/bin/sh
-
-curl http://localhost:8000/v1/species/list | jq
-
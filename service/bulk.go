@@ -0,0 +1,682 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"dinocage/das"
+)
+
+var ErrImportRejected = errors.New("import rejected")
+
+// the room left in a cage while planning moves
+type room struct {
+	id   int
+	free int
+	held map[string]int
+}
+
+func newRoom(id, free int) *room {
+	return &room{id: id, free: free, held: make(map[string]int)}
+}
+
+// rooms for cages counting the dinosaurs of each species they hold
+// free is the space left below limit or below capacity if limit is nil
+func newRooms(cages []das.Cage, dinos []das.Dinosaur, limit func(das.Cage) int) []*room {
+	rooms := make([]*room, len(cages))
+	byID := make(map[int]*room)
+	for i, c := range cages {
+		top := c.Capacity
+		if limit != nil {
+			top = limit(c)
+		}
+		rooms[i] = newRoom(c.ID, top-c.Count)
+		byID[c.ID] = rooms[i]
+	}
+	for _, d := range dinos {
+		if r, ok := byID[int(d.Cage)]; ok {
+			r.held[d.Species]++
+		}
+	}
+	return rooms
+}
+
+// check a room has space for a dinosaur and holds fewer of its species than
+// limits allows, a species without a limit may fill the room
+func (r *room) admits(d das.Dinosaur, limits map[string]int) bool {
+	if r.free <= 0 {
+		return false
+	}
+	max := limits[d.Species]
+	return max == 0 || r.held[d.Species] < max
+}
+
+func (r *room) take(d das.Dinosaur) {
+	r.free--
+	r.held[d.Species]++
+}
+
+func (r *room) give(d das.Dinosaur) {
+	r.free++
+	r.held[d.Species]--
+}
+
+// the first room other than skip admitting a dinosaur or nil
+func firstAdmitting(rooms []*room, skip *room, d das.Dinosaur, limits map[string]int) *room {
+	for _, r := range rooms {
+		if r != skip && r.admits(d, limits) {
+			return r
+		}
+	}
+	return nil
+}
+
+// the most of each species of dinos a cage may hold, 0 for no limit
+func speciesLimitsTx(ctx context.Context, tx das.Tx, dinos []das.Dinosaur) (map[string]int, error) {
+	species, err := tx.ListSpecies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]int)
+	for _, s := range species {
+		known[s.Name] = s.MaxPerCage
+	}
+	limits := make(map[string]int)
+	for _, d := range dinos {
+		limits[d.Species] = known[d.Species]
+	}
+	return limits, nil
+}
+
+// lock the cages of every diet admitting placements in id order returning
+// them with the dinosaurs they hold
+func activeCagesTx(ctx context.Context, tx das.Tx, kinds ...string) ([]das.Cage, []das.Dinosaur, error) {
+	var cages []das.Cage
+	for _, kind := range kinds {
+		found, err := tx.FindCages(ctx, das.StatusActive, kind)
+		if err != nil {
+			return nil, nil, err
+		}
+		cages = append(cages, found...)
+	}
+	if len(cages) == 0 {
+		return nil, nil, nil
+	}
+	ids := make([]int, len(cages))
+	for i, c := range cages {
+		ids[i] = c.ID
+	}
+	dinos, err := tx.GetDinosaursForCages(ctx, ids)
+	return cages, dinos, err
+}
+
+// evacuation
+
+// assign dinosaurs to the rooms of target cages in order
+// a cage never takes more of a species than limits allows
+// if allowed new cages of newCap are planned once targets are full and are
+// given negative placeholder ids -1, -2, ... for the caller to replace
+func planEvacuation(from int, dinos []das.Dinosaur, rooms []*room, limits map[string]int, allowCreate bool, newCap int) ([]das.Relocation, int, error) {
+	var moves []das.Relocation
+	newCages := 0
+	for _, d := range dinos {
+		r := firstAdmitting(rooms, nil, d, limits)
+		if r == nil && allowCreate && newCap > 0 {
+			newCages++
+			r = newRoom(-newCages, newCap)
+			rooms = append(rooms, r)
+		}
+		if r == nil {
+			return nil, 0, fmt.Errorf("%d dinosaurs cannot be moved from cage %d : %w", len(dinos)-len(moves), from, das.ErrNoCapacity)
+		}
+		r.take(d)
+		moves = append(moves, das.Relocation{Dinosaur: d.ID, Name: d.Name, Species: d.Species, From: from, To: r.id, NewCage: r.id < 0})
+	}
+	return moves, newCages, nil
+}
+
+// relocate every dinosaur out of a cage into active cages of its diet
+// the plan is applied in one unit of work unless it is a dry run
+func (ps *ParkService) EvacuateCage(ctx context.Context, cageID int, opts das.EvacuateOptions) (das.EvacuationPlan, error) {
+	plan := das.EvacuationPlan{Cage: cageID, DryRun: opts.DryRun}
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		from, err := tx.GetCage(ctx, cageID)
+		if err != nil {
+			return err
+		}
+		dinos, err := tx.GetDinosaursForCage(ctx, cageID)
+		if err != nil {
+			return err
+		}
+		active, held, err := activeCagesTx(ctx, tx, from.Kind)
+		if err != nil {
+			return err
+		}
+		cages := map[int]das.Cage{cageID: from}
+		var targets []das.Cage
+		for _, c := range active {
+			if c.ID != cageID {
+				cages[c.ID] = c
+				targets = append(targets, c)
+			}
+		}
+		limits, err := speciesLimitsTx(ctx, tx, dinos)
+		if err != nil {
+			return err
+		}
+		rooms := newRooms(targets, held, nil)
+		plan.Relocations, plan.NewCages, err = planEvacuation(cageID, dinos, rooms, limits, opts.CreateCages, das.CageCapacity)
+		if err != nil || opts.DryRun {
+			return err
+		}
+
+		// create any new cages and swap placeholder ids for real ones
+		created := make(map[int]int)
+		for i := 1; i <= plan.NewCages; i++ {
+			cage, err := createCageTx(ctx, tx, das.Cage{Status: das.StatusActive, Capacity: das.CageCapacity, Kind: from.Kind})
+			if err != nil {
+				return err
+			}
+			created[-i] = cage.ID
+			cages[cage.ID] = cage
+		}
+		byID := make(map[uint]das.Dinosaur)
+		for _, d := range dinos {
+			byID[d.ID] = d
+		}
+		for i := range plan.Relocations {
+			mv := &plan.Relocations[i]
+			if mv.NewCage {
+				mv.To = created[mv.To]
+			}
+			if _, err := moveTx(ctx, tx, cages, byID[mv.Dinosaur], *mv); err != nil {
+				return err
+			}
+		}
+		if !opts.PowerDown {
+			return nil
+		}
+		_, err = setCageStatusTx(ctx, tx, cages[cageID], das.StatusChange{Status: das.StatusDown, Reason: opts.Reason, Actor: opts.Actor})
+		return err
+	})
+	if err == nil && !opts.DryRun {
+		// only reported once the status change has committed
+		plan.PoweredDown = opts.PowerDown
+	}
+	return plan, err
+}
+
+// rebalance
+
+// compute the transfers required to rebalance active cages
+// dinosaurs only ever move between active cages of the same diet and a cage
+// never takes more of a species than limits allows
+func planRebalance(cages []das.Cage, dinos []das.Dinosaur, limits map[string]int, opts das.RebalanceOptions) ([]das.Relocation, []int, error) {
+	if !das.ValidRebalanceMode(opts.Mode) {
+		return nil, nil, invalid("unknown rebalance mode %q", opts.Mode)
+	}
+	if opts.Mode == das.RebalanceSpread && (opts.Target <= 0 || opts.Target > 1) {
+		return nil, nil, invalid("rebalance target %v must be > 0 and <= 1", opts.Target)
+	}
+	byCage := make(map[int][]das.Dinosaur)
+	for _, d := range dinos {
+		byCage[int(d.Cage)] = append(byCage[int(d.Cage)], d)
+	}
+	byKind := make(map[string][]das.Cage)
+	var kinds []string
+	for _, c := range cages {
+		if !das.AdmitsPlacement(c.Status) {
+			continue
+		}
+		if _, ok := byKind[c.Kind]; !ok {
+			kinds = append(kinds, c.Kind)
+		}
+		byKind[c.Kind] = append(byKind[c.Kind], c)
+	}
+	sort.Strings(kinds)
+
+	var moves []das.Relocation
+	var emptied []int
+	for _, kind := range kinds {
+		var m []das.Relocation
+		var e []int
+		if opts.Mode == das.RebalanceConsolidate {
+			m, e = planConsolidate(byKind[kind], byCage, limits)
+		} else {
+			m = planSpread(byKind[kind], byCage, limits, opts.Target)
+		}
+		moves = append(moves, m...)
+		emptied = append(emptied, e...)
+	}
+	return moves, emptied, nil
+}
+
+// pack dinosaurs into as few cages as possible
+// the fullest cages are kept so the fewest dinosaurs have to move and a cage
+// is only reported emptied if every dinosaur in it found a place
+func planConsolidate(cages []das.Cage, byCage map[int][]das.Dinosaur, limits map[string]int) ([]das.Relocation, []int) {
+	sorted := append([]das.Cage(nil), cages...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		if sorted[i].Capacity != sorted[j].Capacity {
+			return sorted[i].Capacity > sorted[j].Capacity
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	total := 0
+	for _, c := range sorted {
+		total += c.Count
+	}
+	keep := 0
+	space := 0
+	for keep < len(sorted) && space < total {
+		space += sorted[keep].Capacity
+		keep++
+	}
+	var held []das.Dinosaur
+	for _, c := range sorted[:keep] {
+		held = append(held, byCage[c.ID]...)
+	}
+	rooms := newRooms(sorted[:keep], held, nil)
+
+	var moves []das.Relocation
+	var emptied []int
+	for _, c := range sorted[keep:] {
+		if c.Count == 0 {
+			continue
+		}
+		left := len(byCage[c.ID])
+		for _, d := range byCage[c.ID] {
+			r := firstAdmitting(rooms, nil, d, limits)
+			if r == nil {
+				continue
+			}
+			r.take(d)
+			left--
+			moves = append(moves, das.Relocation{Dinosaur: d.ID, Name: d.Name, Species: d.Species, From: c.ID, To: r.id})
+		}
+		if left == 0 {
+			emptied = append(emptied, c.ID)
+		}
+	}
+	return moves, emptied
+}
+
+// move dinosaurs out of cages filled beyond the target into cages below it
+func planSpread(cages []das.Cage, byCage map[int][]das.Dinosaur, limits map[string]int, target float64) []das.Relocation {
+	sorted := append([]das.Cage(nil), cages...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	var held []das.Dinosaur
+	for _, c := range sorted {
+		held = append(held, byCage[c.ID]...)
+	}
+	rooms := newRooms(sorted, held, func(c das.Cage) int {
+		return int(math.Ceil(float64(c.Capacity) * target))
+	})
+
+	var moves []das.Relocation
+	for i, c := range sorted {
+		dinos := byCage[c.ID]
+		// the most recently placed dinosaurs move first
+		for j := len(dinos) - 1; j >= 0 && rooms[i].free < 0; j-- {
+			d := dinos[j]
+			r := firstAdmitting(rooms, rooms[i], d, limits)
+			if r == nil {
+				continue
+			}
+			rooms[i].give(d)
+			r.take(d)
+			moves = append(moves, das.Relocation{Dinosaur: d.ID, Name: d.Name, Species: d.Species, From: c.ID, To: r.id})
+		}
+	}
+	return moves
+}
+
+// plan and unless it is a dry run apply a rebalance of all active cages
+func (ps *ParkService) RebalanceCages(ctx context.Context, opts das.RebalanceOptions) (das.RebalancePlan, error) {
+	plan := das.RebalancePlan{Mode: opts.Mode, DryRun: opts.DryRun}
+	if opts.Mode == das.RebalanceSpread {
+		plan.Target = opts.Target
+	}
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		active, dinos, err := activeCagesTx(ctx, tx, das.CarnivoreCode, das.HerbivoreCode)
+		if err != nil {
+			return err
+		}
+		limits, err := speciesLimitsTx(ctx, tx, dinos)
+		if err != nil {
+			return err
+		}
+		plan.Relocations, plan.CagesEmptied, err = planRebalance(active, dinos, limits, opts)
+		if err != nil || opts.DryRun {
+			return err
+		}
+		cages := make(map[int]das.Cage)
+		for _, c := range active {
+			cages[c.ID] = c
+		}
+		byID := make(map[uint]das.Dinosaur)
+		for _, d := range dinos {
+			byID[d.ID] = d
+		}
+		for _, mv := range plan.Relocations {
+			if _, err := moveTx(ctx, tx, cages, byID[mv.Dinosaur], mv); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return plan, err
+}
+
+// import
+
+// rolls back a validate only import once every row has been checked
+var errImportChecked = errors.New("import checked")
+
+// import species, cages and dinosaurs in a single unit of work
+// dinosaurs are placed under the same rules as AddDinosaur and PlaceDinosaur
+// and species rows apply to later rows
+// in best effort mode failed rows are skipped otherwise any failure rejects
+// the import with ErrImportRejected, rows are numbered from 1
+// a validate only import applies rows as in best effort mode but rolls
+// them all back, reporting the rows that would have been imported as ok
+func (ps *ParkService) Import(ctx context.Context, records []das.ImportRecord, opts das.ImportOptions) ([]das.ImportResult, error) {
+	if len(opts.Placement.Strategy) == 0 {
+		opts.Placement = ps.placement
+	}
+	if !das.ValidStrategy(opts.Placement.Strategy) {
+		return nil, invalid("unknown placement strategy %s", opts.Placement.Strategy)
+	}
+	results := make([]das.ImportResult, len(records))
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		failed := false
+		for i, rec := range records {
+			rec.Type = strings.ToLower(rec.Type)
+			res := &results[i]
+			res.Row = i + 1
+			res.Type = rec.Type
+			if failed {
+				res.Error = "not attempted"
+				continue
+			}
+			var rowErr error
+			row := func() error {
+				res.ID, rowErr = ps.importRecordTx(ctx, tx, rec, opts.Placement)
+				return rowErr
+			}
+			var err error
+			if opts.BestEffort || opts.ValidateOnly {
+				err = tx.Savepoint(ctx, row)
+			} else {
+				err = row()
+			}
+			if err != rowErr {
+				return err
+			}
+			if rowErr == nil {
+				res.OK = true
+				continue
+			}
+			res.ID = 0
+			res.Error = rowErr.Error()
+			failed = !opts.BestEffort && !opts.ValidateOnly
+		}
+		if opts.ValidateOnly {
+			return errImportChecked
+		}
+		if failed {
+			return ErrImportRejected
+		}
+		return nil
+	})
+	if errors.Is(err, errImportChecked) {
+		for i := range results {
+			results[i].ID = 0
+		}
+		return results, nil
+	}
+	if errors.Is(err, ErrImportRejected) {
+		for i := range results {
+			if results[i].OK {
+				results[i].OK = false
+				results[i].ID = 0
+				results[i].Error = "rolled back"
+			}
+		}
+	}
+	return results, err
+}
+
+// apply one import row returning the id of any cage or dinosaur created
+func (ps *ParkService) importRecordTx(ctx context.Context, tx das.Tx, rec das.ImportRecord, placement das.PlacementOptions) (int, error) {
+	switch rec.Type {
+	case das.ImportSpecies:
+		// keep any details the species already has
+		current, err := lookupSpecies(ctx, tx, rec.Name)
+		found := err == nil
+		if err != nil && !errors.Is(err, ErrUnknownSpecies) {
+			return 0, err
+		}
+		s := current
+		s.Name, s.Diet = rec.Name, rec.Diet
+		s, err = NormalizeSpecies(s)
+		if err != nil {
+			return 0, err
+		}
+		kind := das.EventSpeciesAdded
+		switch {
+		case found && current == s:
+			return 0, nil
+		case found:
+			kind = das.EventSpeciesUpdated
+			err = replaceSpeciesTx(ctx, tx, current, s)
+		default:
+			err = tx.SaveSpecies(ctx, s)
+		}
+		if err != nil {
+			return 0, err
+		}
+		return 0, tx.RecordEvent(ctx, kind, 0, s)
+	case das.ImportCage:
+		cage := das.Cage{Status: strings.ToUpper(rec.Status), Capacity: rec.Capacity, Kind: strings.ToUpper(rec.Kind)}
+		if len(cage.Status) == 0 {
+			cage.Status = das.StatusActive
+		}
+		var ve ValidationError
+		ve.Diet("kind", cage.Kind)
+		if cage.Capacity < 1 {
+			ve.Add("capacity", "must be > 0")
+		}
+		if !das.ValidStatus(cage.Status) {
+			ve.Add("status", "must be a cage status not %q", cage.Status)
+		}
+		if err := ve.Err(); err != nil {
+			return 0, err
+		}
+		cage, err := createCageTx(ctx, tx, cage)
+		return cage.ID, err
+	case das.ImportDinosaur:
+		d, err := newDinosaur(das.Dinosaur{Species: rec.Species, Name: rec.Name, Diet: rec.Diet})
+		if err != nil {
+			return 0, err
+		}
+		d, species, err := resolveSpeciesTx(ctx, tx, d)
+		if err != nil {
+			return 0, err
+		}
+		if rec.Cage == 0 {
+			d, err = ps.addDinosaurTx(ctx, tx, d, species, placement)
+		} else {
+			d, err = placeDinosaurTx(ctx, tx, rec.Cage, d, species)
+		}
+		return int(d.ID), err
+	default:
+		return 0, invalid("unknown record type %q", rec.Type)
+	}
+}
+
+// restore
+
+// load a snapshot into an empty park keeping the original ids
+// cage counts are recalculated from the dinosaurs in the snapshot
+// and its species are saved over any already stored
+// a park that is not empty is refused before the snapshot is checked, and
+// nothing is written unless every cage and dinosaur keeps the park rules
+// each restored row is published as if it had been added
+func (ps *ParkService) Restore(ctx context.Context, snap das.Snapshot) error {
+	return ps.work.Atomic(ctx, func(tx das.Tx) error {
+		if err := tx.CheckEmpty(ctx); err != nil {
+			return err
+		}
+		snap, err := normalizeSnapshot(snap)
+		if err != nil {
+			return err
+		}
+		if err := checkSnapshotTx(ctx, tx, snap); err != nil {
+			return err
+		}
+		if err := tx.LoadSnapshot(ctx, snap); err != nil {
+			return err
+		}
+		return recordSnapshotTx(ctx, tx, snap)
+	})
+}
+
+// a copy of a snapshot with its names, diets, kinds and statuses in the
+// stored case and the count of each cage taken from its dinosaurs
+func normalizeSnapshot(snap das.Snapshot) (das.Snapshot, error) {
+	snap.Species = slices.Clone(snap.Species)
+	for i, s := range snap.Species {
+		s, err := NormalizeSpecies(s)
+		if err != nil {
+			return snap, err
+		}
+		snap.Species[i] = s
+	}
+	snap.Cages = slices.Clone(snap.Cages)
+	for i := range snap.Cages {
+		c := &snap.Cages[i]
+		c.Kind, c.Status = strings.ToUpper(c.Kind), strings.ToUpper(c.Status)
+	}
+	snap.Dinosaurs = slices.Clone(snap.Dinosaurs)
+	counts := make(map[int]int)
+	for i := range snap.Dinosaurs {
+		d := &snap.Dinosaurs[i]
+		d.Species, d.Diet = strings.ToLower(strings.TrimSpace(d.Species)), strings.ToUpper(d.Diet)
+		counts[int(d.Cage)]++
+	}
+	for i := range snap.Cages {
+		snap.Cages[i].Count = counts[snap.Cages[i].ID]
+	}
+	return snap, nil
+}
+
+// check a snapshot holds a park the placement rules allow, reporting every
+// problem found in a ValidationError
+// species missing from the snapshot are looked up among those stored and
+// dinosaurs without a diet take the diet of their species
+func checkSnapshotTx(ctx context.Context, tx das.Tx, snap das.Snapshot) error {
+	var ve ValidationError
+	species := make(map[string]das.Species)
+	for _, s := range snap.Species {
+		species[s.Name] = s
+	}
+	cages := make(map[int]das.Cage)
+	for _, c := range snap.Cages {
+		field := fmt.Sprintf("cage %d", c.ID)
+		if _, ok := cages[c.ID]; ok || c.ID < 1 {
+			ve.Add(field, "must have a unique id > 0")
+		}
+		ve.Diet(field+" kind", c.Kind)
+		if c.Capacity < 1 {
+			ve.Add(field+" capacity", "must be > 0")
+		}
+		if !das.ValidStatus(c.Status) {
+			ve.Add(field+" status", "must be a cage status not %q", c.Status)
+		}
+		cages[c.ID] = c
+	}
+	dinos := make(map[uint]bool)
+	held := make(map[int]map[string]int)
+	for i := range snap.Dinosaurs {
+		d := &snap.Dinosaurs[i]
+		field := fmt.Sprintf("dinosaur %d", d.ID)
+		if dinos[d.ID] || d.ID == 0 {
+			ve.Add(field, "must have a unique id > 0")
+		}
+		dinos[d.ID] = true
+		ve.Name(field+" name", d.Name)
+		s, ok := species[d.Species]
+		if !ok {
+			stored, err := lookupSpecies(ctx, tx, d.Species)
+			if errors.Is(err, ErrUnknownSpecies) {
+				ve.Add(field+" species", "%q is not a known species", d.Species)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			s = stored
+			species[s.Name] = s
+		}
+		if len(d.Diet) == 0 {
+			d.Diet = s.Diet
+		}
+		if d.Diet != s.Diet {
+			ve.Add(field+" diet", "must be %s for %s not %q", s.Diet, s.Name, d.Diet)
+			continue
+		}
+		cage, ok := cages[int(d.Cage)]
+		switch {
+		case !ok:
+			ve.Add(field+" cage", "%d is not in the snapshot", d.Cage)
+			continue
+		case cage.Kind != d.Diet:
+			ve.Add(field+" cage", "%d is for diet %s not %s", cage.ID, cage.Kind, d.Diet)
+		case das.RequiresEmpty(cage.Status):
+			ve.Add(field+" cage", "%d is %s and must be empty", cage.ID, cage.Status)
+		}
+		if held[cage.ID] == nil {
+			held[cage.ID] = make(map[string]int)
+		}
+		held[cage.ID][s.Name]++
+		// each limit is reported once, by the dinosaur that breaks it
+		if held[cage.ID][s.Name] == s.MaxPerCage+1 && s.MaxPerCage != 0 {
+			ve.Add(field+" cage", "%d holds more than %d %s", cage.ID, s.MaxPerCage, s.Name)
+		}
+	}
+	for _, c := range snap.Cages {
+		if c.Count > c.Capacity && c.Capacity > 0 {
+			ve.Add(fmt.Sprintf("cage %d", c.ID), "holds %d dinosaurs over its capacity of %d", c.Count, c.Capacity)
+		}
+	}
+	return ve.Err()
+}
+
+// publish the species, cages and dinosaurs of a loaded snapshot
+func recordSnapshotTx(ctx context.Context, tx das.Tx, snap das.Snapshot) error {
+	for _, s := range snap.Species {
+		if err := tx.RecordEvent(ctx, das.EventSpeciesAdded, 0, s); err != nil {
+			return err
+		}
+	}
+	for _, c := range snap.Cages {
+		if err := tx.RecordEvent(ctx, das.EventCageCreated, c.ID, c); err != nil {
+			return err
+		}
+	}
+	for _, d := range snap.Dinosaurs {
+		if err := tx.RecordEvent(ctx, das.EventDinoAdded, int(d.Cage), d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dinocage/das"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+func TestPlanEvacuation(t *testing.T) {
+	dinos := []das.Dinosaur{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	targets := []das.Cage{
+		{ID: 7, Capacity: 2, Count: 1},
+		{ID: 9, Capacity: 3, Count: 1},
+	}
+	moves, newCages, err := planEvacuation(5, dinos, newRooms(targets, nil, nil), nil, true, 2)
+	if err != nil {
+		t.Fatalf("planEvacuation failed with %v", err)
+	}
+	want := []int{7, 9, 9, -1}
+	for i, mv := range moves {
+		if mv.To != want[i] || mv.From != 5 {
+			t.Errorf("move %d went %d -> %d expected 5 -> %d", i, mv.From, mv.To, want[i])
+		}
+	}
+	if newCages != 1 || !moves[3].NewCage {
+		t.Errorf("expected one new cage for the last dinosaur got %d", newCages)
+	}
+}
+
+func TestPlanEvacuationNoCapacity(t *testing.T) {
+	dinos := []das.Dinosaur{{ID: 1}, {ID: 2}}
+	targets := []das.Cage{{ID: 7, Capacity: 2, Count: 1}}
+	_, _, err := planEvacuation(5, dinos, newRooms(targets, nil, nil), nil, false, das.CageCapacity)
+	if !errors.Is(err, das.ErrNoCapacity) {
+		t.Errorf("expected ErrNoCapacity got %v", err)
+	}
+}
+
+func TestPlanEvacuationSpeciesLimit(t *testing.T) {
+	// cage 7 already holds a rex and takes only one
+	dinos := []das.Dinosaur{{ID: 1, Species: "rex"}, {ID: 2, Species: "raptor"}}
+	targets := []das.Cage{{ID: 7, Capacity: 4, Count: 1}, {ID: 9, Capacity: 4}}
+	held := []das.Dinosaur{{ID: 3, Species: "rex", Cage: 7}}
+	limits := map[string]int{"rex": 1}
+	moves, _, err := planEvacuation(5, dinos, newRooms(targets, held, nil), limits, false, das.CageCapacity)
+	if err != nil || moves[0].To != 9 || moves[1].To != 7 {
+		t.Errorf("expected the rex to skip cage 7 got %+v %v", moves, err)
+	}
+
+	// with no other cage the rex has nowhere to go
+	_, _, err = planEvacuation(5, dinos[:1], newRooms(targets[:1], held, nil), limits, false, das.CageCapacity)
+	if !errors.Is(err, das.ErrNoCapacity) {
+		t.Errorf("expected ErrNoCapacity got %v", err)
+	}
+}
+
+func TestPlanRebalanceConsolidate(t *testing.T) {
+	cages := []das.Cage{
+		{ID: 1, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode},
+		{ID: 2, Status: das.StatusActive, Capacity: 4, Count: 3, Kind: das.CarnivoreCode},
+		{ID: 3, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.HerbivoreCode},
+		{ID: 4, Status: das.StatusDown, Capacity: 4, Count: 0, Kind: das.CarnivoreCode},
+	}
+	dinos := []das.Dinosaur{
+		{ID: 10, Cage: 1},
+		{ID: 11, Cage: 2}, {ID: 12, Cage: 2}, {ID: 13, Cage: 2},
+		{ID: 14, Cage: 3},
+	}
+	moves, emptied, err := planRebalance(cages, dinos, nil, das.RebalanceOptions{Mode: das.RebalanceConsolidate})
+	if err != nil {
+		t.Fatalf("planRebalance failed with %v", err)
+	}
+	if len(moves) != 1 || moves[0].Dinosaur != 10 || moves[0].From != 1 || moves[0].To != 2 {
+		t.Errorf("expected dinosaur 10 to move from cage 1 to 2 got %+v", moves)
+	}
+	if len(emptied) != 1 || emptied[0] != 1 {
+		t.Errorf("expected cage 1 emptied got %v", emptied)
+	}
+
+	// a cage already holding as many of the species as allowed takes no more
+	for i := range dinos {
+		dinos[i].Species = "rex"
+	}
+	moves, emptied, err = planRebalance(cages, dinos, map[string]int{"rex": 3}, das.RebalanceOptions{Mode: das.RebalanceConsolidate})
+	if err != nil || len(moves) != 0 || len(emptied) != 0 {
+		t.Errorf("expected no moves got %+v %v %v", moves, emptied, err)
+	}
+}
+
+func TestPlanRebalanceSpread(t *testing.T) {
+	cages := []das.Cage{
+		{ID: 1, Status: das.StatusActive, Capacity: 4, Count: 4, Kind: das.HerbivoreCode},
+		{ID: 2, Status: das.StatusActive, Capacity: 4, Count: 0, Kind: das.HerbivoreCode},
+	}
+	dinos := []das.Dinosaur{{ID: 10, Cage: 1}, {ID: 11, Cage: 1}, {ID: 12, Cage: 1}, {ID: 13, Cage: 1}}
+	moves, _, err := planRebalance(cages, dinos, nil, das.RebalanceOptions{Mode: das.RebalanceSpread, Target: 0.5})
+	if err != nil {
+		t.Fatalf("planRebalance failed with %v", err)
+	}
+	if len(moves) != 2 {
+		t.Fatalf("expected 2 moves got %+v", moves)
+	}
+	for _, mv := range moves {
+		if mv.From != 1 || mv.To != 2 {
+			t.Errorf("unexpected move %+v", mv)
+		}
+	}
+
+	// only one of a species limited to one per cage may move
+	for i := range dinos {
+		dinos[i].Species = "trike"
+	}
+	dinos[0].Species = "stego"
+	moves, _, err = planRebalance(cages, dinos, map[string]int{"trike": 1}, das.RebalanceOptions{Mode: das.RebalanceSpread, Target: 0.5})
+	if err != nil || len(moves) != 2 || moves[0].Dinosaur != 13 || moves[1].Dinosaur != 10 {
+		t.Errorf("expected dinosaurs 13 and 10 to move got %+v %v", moves, err)
+	}
+}
+
+func TestPlanRebalanceBadTarget(t *testing.T) {
+	_, _, err := planRebalance(nil, nil, nil, das.RebalanceOptions{Mode: das.RebalanceSpread, Target: 1.5})
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected an invalid target got %v", err)
+	}
+}
+
+func TestImportRejected(t *testing.T) {
+	ps, _, tx := testService(t)
+	tx.EXPECT().GetSpecies(gomock.Any(), gomock.Any()).Return(das.Species{}, das.ErrSpeciesNotFound).AnyTimes()
+
+	// a failed row rolls back the rows before it and skips the rows after
+	created := das.Cage{ID: 3, Status: das.StatusActive, Capacity: 2, Kind: das.HerbivoreCode, Version: 1}
+	tx.EXPECT().InsertCage(gomock.Any(), das.Cage{Status: das.StatusActive, Capacity: 2, Kind: das.HerbivoreCode}).Return(created, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageCreated, 3, created).Return(nil)
+	records := []das.ImportRecord{
+		{Type: das.ImportCage, Kind: "h", Capacity: 2},
+		{Type: das.ImportDinosaur, Species: "dodo", Name: "dee"},
+		{Type: das.ImportCage, Kind: "C", Capacity: 2},
+	}
+	results, err := ps.Import(context.Background(), records, das.ImportOptions{})
+	if !errors.Is(err, ErrImportRejected) {
+		t.Fatalf("expected the import to be rejected got %v", err)
+	}
+	want := []string{"rolled back", "dodo : unknown species", "not attempted"}
+	for i, res := range results {
+		if res.OK || res.ID != 0 || res.Error != want[i] {
+			t.Errorf("row %d gave %+v expected %q", i+1, res, want[i])
+		}
+	}
+}
+
+// a service over an sqlite database with the given species
+func sqliteService(t *testing.T, known ...das.Species) (*ParkService, das.DataAccessProvider) {
+	schema, err := os.ReadFile(filepath.Join("..", "dataset", "init.sql"))
+	if err != nil {
+		t.Fatalf("unable to read schema %v", err)
+	}
+	dap, err := das.ConnectSQLite(filepath.Join(t.TempDir(), "dinocage.db"), string(schema))
+	if err != nil {
+		t.Fatalf("unable to open sqlite %v", err)
+	}
+	t.Cleanup(dap.Close)
+	for _, s := range known {
+		if err := dap.SaveSpecies(context.Background(), s); err != nil {
+			t.Fatalf("unable to save species %v", err)
+		}
+	}
+	return NewParkService(dap, dap, das.DefaultPlacement()), dap
+}
+
+func TestImportSpeciesEvents(t *testing.T) {
+	ctx := context.Background()
+	ps, dap := sqliteService(t,
+		das.Species{Name: "dodo", Diet: das.HerbivoreCode, Habitat: "island"},
+		das.Species{Name: "moa", Diet: das.HerbivoreCode},
+	)
+	records := []das.ImportRecord{
+		{Type: das.ImportSpecies, Name: "Dilophosaurus", Diet: "C"},
+		{Type: das.ImportSpecies, Name: "dodo", Diet: "C"},
+		{Type: das.ImportSpecies, Name: "moa", Diet: "H"},
+	}
+	if _, err := ps.Import(ctx, records, das.ImportOptions{}); err != nil {
+		t.Fatalf("import failed %v", err)
+	}
+	if _, err := ps.AddSpecies(ctx, das.Species{Name: "dilophosaurus", Diet: das.CarnivoreCode, DangerLevel: 4}); err != nil {
+		t.Fatalf("add species failed %v", err)
+	}
+
+	// only species not stored before are published as added
+	events, err := dap.CommittedEvents(ctx, das.EventCursor{}, 10)
+	if err != nil {
+		t.Fatalf("committed events failed %v", err)
+	}
+	want := []string{das.EventSpeciesAdded, das.EventSpeciesUpdated, das.EventSpeciesUpdated}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events got %+v", len(want), events)
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Errorf("event %d expected %s got %s", i+1, want[i], e.Type)
+		}
+	}
+	if dodo, _ := dap.GetSpecies(ctx, "dodo"); dodo.Diet != das.CarnivoreCode || dodo.Habitat != "island" {
+		t.Errorf("expected the imported diet with the stored details got %+v", dodo)
+	}
+}
+
+func TestBulkOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	ps, dap := sqliteService(t, das.Species{Name: "velociraptor", Diet: das.CarnivoreCode, MaxPerCage: 2})
+	records := []das.ImportRecord{
+		{Type: das.ImportCage, Kind: das.CarnivoreCode, Capacity: 4},
+		{Type: das.ImportDinosaur, Species: "velociraptor", Name: "blue"},
+		{Type: das.ImportDinosaur, Species: "velociraptor", Name: "delta", Cage: 1},
+		// the species limit applies to imports as to single placements
+		{Type: das.ImportDinosaur, Species: "velociraptor", Name: "echo", Cage: 1},
+		{Type: das.ImportDinosaur, Species: "velociraptor", Name: "charlie", Cage: 2},
+	}
+	results, err := ps.Import(ctx, records, das.ImportOptions{BestEffort: true, Placement: das.DefaultPlacement()})
+	if err != nil || !results[0].OK || !results[1].OK || !results[2].OK || results[3].OK || results[4].OK {
+		t.Fatalf("import returned %+v %v", results, err)
+	}
+
+	plan, err := ps.EvacuateCage(ctx, 1, das.EvacuateOptions{DryRun: true, CreateCages: true, PowerDown: true})
+	if err != nil || len(plan.Relocations) != 2 || plan.NewCages != 1 || plan.PoweredDown {
+		t.Fatalf("dry run evacuate returned %+v %v", plan, err)
+	}
+	// other instances see the evacuation through the event clock
+	before, err := dap.EventClock(ctx)
+	if err != nil {
+		t.Fatalf("event clock failed %v", err)
+	}
+	plan, err = ps.EvacuateCage(ctx, 1, das.EvacuateOptions{CreateCages: true, PowerDown: true, Reason: "storm"})
+	if err != nil || len(plan.Relocations) != 2 || plan.NewCages != 1 || !plan.PoweredDown {
+		t.Fatalf("evacuate returned %+v %v", plan, err)
+	}
+	if after, err := dap.EventClock(ctx); err != nil || after <= before {
+		t.Errorf("event clock went from %d to %d %v", before, after, err)
+	}
+	cage, err := dap.GetCage(ctx, 1)
+	if err != nil || cage.Status != das.StatusDown || cage.Count != 0 {
+		t.Errorf("evacuated cage is %+v %v", cage, err)
+	}
+	moved, err := dap.GetCage(ctx, plan.Relocations[0].To)
+	if err != nil || moved.Count != 2 {
+		t.Errorf("evacuated dinosaurs went to %+v %v", moved, err)
+	}
+
+	// nothing is left to consolidate into fewer cages
+	rebalance, err := ps.RebalanceCages(ctx, das.RebalanceOptions{Mode: das.RebalanceConsolidate})
+	if err != nil || len(rebalance.Relocations) != 0 {
+		t.Errorf("rebalance returned %+v %v", rebalance, err)
+	}
+}
+
+func TestRestoreChecksRules(t *testing.T) {
+	ctx := context.Background()
+	ps, dap := sqliteService(t, das.Species{Name: "triceratops", Diet: das.HerbivoreCode})
+	snap := das.Snapshot{
+		Species: []das.Species{{Name: "Velociraptor", Diet: das.CarnivoreCode, MaxPerCage: 1}},
+		Cages: []das.Cage{
+			{ID: 1, Status: das.StatusActive, Capacity: 1, Kind: das.HerbivoreCode},
+			{ID: 2, Status: das.StatusDown, Capacity: 4, Kind: das.CarnivoreCode},
+		},
+		Dinosaurs: []das.Dinosaur{
+			// a carnivore in a herbivore cage
+			{ID: 1, Species: "velociraptor", Name: "blue", Cage: 1},
+			{ID: 2, Species: "triceratops", Name: "sarah", Diet: das.CarnivoreCode, Cage: 1},
+			{ID: 3, Species: "dodo", Name: "dee", Cage: 1},
+			{ID: 4, Species: "triceratops", Name: "cera", Cage: 1},
+			{ID: 5, Species: "velociraptor", Name: "delta", Cage: 2},
+			{ID: 6, Species: "velociraptor", Name: "echo", Cage: 9},
+		},
+	}
+	err := ps.Restore(ctx, snap)
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected the snapshot to be refused got %v", err)
+	}
+	want := []string{"dinosaur 1 cage", "dinosaur 2 diet", "dinosaur 3 species", "dinosaur 5 cage", "dinosaur 6 cage", "cage 1"}
+	if len(ve.Fields) != len(want) {
+		t.Fatalf("expected problems with %v got %+v", want, ve.Fields)
+	}
+	for i, f := range ve.Fields {
+		if f.Field != want[i] {
+			t.Errorf("problem %d is with %s expected %s", i, f.Field, want[i])
+		}
+	}
+	if cages, _ := dap.GetCages(ctx); len(cages) != 0 {
+		t.Errorf("expected nothing restored got %+v", cages)
+	}
+
+	// once it keeps the rules the snapshot is loaded with the stored species
+	snap.Cages[1].Status = das.StatusActive
+	snap.Dinosaurs = []das.Dinosaur{
+		{ID: 4, Species: "triceratops", Name: "cera", Cage: 1},
+		{ID: 5, Species: "velociraptor", Name: "delta", Cage: 2},
+	}
+	if err := ps.Restore(ctx, snap); err != nil {
+		t.Fatalf("restore failed %v", err)
+	}
+	cera, err := dap.GetDinosaur(ctx, 4)
+	if err != nil || cera.Diet != das.HerbivoreCode {
+		t.Errorf("restored dinosaur is %+v %v", cera, err)
+	}
+
+	// a park that is no longer empty is refused before any snapshot is checked
+	snap.Dinosaurs[0].Diet = das.CarnivoreCode
+	if err := ps.Restore(ctx, snap); !errors.Is(err, das.ErrNotEmpty) {
+		t.Errorf("expected a restore over a park to be refused got %v", err)
+	}
+	snap.Species[0].Diet = "V"
+	if err := ps.Restore(ctx, snap); !errors.Is(err, das.ErrNotEmpty) {
+		t.Errorf("expected a restore over a park to be refused got %v", err)
+	}
+}
@@ -0,0 +1,777 @@
+// Package service holds the park rules applied between the api handlers and
+// the repositories.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"dinocage/das"
+)
+
+// errors for requests the park rules reject
+var (
+	ErrInvalid        = errors.New("invalid request")
+	ErrUnknownSpecies = errors.New("unknown species")
+	ErrDietMismatch   = errors.New("diet does not match")
+	ErrNotAdmitting   = errors.New("cage is not admitting dinosaurs")
+	ErrSpeciesInUse   = errors.New("species in use by existing dinosaurs")
+)
+
+// park rules for species, cages and dinosaurs
+// every change runs in a unit of work so the rows it reads stay locked
+// until its writes and events are committed
+type ParkService struct {
+	species   das.SpeciesRepo
+	work      das.UnitOfWork
+	placement das.PlacementOptions
+	rr        das.RoundRobin
+}
+
+// create a service placing dinosaurs with the given default options
+func NewParkService(species das.SpeciesRepo, work das.UnitOfWork, placement das.PlacementOptions) *ParkService {
+	if len(placement.Strategy) == 0 {
+		placement = das.DefaultPlacement()
+	}
+	return &ParkService{species: species, work: work, placement: placement}
+}
+
+// the default placement options
+func (ps *ParkService) Placement() das.PlacementOptions {
+	return ps.placement
+}
+
+func invalid(format string, a ...any) error {
+	return fmt.Errorf("%s : %w", fmt.Sprintf(format, a...), ErrInvalid)
+}
+
+// species
+
+// look up a species by name in any case failing with ErrUnknownSpecies
+func (ps *ParkService) Species(ctx context.Context, name string) (das.Species, error) {
+	return lookupSpecies(ctx, ps.species, name)
+}
+
+// every stored species in name order
+func (ps *ParkService) ListSpecies(ctx context.Context) ([]das.Species, error) {
+	return ps.species.ListSpecies(ctx)
+}
+
+// look up a species in any case, within a unit of work its row stays locked
+// so it cannot be removed or change its diet until the unit of work ends
+func lookupSpecies(ctx context.Context, repo das.SpeciesRepo, name string) (das.Species, error) {
+	s, err := repo.GetSpecies(ctx, strings.ToLower(strings.TrimSpace(name)))
+	if errors.Is(err, das.ErrSpeciesNotFound) {
+		return s, fmt.Errorf("%s : %w", name, ErrUnknownSpecies)
+	}
+	return s, err
+}
+
+// check the details of a species returning them in their stored form
+// species names are matched in any case so are stored in lower case
+// every problem found is reported in a ValidationError
+func NormalizeSpecies(s das.Species) (das.Species, error) {
+	s.Name = strings.ToLower(strings.TrimSpace(s.Name))
+	s.Diet = strings.ToUpper(s.Diet)
+	s.Period = strings.ToLower(strings.TrimSpace(s.Period))
+	s.Habitat = strings.ToLower(strings.TrimSpace(s.Habitat))
+	var ve ValidationError
+	ve.Name("name", s.Name)
+	ve.Diet("diet", s.Diet)
+	if len(s.Period) != 0 && !das.ValidPeriod(s.Period) {
+		ve.Add("period", "must be triassic, jurassic or cretaceous not %q", s.Period)
+	}
+	if s.WeightKg < 0 {
+		ve.Add("weight_kg", "must not be negative")
+	}
+	if s.DangerLevel < 0 || s.DangerLevel > das.MaxDangerLevel {
+		ve.Add("danger_level", "must be between 0 and %d", das.MaxDangerLevel)
+	}
+	if s.MaxPerCage < 0 {
+		ve.Add("max_per_cage", "must not be negative")
+	}
+	if len(s.Habitat) != 0 {
+		ve.Name("habitat", s.Habitat)
+	}
+	return s, ve.Err()
+}
+
+// save and publish species only when none are stored so a reference file seeds
+// an empty park without undoing later changes, returning how many were saved
+func (ps *ParkService) SeedSpecies(ctx context.Context, species []das.Species) (int, error) {
+	seeded := 0
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		stored, err := tx.ListSpecies(ctx)
+		if err != nil || len(stored) != 0 {
+			return err
+		}
+		for _, s := range species {
+			s, err := NormalizeSpecies(s)
+			if err != nil {
+				return err
+			}
+			if err := tx.SaveSpecies(ctx, s); err != nil {
+				return err
+			}
+			if err := tx.RecordEvent(ctx, das.EventSpeciesAdded, 0, s); err != nil {
+				return err
+			}
+			seeded++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return seeded, nil
+}
+
+// save a species and publish it as added or updated
+// a species already stored keeps any details the new one leaves unset
+func (ps *ParkService) AddSpecies(ctx context.Context, s das.Species) (das.Species, error) {
+	s, err := NormalizeSpecies(s)
+	if err != nil {
+		return s, err
+	}
+	saved := s
+	err = ps.work.Atomic(ctx, func(tx das.Tx) error {
+		saved = s
+		kind := das.EventSpeciesAdded
+		current, err := tx.GetSpecies(ctx, s.Name)
+		switch {
+		case errors.Is(err, das.ErrSpeciesNotFound):
+			err = tx.SaveSpecies(ctx, saved)
+		case err == nil:
+			kind = das.EventSpeciesUpdated
+			saved = keepSpeciesDetails(current, s)
+			err = replaceSpeciesTx(ctx, tx, current, saved)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, kind, 0, saved)
+	})
+	return saved, err
+}
+
+// fill the details a species leaves unset from the stored species
+func keepSpeciesDetails(current, s das.Species) das.Species {
+	if len(s.Period) == 0 {
+		s.Period = current.Period
+	}
+	if s.WeightKg == 0 {
+		s.WeightKg = current.WeightKg
+	}
+	if s.DangerLevel == 0 {
+		s.DangerLevel = current.DangerLevel
+	}
+	if s.MaxPerCage == 0 {
+		s.MaxPerCage = current.MaxPerCage
+	}
+	if len(s.Habitat) == 0 {
+		s.Habitat = current.Habitat
+	}
+	return s
+}
+
+// fail if any dinosaurs of the species are in the park
+func speciesUnusedTx(ctx context.Context, tx das.Tx, name string) error {
+	dinos, err := tx.GetDinosaursForSpecies(ctx, []string{name})
+	if err != nil {
+		return err
+	}
+	if len(dinos) != 0 {
+		return fmt.Errorf("%d %s in the park : %w", len(dinos), name, ErrSpeciesInUse)
+	}
+	return nil
+}
+
+// save new details over a locked species
+// the diet may only change while there are no dinosaurs of the species
+func replaceSpeciesTx(ctx context.Context, tx das.Tx, current, s das.Species) error {
+	if current.Diet != s.Diet {
+		if err := speciesUnusedTx(ctx, tx, s.Name); err != nil {
+			return err
+		}
+	}
+	return tx.SaveSpecies(ctx, s)
+}
+
+// replace the details of a stored species and publish the change
+// a lower max per cage applies to later placements only
+func (ps *ParkService) UpdateSpecies(ctx context.Context, s das.Species) (das.Species, error) {
+	s, err := NormalizeSpecies(s)
+	if err != nil {
+		return s, err
+	}
+	err = ps.work.Atomic(ctx, func(tx das.Tx) error {
+		current, err := lookupSpecies(ctx, tx, s.Name)
+		if err != nil {
+			return err
+		}
+		if err := replaceSpeciesTx(ctx, tx, current, s); err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, das.EventSpeciesUpdated, 0, s)
+	})
+	return s, err
+}
+
+// remove a species without dinosaurs and publish the removal
+func (ps *ParkService) DeleteSpecies(ctx context.Context, name string) error {
+	return ps.work.Atomic(ctx, func(tx das.Tx) error {
+		current, err := lookupSpecies(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if err := speciesUnusedTx(ctx, tx, current.Name); err != nil {
+			return err
+		}
+		if err := tx.RemoveSpecies(ctx, current.Name); err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, das.EventSpeciesRemoved, 0, current)
+	})
+}
+
+// apply changes to the species of a reference file in one unit of work
+// publishing each one, species already removed are skipped
+// nothing is applied if a species dinosaurs belong to would be removed or
+// change its diet, the error then names every such species
+func (ps *ParkService) ApplySpecies(ctx context.Context, save []das.Species, remove []string) error {
+	return ps.work.Atomic(ctx, func(tx das.Tx) error {
+		var inUse []string
+		for _, s := range save {
+			s, err := NormalizeSpecies(s)
+			if err != nil {
+				return err
+			}
+			kind := das.EventSpeciesUpdated
+			current, err := tx.GetSpecies(ctx, s.Name)
+			switch {
+			case errors.Is(err, das.ErrSpeciesNotFound):
+				kind = das.EventSpeciesAdded
+				err = tx.SaveSpecies(ctx, s)
+			case err == nil && current == s:
+				continue
+			case err == nil:
+				err = replaceSpeciesTx(ctx, tx, current, s)
+			}
+			if errors.Is(err, ErrSpeciesInUse) {
+				inUse = append(inUse, s.Name)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.RecordEvent(ctx, kind, 0, s); err != nil {
+				return err
+			}
+		}
+		for _, name := range remove {
+			current, err := tx.GetSpecies(ctx, name)
+			if errors.Is(err, das.ErrSpeciesNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			err = speciesUnusedTx(ctx, tx, name)
+			if errors.Is(err, ErrSpeciesInUse) {
+				inUse = append(inUse, name)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.RemoveSpecies(ctx, name); err != nil {
+				return err
+			}
+			if err := tx.RecordEvent(ctx, das.EventSpeciesRemoved, 0, current); err != nil {
+				return err
+			}
+		}
+		if len(inUse) != 0 {
+			slices.Sort(inUse)
+			return fmt.Errorf("%s : %w", strings.Join(inUse, ", "), ErrSpeciesInUse)
+		}
+		return nil
+	})
+}
+
+// trim and check the details given for a new dinosaur
+func newDinosaur(d das.Dinosaur) (das.Dinosaur, error) {
+	d.Name = strings.TrimSpace(d.Name)
+	d.Species = strings.TrimSpace(d.Species)
+	return d, ValidateDinosaur(d)
+}
+
+// resolve the species and diet of a new dinosaur locking its species row
+// a diet given with the dinosaur must match its species
+// the name is kept in the case it was given
+func resolveSpeciesTx(ctx context.Context, tx das.Tx, d das.Dinosaur) (das.Dinosaur, das.Species, error) {
+	species, err := lookupSpecies(ctx, tx, d.Species)
+	if err != nil {
+		return d, species, err
+	}
+	if len(d.Diet) != 0 && !strings.EqualFold(d.Diet, species.Diet) {
+		return d, species, fmt.Errorf("%s eat %s not %s : %w", species.Name, species.Diet, d.Diet, ErrDietMismatch)
+	}
+	d.Species = species.Name
+	d.Diet = species.Diet
+	return d, species, nil
+}
+
+// cages
+
+// check a cage will take a dinosaur of the given diet
+func checkAdmits(cage das.Cage, diet string) error {
+	switch {
+	case !das.AdmitsPlacement(cage.Status):
+		return fmt.Errorf("cage %d is %s : %w", cage.ID, cage.Status, ErrNotAdmitting)
+	case cage.Kind != diet:
+		return fmt.Errorf("cage %d is for diet %s not %s : %w", cage.ID, cage.Kind, diet, ErrDietMismatch)
+	case cage.Count >= cage.Capacity:
+		return fmt.Errorf("cage %d holds %d of %d : %w", cage.ID, cage.Count, cage.Capacity, das.ErrNoCapacity)
+	}
+	return nil
+}
+
+func checkVersion(cage das.Cage, version int) error {
+	if version != 0 && cage.Version != version {
+		return fmt.Errorf("cage %d is at version %d : %w", cage.ID, cage.Version, das.ErrVersionMismatch)
+	}
+	return nil
+}
+
+func createCageTx(ctx context.Context, tx das.Tx, cage das.Cage) (das.Cage, error) {
+	cage, err := tx.InsertCage(ctx, cage)
+	if err != nil {
+		return cage, err
+	}
+	return cage, tx.RecordEvent(ctx, das.EventCageCreated, cage.ID, cage)
+}
+
+// create an active cage for a diet
+func (ps *ParkService) AddCage(ctx context.Context, kind string, capacity int) (das.Cage, error) {
+	kind = strings.ToUpper(kind)
+	var ve ValidationError
+	ve.Diet("kind", kind)
+	if capacity < 1 {
+		ve.Add("capacity", "must be > 0")
+	}
+	if err := ve.Err(); err != nil {
+		return das.Cage{}, err
+	}
+	var cage das.Cage
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		var err error
+		cage, err = createCageTx(ctx, tx, das.Cage{Status: das.StatusActive, Capacity: capacity, Kind: kind})
+		return err
+	})
+	return cage, err
+}
+
+// move a cage to a new status enforcing the permitted transitions
+// the transition is recorded along with its reason and actor
+func (ps *ParkService) SetCageStatus(ctx context.Context, cageID int, change das.StatusChange) (das.Cage, error) {
+	if !das.ValidStatus(change.Status) {
+		return das.Cage{}, invalid("invalid status %s", change.Status)
+	}
+	var cage das.Cage
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		current, err := tx.GetCage(ctx, cageID)
+		if err != nil {
+			return err
+		}
+		cage, err = setCageStatusTx(ctx, tx, current, change)
+		return err
+	})
+	return cage, err
+}
+
+// change the status of a locked cage recording the transition
+func setCageStatusTx(ctx context.Context, tx das.Tx, current das.Cage, change das.StatusChange) (das.Cage, error) {
+	if err := checkVersion(current, change.Version); err != nil {
+		return current, err
+	}
+	if !das.ValidTransition(current.Status, change.Status) {
+		return current, fmt.Errorf("cage %d %s -> %s : %w", current.ID, current.Status, change.Status, das.ErrIllegalTransition)
+	}
+	if das.RequiresEmpty(change.Status) && current.Count != 0 {
+		return current, fmt.Errorf("cage %d holds %d dinosaurs : %w", current.ID, current.Count, das.ErrCageNotEmpty)
+	}
+	st := das.StatusTransition{Cage: current.ID, From: current.Status, To: change.Status, Reason: change.Reason, Actor: change.Actor, ChangedAt: time.Now().UTC()}
+	current.Status = change.Status
+	cage, err := tx.SaveCage(ctx, current)
+	if err != nil {
+		return cage, err
+	}
+	if err := tx.AddStatusTransition(ctx, st); err != nil {
+		return cage, err
+	}
+	return cage, tx.RecordEvent(ctx, das.EventCageStatusChanged, current.ID, st)
+}
+
+// change the capacity of a cage at the given version
+// the capacity may not drop below the current occupancy
+func (ps *ParkService) ResizeCage(ctx context.Context, cageID, version, capacity int) (das.Cage, error) {
+	var ve ValidationError
+	if capacity < 1 {
+		ve.Add("capacity", "must be > 0")
+	}
+	if err := ve.Err(); err != nil {
+		return das.Cage{}, err
+	}
+	var cage das.Cage
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		current, err := tx.GetCage(ctx, cageID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(current, version); err != nil {
+			return err
+		}
+		if current.Count > capacity {
+			return fmt.Errorf("cage %d holds %d dinosaurs : %w", cageID, current.Count, das.ErrCageNotEmpty)
+		}
+		current.Capacity = capacity
+		cage, err = tx.SaveCage(ctx, current)
+		if err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, das.EventCageResized, cage.ID, cage)
+	})
+	return cage, err
+}
+
+// remove an empty cage at the given version
+func (ps *ParkService) DeleteCage(ctx context.Context, cageID, version int) error {
+	return ps.work.Atomic(ctx, func(tx das.Tx) error {
+		current, err := tx.GetCage(ctx, cageID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(current, version); err != nil {
+			return err
+		}
+		if current.Count != 0 {
+			return fmt.Errorf("cage %d holds %d dinosaurs : %w", cageID, current.Count, das.ErrCageNotEmpty)
+		}
+		if err := tx.RemoveCage(ctx, cageID); err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, das.EventCageDeleted, cageID, current)
+	})
+}
+
+// dinosaurs
+
+// check a cage holds fewer dinosaurs of a species than the species allows
+func checkSpeciesLimitTx(ctx context.Context, tx das.Tx, cage das.Cage, species das.Species) error {
+	if species.MaxPerCage == 0 {
+		return nil
+	}
+	dinos, err := tx.GetDinosaursForCage(ctx, cage.ID)
+	if err != nil {
+		return err
+	}
+	if n := countSpecies(dinos, species.Name); n >= species.MaxPerCage {
+		return fmt.Errorf("cage %d holds %d %s of at most %d : %w", cage.ID, n, species.Name, species.MaxPerCage, ErrNotAdmitting)
+	}
+	return nil
+}
+
+func countSpecies(dinos []das.Dinosaur, species string) int {
+	n := 0
+	for _, d := range dinos {
+		if d.Species == species {
+			n++
+		}
+	}
+	return n
+}
+
+// choose a cage for a species with room using the placement strategy
+// skipping cages already holding as many of the species as it allows
+// if none has room and auto creation is allowed a new cage is created
+func (ps *ParkService) selectCageTx(ctx context.Context, tx das.Tx, species das.Species, opts das.PlacementOptions) (das.Cage, error) {
+	diet := species.Diet
+	cages, err := tx.FindCages(ctx, das.StatusActive, diet)
+	if err != nil {
+		return das.Cage{}, err
+	}
+	var candidates []das.Cage
+	for _, c := range cages {
+		if c.Count < c.Capacity {
+			candidates = append(candidates, c)
+		}
+	}
+	if species.MaxPerCage != 0 && len(candidates) != 0 {
+		var ids []int
+		for _, c := range candidates {
+			ids = append(ids, c.ID)
+		}
+		dinos, err := tx.GetDinosaursForCages(ctx, ids)
+		if err != nil {
+			return das.Cage{}, err
+		}
+		held := make(map[int]int)
+		for _, d := range dinos {
+			if d.Species == species.Name {
+				held[int(d.Cage)]++
+			}
+		}
+		candidates = slices.DeleteFunc(candidates, func(c das.Cage) bool {
+			return held[c.ID] >= species.MaxPerCage
+		})
+	}
+	cage, ok := das.SelectCage(opts.Strategy, candidates, ps.rr.Get(diet))
+	if ok {
+		return cage, nil
+	}
+	if !opts.AutoCreate {
+		return das.Cage{}, fmt.Errorf("no %s cage has room for %s : %w", diet, species.Name, das.ErrNoCapacity)
+	}
+	return createCageTx(ctx, tx, das.Cage{Status: das.StatusActive, Capacity: das.CageCapacity, Kind: diet})
+}
+
+// put a new dinosaur in a cage taking its place
+// the dinosaur is published as kind followed by cage.full if the cage is now full
+func insertInCageTx(ctx context.Context, tx das.Tx, cage das.Cage, d das.Dinosaur, kind string) (das.Dinosaur, error) {
+	cage.Count++
+	saved, err := tx.SaveCage(ctx, cage)
+	if err != nil {
+		return d, err
+	}
+	d.Cage = uint(cage.ID)
+	d, err = tx.InsertDinosaur(ctx, d)
+	if err != nil {
+		return d, err
+	}
+	if err := tx.RecordEvent(ctx, kind, cage.ID, d); err != nil {
+		return d, err
+	}
+	return d, recordFullTx(ctx, tx, cage, saved)
+}
+
+// publish the saved row of a cage a placement has left at capacity
+func recordFullTx(ctx context.Context, tx das.Tx, cage, saved das.Cage) error {
+	if cage.Count < cage.Capacity {
+		return nil
+	}
+	return tx.RecordEvent(ctx, das.EventCageFull, cage.ID, saved)
+}
+
+// add a dinosaur to a cage chosen by the placement strategy
+func (ps *ParkService) AddDinosaur(ctx context.Context, d das.Dinosaur, opts das.PlacementOptions) (das.Dinosaur, error) {
+	d, err := newDinosaur(d)
+	if err != nil {
+		return d, err
+	}
+	if !das.ValidStrategy(opts.Strategy) {
+		return d, invalid("unknown placement strategy %s", opts.Strategy)
+	}
+	err = ps.work.Atomic(ctx, func(tx das.Tx) error {
+		var species das.Species
+		d, species, err = resolveSpeciesTx(ctx, tx, d)
+		if err != nil {
+			return err
+		}
+		d, err = ps.addDinosaurTx(ctx, tx, d, species, opts)
+		return err
+	})
+	return d, err
+}
+
+// place a resolved dinosaur in a cage chosen by the placement strategy
+func (ps *ParkService) addDinosaurTx(ctx context.Context, tx das.Tx, d das.Dinosaur, species das.Species, opts das.PlacementOptions) (das.Dinosaur, error) {
+	cage, err := ps.selectCageTx(ctx, tx, species, opts)
+	if err != nil {
+		return d, err
+	}
+	d, err = insertInCageTx(ctx, tx, cage, d, das.EventDinoAdded)
+	if err != nil {
+		return d, err
+	}
+	// a hint only so a rolled back placement does no harm
+	ps.rr.Set(d.Diet, cage.ID)
+	return d, nil
+}
+
+// add a dinosaur to the given cage
+func (ps *ParkService) PlaceDinosaur(ctx context.Context, cageID int, d das.Dinosaur) (das.Dinosaur, error) {
+	var ve ValidationError
+	ve.Cage("cage", cageID)
+	if err := ve.Err(); err != nil {
+		return d, err
+	}
+	d, err := newDinosaur(d)
+	if err != nil {
+		return d, err
+	}
+	err = ps.work.Atomic(ctx, func(tx das.Tx) error {
+		var species das.Species
+		d, species, err = resolveSpeciesTx(ctx, tx, d)
+		if err != nil {
+			return err
+		}
+		d, err = placeDinosaurTx(ctx, tx, cageID, d, species)
+		return err
+	})
+	return d, err
+}
+
+// place a resolved dinosaur in the given cage
+func placeDinosaurTx(ctx context.Context, tx das.Tx, cageID int, d das.Dinosaur, species das.Species) (das.Dinosaur, error) {
+	cage, err := tx.GetCage(ctx, cageID)
+	if err != nil {
+		return d, err
+	}
+	if err := checkAdmits(cage, d.Diet); err != nil {
+		return d, err
+	}
+	if err := checkSpeciesLimitTx(ctx, tx, cage, species); err != nil {
+		return d, err
+	}
+	return insertInCageTx(ctx, tx, cage, d, das.EventDinoPlaced)
+}
+
+// load a dinosaur checking it is still at the given version
+func dinosaurAtVersion(ctx context.Context, tx das.Tx, dinoID, version int) (das.Dinosaur, error) {
+	d, err := tx.GetDinosaur(ctx, dinoID)
+	if err != nil {
+		return d, err
+	}
+	if d.Version != version {
+		return d, fmt.Errorf("dinosaur %d is at version %d : %w", dinoID, d.Version, das.ErrVersionMismatch)
+	}
+	return d, nil
+}
+
+// move a dinosaur at the given version to an active cage of its diet with room
+func (ps *ParkService) MoveDinosaur(ctx context.Context, dinoID, version, cageID int) (das.Dinosaur, error) {
+	var ve ValidationError
+	ve.Cage("cage", cageID)
+	if err := ve.Err(); err != nil {
+		return das.Dinosaur{}, err
+	}
+	var moved das.Dinosaur
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		d, err := dinosaurAtVersion(ctx, tx, dinoID, version)
+		if err != nil {
+			return err
+		}
+		if int(d.Cage) == cageID {
+			moved = d
+			return nil
+		}
+		// lock the cages in id order so opposing moves cannot deadlock
+		ids := []int{int(d.Cage), cageID}
+		if cageID < int(d.Cage) {
+			ids[0], ids[1] = ids[1], ids[0]
+		}
+		cages := make(map[int]das.Cage)
+		for _, id := range ids {
+			cages[id], err = tx.GetCage(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+		from, to := cages[int(d.Cage)], cages[cageID]
+		if err := checkAdmits(to, d.Diet); err != nil {
+			return err
+		}
+		species, err := tx.GetSpecies(ctx, d.Species)
+		switch {
+		case errors.Is(err, das.ErrSpeciesNotFound):
+		case err != nil:
+			return err
+		default:
+			if err := checkSpeciesLimitTx(ctx, tx, to, species); err != nil {
+				return err
+			}
+		}
+		mv := das.Relocation{Dinosaur: d.ID, Name: d.Name, Species: d.Species, From: from.ID, To: cageID}
+		moved, err = moveTx(ctx, tx, cages, d, mv)
+		return err
+	})
+	return moved, err
+}
+
+// apply a checked move between two locked cages
+// cages holds the current rows and is kept in step with the saved counts
+func moveTx(ctx context.Context, tx das.Tx, cages map[int]das.Cage, d das.Dinosaur, mv das.Relocation) (das.Dinosaur, error) {
+	from, to := cages[mv.From], cages[mv.To]
+	from.Count--
+	from, err := tx.SaveCage(ctx, from)
+	if err != nil {
+		return d, err
+	}
+	cages[mv.From] = from
+	to.Count++
+	saved, err := tx.SaveCage(ctx, to)
+	if err != nil {
+		return d, err
+	}
+	cages[mv.To] = saved
+	d.Cage = uint(mv.To)
+	moved, err := tx.SaveDinosaur(ctx, d)
+	if err != nil {
+		return moved, err
+	}
+	if err := tx.RecordEvent(ctx, das.EventDinoPlaced, mv.To, mv); err != nil {
+		return moved, err
+	}
+	return moved, recordFullTx(ctx, tx, to, saved)
+}
+
+// rename a dinosaur at the given version
+func (ps *ParkService) RenameDinosaur(ctx context.Context, dinoID, version int, name string) (das.Dinosaur, error) {
+	name = strings.TrimSpace(name)
+	var ve ValidationError
+	ve.Name("name", name)
+	if err := ve.Err(); err != nil {
+		return das.Dinosaur{}, err
+	}
+	var renamed das.Dinosaur
+	err := ps.work.Atomic(ctx, func(tx das.Tx) error {
+		d, err := dinosaurAtVersion(ctx, tx, dinoID, version)
+		if err != nil {
+			return err
+		}
+		d.Name = name
+		renamed, err = tx.SaveDinosaur(ctx, d)
+		if err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, das.EventDinoRenamed, int(renamed.Cage), renamed)
+	})
+	return renamed, err
+}
+
+// remove a dinosaur at the given version freeing its place in its cage
+func (ps *ParkService) DeleteDinosaur(ctx context.Context, dinoID, version int) error {
+	return ps.work.Atomic(ctx, func(tx das.Tx) error {
+		d, err := dinosaurAtVersion(ctx, tx, dinoID, version)
+		if err != nil {
+			return err
+		}
+		cage, err := tx.GetCage(ctx, int(d.Cage))
+		if err != nil {
+			return err
+		}
+		if err := tx.RemoveDinosaur(ctx, dinoID); err != nil {
+			return err
+		}
+		cage.Count--
+		if _, err := tx.SaveCage(ctx, cage); err != nil {
+			return err
+		}
+		return tx.RecordEvent(ctx, das.EventDinoRemoved, cage.ID, d)
+	})
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"dinocage/das"
+	"dinocage/mocks"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// build a service whose units of work run on a mocked transaction
+func testService(t *testing.T) (*ParkService, *mocks.MockSpeciesRepo, *mocks.MockTx) {
+	ctrl := gomock.NewController(t)
+	species := mocks.NewMockSpeciesRepo(ctrl)
+	work := mocks.NewMockUnitOfWork(ctrl)
+	tx := mocks.NewMockTx(ctrl)
+	work.EXPECT().Atomic(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(das.Tx) error) error {
+			return fn(tx)
+		},
+	).AnyTimes()
+	return NewParkService(species, work, das.PlacementOptions{}), species, tx
+}
+
+func TestSeedSpecies(t *testing.T) {
+	ps, _, tx := testService(t)
+	ctx := context.Background()
+
+	tx.EXPECT().ListSpecies(gomock.Any()).Return(nil, nil)
+	tx.EXPECT().SaveSpecies(gomock.Any(), das.Species{Name: "stegosaurus", Diet: das.HerbivoreCode}).Return(nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventSpeciesAdded, 0, das.Species{Name: "stegosaurus", Diet: das.HerbivoreCode}).Return(nil)
+	n, err := ps.SeedSpecies(ctx, []das.Species{{Name: " Stegosaurus ", Diet: "h"}})
+	if err != nil || n != 1 {
+		t.Errorf("seed returned %d %v", n, err)
+	}
+
+	// stored species are left as they are
+	tx.EXPECT().ListSpecies(gomock.Any()).Return([]das.Species{{Name: "dodo", Diet: das.HerbivoreCode}}, nil)
+	if n, err := ps.SeedSpecies(ctx, []das.Species{{Name: "stegosaurus", Diet: "H"}}); err != nil || n != 0 {
+		t.Errorf("seed over stored species returned %d %v", n, err)
+	}
+
+	for _, s := range []das.Species{{Name: "", Diet: "H"}, {Name: "dodo", Diet: "O"}} {
+		tx.EXPECT().ListSpecies(gomock.Any()).Return(nil, nil)
+		if _, err := ps.SeedSpecies(ctx, []das.Species{s}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %+v to be invalid got %v", s, err)
+		}
+	}
+}
+
+func TestPlaceDinosaurRules(t *testing.T) {
+	ps, _, tx := testService(t)
+	tx.EXPECT().GetSpecies(gomock.Any(), "tyrannosaurus").Return(das.Species{Name: "tyrannosaurus", Diet: das.CarnivoreCode}, nil).AnyTimes()
+	tx.EXPECT().GetSpecies(gomock.Any(), "dodo").Return(das.Species{}, das.ErrSpeciesNotFound)
+
+	tests := []struct {
+		cage das.Cage
+		want error
+	}{
+		{das.Cage{ID: 1, Status: das.StatusActive, Capacity: 2, Kind: das.HerbivoreCode}, ErrDietMismatch},
+		{das.Cage{ID: 1, Status: das.StatusMaintenance, Capacity: 2, Kind: das.CarnivoreCode}, ErrNotAdmitting},
+		{das.Cage{ID: 1, Status: das.StatusActive, Capacity: 2, Count: 2, Kind: das.CarnivoreCode}, das.ErrNoCapacity},
+	}
+	rex := das.Dinosaur{Species: "Tyrannosaurus", Name: "Rex"}
+	for _, tc := range tests {
+		tx.EXPECT().GetCage(gomock.Any(), 1).Return(tc.cage, nil)
+		if _, err := ps.PlaceDinosaur(context.Background(), 1, rex); !errors.Is(err, tc.want) {
+			t.Errorf("placing in %+v expected %v got %v", tc.cage, tc.want, err)
+		}
+	}
+
+	if _, err := ps.PlaceDinosaur(context.Background(), 1, das.Dinosaur{Species: "dodo", Name: "dodo"}); !errors.Is(err, ErrUnknownSpecies) {
+		t.Errorf("expected unknown species got %v", err)
+	}
+	if _, err := ps.PlaceDinosaur(context.Background(), 1, das.Dinosaur{Species: "tyrannosaurus", Name: "rex", Diet: "H"}); !errors.Is(err, ErrDietMismatch) {
+		t.Errorf("expected a diet mismatch got %v", err)
+	}
+
+	cage := das.Cage{ID: 1, Status: das.StatusActive, Capacity: 2, Count: 1, Kind: das.CarnivoreCode, Version: 3}
+	// the name keeps its case while the species takes the registered name
+	want := das.Dinosaur{Species: "tyrannosaurus", Name: "Rex", Diet: das.CarnivoreCode, Cage: 1}
+	saved := das.Cage{ID: 1, Status: das.StatusActive, Capacity: 2, Count: 2, Kind: das.CarnivoreCode, Version: 4}
+	tx.EXPECT().GetCage(gomock.Any(), 1).Return(cage, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), das.Cage{ID: 1, Status: das.StatusActive, Capacity: 2, Count: 2, Kind: das.CarnivoreCode, Version: 3}).Return(saved, nil)
+	tx.EXPECT().InsertDinosaur(gomock.Any(), want).Return(want, nil)
+	// the placement takes the last place so the full cage follows the dinosaur
+	gomock.InOrder(
+		tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoPlaced, 1, want).Return(nil),
+		tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageFull, 1, saved).Return(nil),
+	)
+	if d, err := ps.PlaceDinosaur(context.Background(), 1, rex); err != nil || d != want {
+		t.Errorf("place returned %+v %v", d, err)
+	}
+}
+
+func TestValidationReportsEveryField(t *testing.T) {
+	ps, _, _ := testService(t)
+
+	_, err := ps.PlaceDinosaur(context.Background(), 1, das.Dinosaur{Name: "<script>", Diet: "V"})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected a validation error got %v", err)
+	}
+	want := []string{"name", "species", "diet"}
+	if len(ve.Fields) != len(want) {
+		t.Fatalf("expected problems with %v got %+v", want, ve.Fields)
+	}
+	for i, f := range ve.Fields {
+		if f.Field != want[i] {
+			t.Errorf("expected a problem with %s got %+v", want[i], f)
+		}
+	}
+
+	_, err = ps.AddCage(context.Background(), "V", 0)
+	if !errors.As(err, &ve) || len(ve.Fields) != 2 {
+		t.Errorf("expected kind and capacity problems got %v", err)
+	}
+	if _, err := NormalizeSpecies(das.Species{Name: strings.Repeat("x", MaxNameLength+1), Diet: "H"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected a long name to be refused got %v", err)
+	}
+	if _, err := ps.MoveDinosaur(context.Background(), 1, 1, 0); !errors.As(err, &ve) || ve.Fields[0].Field != "cage" {
+		t.Errorf("expected a cage problem got %v", err)
+	}
+}
+
+func TestMoveDinosaurLocksCagesInOrder(t *testing.T) {
+	ps, _, tx := testService(t)
+	tx.EXPECT().GetSpecies(gomock.Any(), "tyrannosaurus").Return(das.Species{Name: "tyrannosaurus", Diet: das.CarnivoreCode}, nil)
+
+	rex := das.Dinosaur{ID: 9, Species: "tyrannosaurus", Name: "rex", Diet: das.CarnivoreCode, Cage: 5, Version: 2}
+	tx.EXPECT().GetDinosaur(gomock.Any(), 9).Return(rex, nil)
+	gomock.InOrder(
+		tx.EXPECT().GetCage(gomock.Any(), 2).Return(das.Cage{ID: 2, Status: das.StatusActive, Capacity: 4, Kind: das.CarnivoreCode}, nil),
+		tx.EXPECT().GetCage(gomock.Any(), 5).Return(das.Cage{ID: 5, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode}, nil),
+	)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c das.Cage) (das.Cage, error) {
+		if (c.ID == 5 && c.Count != 0) || (c.ID == 2 && c.Count != 1) {
+			t.Errorf("unexpected cage count %+v", c)
+		}
+		return c, nil
+	}).Times(2)
+	tx.EXPECT().SaveDinosaur(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+		d.Version++
+		return d, nil
+	})
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoPlaced, 2, das.Relocation{Dinosaur: 9, Name: "rex", Species: "tyrannosaurus", From: 5, To: 2}).Return(nil)
+
+	moved, err := ps.MoveDinosaur(context.Background(), 9, 2, 2)
+	if err != nil || moved.Cage != 2 || moved.Version != 3 {
+		t.Errorf("move returned %+v %v", moved, err)
+	}
+
+	tx.EXPECT().GetDinosaur(gomock.Any(), 9).Return(rex, nil)
+	if _, err := ps.MoveDinosaur(context.Background(), 9, 1, 2); !errors.Is(err, das.ErrVersionMismatch) {
+		t.Errorf("expected a version mismatch got %v", err)
+	}
+}
+
+func TestMoveIntoLastPlace(t *testing.T) {
+	ps, _, tx := testService(t)
+	tx.EXPECT().GetSpecies(gomock.Any(), "tyrannosaurus").Return(das.Species{Name: "tyrannosaurus", Diet: das.CarnivoreCode}, nil)
+
+	rex := das.Dinosaur{ID: 9, Species: "tyrannosaurus", Name: "rex", Diet: das.CarnivoreCode, Cage: 5, Version: 2}
+	tx.EXPECT().GetDinosaur(gomock.Any(), 9).Return(rex, nil)
+	tx.EXPECT().GetCage(gomock.Any(), 2).Return(das.Cage{ID: 2, Status: das.StatusActive, Capacity: 2, Count: 1, Kind: das.CarnivoreCode}, nil)
+	tx.EXPECT().GetCage(gomock.Any(), 5).Return(das.Cage{ID: 5, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c das.Cage) (das.Cage, error) {
+		c.Version++
+		return c, nil
+	}).Times(2)
+	tx.EXPECT().SaveDinosaur(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+		return d, nil
+	})
+	full := das.Cage{ID: 2, Status: das.StatusActive, Capacity: 2, Count: 2, Kind: das.CarnivoreCode, Version: 1}
+	gomock.InOrder(
+		tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoPlaced, 2, gomock.Any()).Return(nil),
+		tx.EXPECT().RecordEvent(gomock.Any(), das.EventCageFull, 2, full).Return(nil),
+	)
+	if _, err := ps.MoveDinosaur(context.Background(), 9, 2, 2); err != nil {
+		t.Errorf("move failed %v", err)
+	}
+}
+
+func TestSetCageStatusRules(t *testing.T) {
+	ps, _, tx := testService(t)
+
+	if _, err := ps.SetCageStatus(context.Background(), 3, das.StatusChange{Status: "OPEN"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected an invalid status got %v", err)
+	}
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(das.Cage{ID: 3, Status: das.StatusActive, Count: 2, Version: 1}, nil)
+	if _, err := ps.SetCageStatus(context.Background(), 3, das.StatusChange{Status: das.StatusDecommissioned}); !errors.Is(err, das.ErrIllegalTransition) {
+		t.Errorf("expected an illegal transition got %v", err)
+	}
+	tx.EXPECT().GetCage(gomock.Any(), 3).Return(das.Cage{ID: 3, Status: das.StatusDown, Count: 2, Version: 1}, nil)
+	if _, err := ps.SetCageStatus(context.Background(), 3, das.StatusChange{Status: das.StatusDecommissioned}); !errors.Is(err, das.ErrCageNotEmpty) {
+		t.Errorf("expected a non empty cage got %v", err)
+	}
+}
+
+func TestUpdateAndDeleteSpecies(t *testing.T) {
+	ps, _, tx := testService(t)
+	ctx := context.Background()
+	raptor := das.Species{Name: "velociraptor", Diet: das.CarnivoreCode}
+	tx.EXPECT().GetSpecies(gomock.Any(), "velociraptor").Return(raptor, nil).AnyTimes()
+	tx.EXPECT().GetSpecies(gomock.Any(), "dodo").Return(das.Species{}, das.ErrSpeciesNotFound).AnyTimes()
+
+	// details other than the diet change freely
+	details := das.Species{Name: "Velociraptor", Diet: "c", Period: "Cretaceous", WeightKg: 15, DangerLevel: 4, MaxPerCage: 6, Habitat: "Scrub"}
+	want := das.Species{Name: "velociraptor", Diet: "C", Period: "cretaceous", WeightKg: 15, DangerLevel: 4, MaxPerCage: 6, Habitat: "scrub"}
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventSpeciesUpdated, 0, want).Return(nil)
+	tx.EXPECT().SaveSpecies(gomock.Any(), want).Return(nil)
+	if s, err := ps.UpdateSpecies(ctx, details); err != nil || s != want {
+		t.Errorf("update returned %+v %v", s, err)
+	}
+
+	// the diet cannot change under dinosaurs already in the park
+	tx.EXPECT().GetDinosaursForSpecies(gomock.Any(), []string{"velociraptor"}).Return([]das.Dinosaur{{ID: 1, Species: "velociraptor"}}, nil).Times(2)
+	if _, err := ps.UpdateSpecies(ctx, das.Species{Name: "velociraptor", Diet: "H"}); !errors.Is(err, ErrSpeciesInUse) {
+		t.Errorf("expected the diet change to be refused got %v", err)
+	}
+	if err := ps.DeleteSpecies(ctx, "velociraptor"); !errors.Is(err, ErrSpeciesInUse) {
+		t.Errorf("expected the delete to be refused got %v", err)
+	}
+
+	tx.EXPECT().GetDinosaursForSpecies(gomock.Any(), []string{"velociraptor"}).Return(nil, nil)
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventSpeciesRemoved, 0, raptor).Return(nil)
+	tx.EXPECT().RemoveSpecies(gomock.Any(), "velociraptor").Return(nil)
+	if err := ps.DeleteSpecies(ctx, "Velociraptor"); err != nil {
+		t.Errorf("delete failed %v", err)
+	}
+
+	if err := ps.DeleteSpecies(ctx, "dodo"); !errors.Is(err, ErrUnknownSpecies) {
+		t.Errorf("expected an unknown species got %v", err)
+	}
+	for _, s := range []das.Species{
+		{Name: "dodo", Diet: "H"},
+		{Name: "velociraptor", Diet: "C", Period: "holocene"},
+		{Name: "velociraptor", Diet: "C", DangerLevel: 6},
+		{Name: "velociraptor", Diet: "C", MaxPerCage: -1},
+	} {
+		if _, err := ps.UpdateSpecies(ctx, s); err == nil {
+			t.Errorf("expected %+v to be refused", s)
+		}
+	}
+}
+
+func TestMaxPerCage(t *testing.T) {
+	ps, _, tx := testService(t)
+	ctx := context.Background()
+	tx.EXPECT().GetSpecies(gomock.Any(), "spinosaurus").Return(das.Species{Name: "spinosaurus", Diet: das.CarnivoreCode, MaxPerCage: 1}, nil).AnyTimes()
+	spino := das.Dinosaur{Species: "spinosaurus", Name: "spike"}
+
+	// the first cage already has its spinosaurus so the second is chosen
+	tx.EXPECT().FindCages(gomock.Any(), das.StatusActive, das.CarnivoreCode).Return([]das.Cage{
+		{ID: 1, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode},
+		{ID: 2, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode},
+	}, nil)
+	tx.EXPECT().GetDinosaursForCages(gomock.Any(), []int{1, 2}).Return([]das.Dinosaur{
+		{ID: 7, Species: "spinosaurus", Cage: 1},
+		{ID: 8, Species: "tyrannosaurus", Cage: 2},
+	}, nil)
+	tx.EXPECT().SaveCage(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c das.Cage) (das.Cage, error) {
+		return c, nil
+	})
+	tx.EXPECT().InsertDinosaur(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, d das.Dinosaur) (das.Dinosaur, error) {
+		d.ID = 9
+		return d, nil
+	})
+	tx.EXPECT().RecordEvent(gomock.Any(), das.EventDinoAdded, 2, gomock.Any()).Return(nil)
+	d, err := ps.AddDinosaur(ctx, spino, das.PlacementOptions{Strategy: das.StrategyFirstFit})
+	if err != nil || d.Cage != 2 {
+		t.Errorf("expected placement in cage 2 got %+v %v", d, err)
+	}
+
+	// placing directly in a cage at the limit is refused
+	tx.EXPECT().GetCage(gomock.Any(), 1).Return(das.Cage{ID: 1, Status: das.StatusActive, Capacity: 4, Count: 1, Kind: das.CarnivoreCode}, nil)
+	tx.EXPECT().GetDinosaursForCage(gomock.Any(), 1).Return([]das.Dinosaur{{ID: 7, Species: "spinosaurus", Cage: 1}}, nil)
+	if _, err := ps.PlaceDinosaur(ctx, 1, spino); !errors.Is(err, ErrNotAdmitting) {
+		t.Errorf("expected the species limit to refuse the placement got %v", err)
+	}
+}
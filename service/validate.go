@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"dinocage/das"
+)
+
+// longest name accepted for a species, dinosaur or habitat
+const MaxNameLength = 64
+
+// a problem with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// every field problem found in a request so they can be reported together
+// wraps ErrInvalid
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (ve *ValidationError) Add(field, format string, a ...any) {
+	ve.Fields = append(ve.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+}
+
+// the error if any problems were found or nil
+func (ve *ValidationError) Err() error {
+	if len(ve.Fields) == 0 {
+		return nil
+	}
+	return ve
+}
+
+func (ve *ValidationError) Error() string {
+	var parts []string
+	for _, f := range ve.Fields {
+		parts = append(parts, f.Field+" "+f.Message)
+	}
+	return fmt.Sprintf("%s : %s", strings.Join(parts, ", "), ErrInvalid)
+}
+
+func (ve *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
+
+// check a name is present, not too long and made of letters, digits, spaces
+// and the punctuation - ' . _ starting with a letter or digit
+func (ve *ValidationError) Name(field, name string) {
+	switch {
+	case len(name) == 0:
+		ve.Add(field, "is required")
+		return
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		ve.Add(field, "must be at most %d characters", MaxNameLength)
+		return
+	}
+	for i, c := range name {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			continue
+		}
+		if i == 0 || !strings.ContainsRune(" -'._", c) {
+			ve.Add(field, "must start with a letter or digit and contain only letters, digits, spaces and - ' . _")
+			return
+		}
+	}
+}
+
+// check a diet is one of the diet codes H or C
+func (ve *ValidationError) Diet(field, diet string) {
+	if !das.ValidDiet(diet) {
+		ve.Add(field, "must be H (herbivore) or C (carnivore) not %q", diet)
+	}
+}
+
+// check an id refers to a cage
+func (ve *ValidationError) Cage(field string, cageID int) {
+	if cageID < 1 {
+		ve.Add(field, "must be a cage id > 0")
+	}
+}
+
+// check the fields of a new dinosaur, the diet is optional
+func ValidateDinosaur(d das.Dinosaur) error {
+	var ve ValidationError
+	ve.Name("name", d.Name)
+	ve.Name("species", d.Species)
+	if len(d.Diet) != 0 {
+		ve.Diet("diet", d.Diet)
+	}
+	return ve.Err()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"dinocage/das"
+)
+
+const FormatJSON = "json"
+
+// full park state as exported and restored
+type ParkSnapshot struct {
+	TakenAt   time.Time      `json:"taken_at"`
+	Species   []das.Species  `json:"species"`
+	Cages     []das.Cage     `json:"cages"`
+	Dinosaurs []das.Dinosaur `json:"dinosaurs"`
+}
+
+var snapshotColumns = []string{"type", "id", "name", "species", "diet", "kind", "status", "capacity", "cage"}
+
+func NewParkSnapshot(snap das.Snapshot) ParkSnapshot {
+	return ParkSnapshot{TakenAt: snap.TakenAt, Species: snap.Species, Cages: snap.Cages, Dinosaurs: snap.Dinosaurs}
+}
+
+// flatten a snapshot into import records so exports can be imported or restored
+func (ps ParkSnapshot) Records() []das.ImportRecord {
+	var records []das.ImportRecord
+	for _, s := range ps.Species {
+		records = append(records, das.ImportRecord{Type: das.ImportSpecies, Name: s.Name, Diet: s.Diet})
+	}
+	for _, c := range ps.Cages {
+		records = append(records, das.ImportRecord{Type: das.ImportCage, ID: c.ID, Kind: c.Kind, Status: c.Status, Capacity: c.Capacity})
+	}
+	for _, d := range ps.Dinosaurs {
+		records = append(records, das.ImportRecord{Type: das.ImportDinosaur, ID: int(d.ID), Name: d.Name, Species: d.Species, Diet: d.Diet, Cage: int(d.Cage)})
+	}
+	return records
+}
+
+// rebuild a snapshot from import records
+func SnapshotFromRecords(records []das.ImportRecord) (ParkSnapshot, error) {
+	var ps ParkSnapshot
+	for i, rec := range records {
+		switch rec.Type {
+		case das.ImportSpecies:
+			ps.Species = append(ps.Species, das.Species{Name: rec.Name, Diet: rec.Diet})
+		case das.ImportCage:
+			ps.Cages = append(ps.Cages, das.Cage{ID: rec.ID, Kind: rec.Kind, Status: rec.Status, Capacity: rec.Capacity})
+		case das.ImportDinosaur:
+			ps.Dinosaurs = append(ps.Dinosaurs, das.Dinosaur{ID: uint(rec.ID), Name: rec.Name, Species: rec.Species, Diet: rec.Diet, Cage: uint(rec.Cage)})
+		default:
+			return ps, fmt.Errorf("record %d : unknown record type %q", i+1, rec.Type)
+		}
+	}
+	return ps, nil
+}
+
+// write a snapshot as a json document, json lines or csv
+func WriteSnapshot(w io.Writer, ps ParkSnapshot, format string) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(ps)
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, rec := range ps.Records() {
+			err := enc.Encode(rec)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		err := cw.Write(snapshotColumns)
+		if err != nil {
+			return err
+		}
+		for _, rec := range ps.Records() {
+			err = cw.Write([]string{rec.Type, itoaOrEmpty(rec.ID), rec.Name, rec.Species, rec.Diet, rec.Kind, rec.Status, itoaOrEmpty(rec.Capacity), itoaOrEmpty(rec.Cage)})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown snapshot format %s", format)
+	}
+}
+
+// read a snapshot written by WriteSnapshot
+func ReadSnapshot(r io.Reader, format string) (ParkSnapshot, error) {
+	if format == FormatJSON {
+		var ps ParkSnapshot
+		err := json.NewDecoder(r).Decode(&ps)
+		return ps, err
+	}
+	records, err := ParseImport(r, format)
+	if err != nil {
+		return ParkSnapshot{}, err
+	}
+	return SnapshotFromRecords(records)
+}
+
+func itoaOrEmpty(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
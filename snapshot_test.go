@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"dinocage/das"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ps := ParkSnapshot{
+		Species:   []das.Species{{Name: "tyrannosaurus", Diet: "C"}},
+		Cages:     []das.Cage{{ID: 3, Status: das.StatusActive, Capacity: 5, Kind: "C"}},
+		Dinosaurs: []das.Dinosaur{{ID: 8, Species: "tyrannosaurus", Name: "rexy", Diet: "C", Cage: 3}},
+	}
+	for _, format := range []string{FormatJSON, FormatJSONL, FormatCSV} {
+		var buf bytes.Buffer
+		err := WriteSnapshot(&buf, ps, format)
+		if err != nil {
+			t.Fatalf("%s WriteSnapshot failed with %v", format, err)
+		}
+		got, err := ReadSnapshot(&buf, format)
+		if err != nil {
+			t.Fatalf("%s ReadSnapshot failed with %v", format, err)
+		}
+		got.TakenAt = time.Time{}
+		if !reflect.DeepEqual(got, ps) {
+			t.Errorf("%s round trip gave %+v expected %+v", format, got, ps)
+		}
+	}
+}
@@ -1,38 +1,202 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"dinocage/das"
+	"dinocage/service"
 )
 
-type Species struct {
-	Name string `json:"name"`
-	Diet string `json:"diet"`
-}
-
-func ReadSpecies(name string) (*GenMap[string, string], error) {
-	speciesMap := &GenMap[string, string]{}
-	var species []Species
+func ReadSpecies(name string) (*GenMap[string, das.Species], error) {
+	speciesMap := &GenMap[string, das.Species]{}
+	var species []das.Species
 	b, err := os.ReadFile(name)
 	if err != nil {
 		return speciesMap, err
 	}
 	err = json.Unmarshal(b, &species)
 	for _, s := range species {
-		diet := strings.ToUpper(s.Diet)
-		if diet != das.HerbivoreCode && diet != das.CarnivoreCode {
-			// invalid code given skip
+		s, verr := service.NormalizeSpecies(s)
+		if verr != nil {
+			// invalid entry given skip
+			log.Printf("skipping species : %v", verr)
 			continue
 		}
-		speciesMap.Store(strings.ToLower(s.Name), s.Diet)
+		speciesMap.Store(s.Name, s)
+	}
+	for _, e := range SortedEntries(speciesMap) {
+		log.Printf("known species: %s diet: %s", e.Key, e.Value.Diet)
 	}
-	speciesMap.Range(func(name, diet string) bool {
-		log.Printf("known species: %s diet: %s", name, diet)
-		return true
-	})
 	return speciesMap, err
 }
+
+// species reloading
+
+// the difference between two sets of species
+// changed species are given with their new details
+type SpeciesDiff struct {
+	Added   []das.Species `json:"added,omitempty"`
+	Removed []das.Species `json:"removed,omitempty"`
+	Changed []das.Species `json:"changed,omitempty"`
+}
+
+func (sd SpeciesDiff) Empty() bool {
+	return len(sd.Added) == 0 && len(sd.Removed) == 0 && len(sd.Changed) == 0
+}
+
+func (sd SpeciesDiff) String() string {
+	if sd.Empty() {
+		return "no changes"
+	}
+	var parts []string
+	for _, group := range []struct {
+		label   string
+		species []das.Species
+	}{{"added", sd.Added}, {"removed", sd.Removed}, {"changed", sd.Changed}} {
+		for _, s := range group.species {
+			parts = append(parts, group.label+" "+s.Name+" ("+s.Diet+")")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// compare two species maps in name order
+func DiffSpecies(from, to *GenMap[string, das.Species]) SpeciesDiff {
+	var diff SpeciesDiff
+	for _, e := range SortedEntries(to) {
+		s, ok := from.Load(e.Key)
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, e.Value)
+		case s != e.Value:
+			diff.Changed = append(diff.Changed, e.Value)
+		}
+	}
+	for _, e := range SortedEntries(from) {
+		if _, ok := to.Load(e.Key); !ok {
+			diff.Removed = append(diff.Removed, e.Value)
+		}
+	}
+	return diff
+}
+
+// parse a species file rejecting it as a whole if any entry is invalid
+// unlike ReadSpecies which skips bad entries at startup
+func ParseSpecies(b []byte) (*GenMap[string, das.Species], error) {
+	var species []das.Species
+	err := json.Unmarshal(b, &species)
+	if err != nil {
+		return nil, err
+	}
+	speciesMap := &GenMap[string, das.Species]{}
+	var problems []error
+	for i, s := range species {
+		s, err := service.NormalizeSpecies(s)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("entry %d : %w", i, err))
+			continue
+		}
+		if prev, loaded := speciesMap.LoadOrStore(s.Name, s); loaded && prev != s {
+			problems = append(problems, fmt.Errorf("entry %d : %s is listed twice with different details", i, s.Name))
+		}
+	}
+	return speciesMap, errors.Join(problems...)
+}
+
+const DefaultSpeciesInterval = 5 * time.Second
+
+// applies changes to the species file when the file changes or on request
+type SpeciesReloader struct {
+	path string
+	park *service.ParkService
+	// the species the file held when it was last applied
+	file *GenMap[string, das.Species]
+	// how often the file is checked for changes, zero only reloads on request
+	Interval time.Duration
+	modTime  time.Time
+	size     int64
+}
+
+func NewSpeciesReloader(path string, park *service.ParkService, file *GenMap[string, das.Species]) *SpeciesReloader {
+	sr := &SpeciesReloader{path: path, park: park, file: file, Interval: DefaultSpeciesInterval}
+	sr.changed()
+	return sr
+}
+
+// report whether the file has been modified since it was last seen
+func (sr *SpeciesReloader) changed() bool {
+	info, err := os.Stat(sr.path)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(sr.modTime) && info.Size() == sr.size {
+		return false
+	}
+	sr.modTime, sr.size = info.ModTime(), info.Size()
+	return true
+}
+
+// apply the changes made to the file since it was last applied
+// species added, changed or removed through the api are kept unless the file
+// changes them too, and nothing is applied if the change would remove or
+// change the diet of a species that existing dinosaurs belong to
+func (sr *SpeciesReloader) Reload(ctx context.Context) (SpeciesDiff, error) {
+	b, err := os.ReadFile(sr.path)
+	if err != nil {
+		return SpeciesDiff{}, err
+	}
+	next, err := ParseSpecies(b)
+	if err != nil {
+		return SpeciesDiff{}, fmt.Errorf("invalid species file : %w", err)
+	}
+	diff := DiffSpecies(sr.file, next)
+	if diff.Empty() {
+		return diff, nil
+	}
+	var removed []string
+	for _, s := range diff.Removed {
+		removed = append(removed, s.Name)
+	}
+	err = sr.park.ApplySpecies(ctx, append(slices.Clone(diff.Added), diff.Changed...), removed)
+	if err != nil {
+		return diff, err
+	}
+	sr.file = next
+	return diff, nil
+}
+
+// reload when the file changes or a value arrives on reload until the context is done
+func (sr *SpeciesReloader) Run(ctx context.Context, reload <-chan os.Signal) {
+	var tick <-chan time.Time
+	if sr.Interval > 0 {
+		ticker := time.NewTicker(sr.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			sr.changed()
+		case <-tick:
+			if !sr.changed() {
+				continue
+			}
+		}
+		diff, err := sr.Reload(ctx)
+		if err != nil {
+			log.Printf("species reload refused, keeping the current species : %v", err)
+			continue
+		}
+		log.Printf("species reloaded from %s : %v", sr.path, diff)
+	}
+}
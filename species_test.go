@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"dinocage/das"
+	"dinocage/service"
+)
+
+func writeSpeciesFile(t *testing.T, path, content string) {
+	err := os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("unable to write species file %v", err)
+	}
+}
+
+func TestParseSpecies(t *testing.T) {
+	m, err := ParseSpecies([]byte(`[{"name":" Stegosaurus ","diet":"h"},{"name":"stegosaurus","diet":"H"}]`))
+	if err != nil || m.Len() != 1 {
+		t.Fatalf("expected one species got %v %v", m.Keys(), err)
+	}
+	if s, _ := m.Load("stegosaurus"); s.Diet != das.HerbivoreCode {
+		t.Errorf("expected a normalised diet got %s", s.Diet)
+	}
+	_, err = ParseSpecies([]byte(`[{"name":"","diet":"H"},{"name":"dodo","diet":"X"},{"name":"raptor","diet":"C"},{"name":"raptor","diet":"H"}]`))
+	if err == nil {
+		t.Fatalf("expected invalid entries to be rejected")
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 3 {
+		t.Errorf("expected every problem to be reported got %v", err)
+	}
+	if _, err := ParseSpecies([]byte(`{"name":"raptor"}`)); err == nil {
+		t.Errorf("expected malformed json to be rejected")
+	}
+}
+
+func TestDiffSpecies(t *testing.T) {
+	from, to := &GenMap[string, das.Species]{}, &GenMap[string, das.Species]{}
+	for _, s := range []das.Species{{Name: "triceratops", Diet: "H"}, {Name: "velociraptor", Diet: "C"}, {Name: "dodo", Diet: "H"}} {
+		from.Store(s.Name, s)
+	}
+	for _, s := range []das.Species{{Name: "triceratops", Diet: "H"}, {Name: "velociraptor", Diet: "C", DangerLevel: 4}, {Name: "brachiosaurus", Diet: "H"}} {
+		to.Store(s.Name, s)
+	}
+	want := SpeciesDiff{
+		Added:   []das.Species{{Name: "brachiosaurus", Diet: "H"}},
+		Removed: []das.Species{{Name: "dodo", Diet: "H"}},
+		Changed: []das.Species{{Name: "velociraptor", Diet: "C", DangerLevel: 4}},
+	}
+	if diff := DiffSpecies(from, to); !reflect.DeepEqual(diff, want) {
+		t.Errorf("expected %+v got %+v", want, diff)
+	}
+	if diff := DiffSpecies(to, to); !diff.Empty() || diff.String() != "no changes" {
+		t.Errorf("expected no changes got %v", diff)
+	}
+}
+
+// handlers over an sqlite database seeded with the species in a file
+func sqliteHandlers(t *testing.T, path string) (*AppHandlers, *GenMap[string, das.Species]) {
+	dap, err := das.ConnectSQLite(filepath.Join(t.TempDir(), "dinocage.db"), schema)
+	if err != nil {
+		t.Fatalf("unable to open sqlite %v", err)
+	}
+	t.Cleanup(dap.Close)
+	speciesMap, err := ReadSpecies(path)
+	if err != nil {
+		t.Fatalf("unable to read species %v", err)
+	}
+	var seed []das.Species
+	for _, e := range SortedEntries(speciesMap) {
+		seed = append(seed, e.Value)
+	}
+	ah := NewAppHandlers(dap, das.DefaultPlacement())
+	if _, err := ah.park.SeedSpecies(context.Background(), seed); err != nil {
+		t.Fatalf("unable to seed species %v", err)
+	}
+	return ah, speciesMap
+}
+
+func TestSpeciesReload(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "species.json")
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"},{"name":"velociraptor","diet":"C"}]`)
+	ah, speciesMap := sqliteHandlers(t, path)
+	reloader := NewSpeciesReloader(path, ah.park, speciesMap)
+	if _, err := ah.park.AddDinosaur(ctx, das.Dinosaur{Species: "velociraptor", Name: "blue"}, das.DefaultPlacement()); err != nil {
+		t.Fatalf("unable to add a dinosaur %v", err)
+	}
+	if _, err := ah.park.AddSpecies(ctx, das.Species{Name: "dodo", Diet: das.HerbivoreCode}); err != nil {
+		t.Fatalf("unable to add a species %v", err)
+	}
+	known := func(name string) bool {
+		_, err := ah.park.Species(ctx, name)
+		return err == nil
+	}
+
+	// velociraptors are in the park so they cannot be removed
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"},{"name":"stegosaurus","diet":"H"}]`)
+	_, err := reloader.Reload(ctx)
+	if !errors.Is(err, service.ErrSpeciesInUse) {
+		t.Errorf("expected the reload to be refused got %v", err)
+	}
+	if known("stegosaurus") || !known("velociraptor") {
+		t.Errorf("a refused reload should leave the species unchanged")
+	}
+
+	// an invalid file is refused before any lookup
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"Q"}]`)
+	if _, err = reloader.Reload(ctx); err == nil {
+		t.Errorf("expected an invalid file to be refused")
+	}
+
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"},{"name":"velociraptor","diet":"C"},{"name":"stegosaurus","diet":"h"}]`)
+	diff, err := reloader.Reload(ctx)
+	if err != nil || len(diff.Added) != 1 || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+		t.Fatalf("expected stegosaurus to be added got %+v %v", diff, err)
+	}
+	if s, err := ah.park.Species(ctx, "stegosaurus"); err != nil || s.Diet != das.HerbivoreCode {
+		t.Errorf("expected the new species got %+v %v", s, err)
+	}
+	// species added through the api are not in the file and are kept
+	if !known("dodo") {
+		t.Errorf("expected the reload to keep a species added through the api")
+	}
+
+	// details other than the diet may change under existing dinosaurs
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"},{"name":"velociraptor","diet":"C","danger_level":4},{"name":"stegosaurus","diet":"h"}]`)
+	diff, err = reloader.Reload(ctx)
+	if err != nil || len(diff.Changed) != 1 {
+		t.Errorf("expected velociraptor to change got %+v %v", diff, err)
+	}
+	if s, _ := ah.park.Species(ctx, "velociraptor"); s.DangerLevel != 4 {
+		t.Errorf("expected the changed details to be stored got %+v", s)
+	}
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"},{"name":"velociraptor","diet":"H"},{"name":"stegosaurus","diet":"h"}]`)
+	if _, err = reloader.Reload(ctx); !errors.Is(err, service.ErrSpeciesInUse) {
+		t.Errorf("expected the diet change to be refused got %v", err)
+	}
+
+	// species without dinosaurs may go
+	writeSpeciesFile(t, path, `[{"name":"velociraptor","diet":"C","danger_level":4}]`)
+	diff, err = reloader.Reload(ctx)
+	if err != nil || len(diff.Removed) != 2 {
+		t.Errorf("expected two species to be removed got %+v %v", diff, err)
+	}
+	species, err := ah.park.ListSpecies(ctx)
+	if err != nil || len(species) != 2 || species[0].Name != "dodo" || species[1].Name != "velociraptor" {
+		t.Errorf("expected dodo and velociraptor to remain got %+v %v", species, err)
+	}
+}
+
+func TestSpeciesReloadOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "species.json")
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"}]`)
+	ah, speciesMap := sqliteHandlers(t, path)
+	reloader := NewSpeciesReloader(path, ah.park, speciesMap)
+	reloader.Interval = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reload := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		reloader.Run(ctx, reload)
+		close(done)
+	}()
+	writeSpeciesFile(t, path, `[{"name":"triceratops","diet":"H"},{"name":"stegosaurus","diet":"H"}]`)
+	reload <- os.Interrupt
+	// the send returns once the reload has started, a second send waits for it to finish
+	reload <- os.Interrupt
+	if _, err := ah.park.Species(ctx, "stegosaurus"); err != nil {
+		t.Errorf("expected the signal to reload the species got %v", err)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("reloader did not stop with its context")
+	}
+}
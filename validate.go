@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"dinocage/service"
+)
+
+const (
+	// largest json payload accepted by the api
+	MaxBodyBytes = 1 << 20
+	// largest import or restore payload
+	MaxImportBytes = 32 << 20
+)
+
+// the largest payload accepted for a request path
+func bodyLimit(path string) int64 {
+	if strings.HasSuffix(path, "/import") || strings.HasSuffix(path, "/restore") {
+		return MaxImportBytes
+	}
+	return MaxBodyBytes
+}
+
+// mux middleware limiting every request body so nothing later in the chain,
+// the api validator and idempotency store included, reads an unbounded payload
+func LimitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, bodyLimit(r.URL.Path))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// error response listing every field problem found in a payload
+type ValidationResponse struct {
+	Error  string               `json:"error"`
+	Fields []service.FieldError `json:"fields"`
+}
+
+// read a single json value from a request body into v
+// the body is limited to MaxBodyBytes and for a struct every unknown field
+// and every field of the wrong type is reported in a service.ValidationError
+// an empty body gives io.EOF so handlers with optional payloads can allow it
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return io.EOF
+	}
+	if err := checkFields(b, v); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(v)
+	if err != nil {
+		return fmt.Errorf("malformed payload %v : %w", err, service.ErrInvalid)
+	}
+	if dec.More() {
+		return fmt.Errorf("payload must be a single json value : %w", service.ErrInvalid)
+	}
+	return nil
+}
+
+// check each field of a json object against the struct v points to
+// fields are matched in any case as the decoder does
+func checkFields(b []byte, v any) error {
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(b, &fields) != nil {
+		// not an object, left to the decoder to report
+		return nil
+	}
+	known := jsonFields(t.Elem())
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var ve service.ValidationError
+	for _, name := range names {
+		ft, ok := known[strings.ToLower(name)]
+		if !ok {
+			ve.Add(name, "is not a known field")
+			continue
+		}
+		if json.Unmarshal(fields[name], reflect.New(ft).Interface()) != nil {
+			ve.Add(name, "must be %s", jsonKind(ft))
+		}
+	}
+	return ve.Err()
+}
+
+// the json names of the fields of a struct keyed in lower case
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	known := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		known[strings.ToLower(name)] = f.Type
+	}
+	return known
+}
+
+// describe the json value expected for a type
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// write an error response falling back to the given status
+// field problems are written as json so clients see every one at once
+func WriteError(w http.ResponseWriter, err error, def int) {
+	var ve *service.ValidationError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		WriteMsg(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("payload is larger than %d bytes", tooLarge.Limit))
+	case errors.Is(err, io.EOF):
+		WriteMsg(w, http.StatusBadRequest, "payload is required")
+	case errors.As(err, &ve):
+		b, _ := json.Marshal(ValidationResponse{Error: service.ErrInvalid.Error(), Fields: ve.Fields})
+		w.Header().Set("Content-Type", "application/json")
+		WriteMsg(w, StatusForError(err, def), string(b))
+	default:
+		WriteMsg(w, StatusForError(err, def), err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dinocage/das"
+	"dinocage/mocks"
+	"dinocage/service"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		body   string
+		fields []string
+	}{
+		{`{"species":"triceratops","name":"Cera"}`, nil},
+		{`{"Species":"triceratops","name":"Cera"}`, nil},
+		{`{"species":"triceratops","colour":"green","name":7,"size":3}`, []string{"colour", "name", "size"}},
+	}
+	for _, tc := range tests {
+		var d das.Dinosaur
+		r := httptest.NewRequest("POST", "/v1/dino/add", strings.NewReader(tc.body))
+		err := DecodeJSON(httptest.NewRecorder(), r, &d)
+		var ve *service.ValidationError
+		if len(tc.fields) == 0 {
+			if err != nil || d.Species != "triceratops" {
+				t.Errorf("%s decoded %+v %v", tc.body, d, err)
+			}
+			continue
+		}
+		if !errors.As(err, &ve) || len(ve.Fields) != len(tc.fields) {
+			t.Fatalf("%s expected problems with %v got %v", tc.body, tc.fields, err)
+		}
+		for i, f := range ve.Fields {
+			if f.Field != tc.fields[i] {
+				t.Errorf("%s expected a problem with %s got %+v", tc.body, tc.fields[i], f)
+			}
+		}
+	}
+
+	var d das.Dinosaur
+	r := httptest.NewRequest("POST", "/v1/dino/add", strings.NewReader(""))
+	if err := DecodeJSON(httptest.NewRecorder(), r, &d); err != io.EOF {
+		t.Errorf("expected an empty body to give EOF got %v", err)
+	}
+	r = httptest.NewRequest("POST", "/v1/dino/add", strings.NewReader(`{"name":"a"} {"name":"b"}`))
+	if err := DecodeJSON(httptest.NewRecorder(), r, &d); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("expected trailing data to be refused got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/v1/dino/add", strings.NewReader(`{"name":"`+strings.Repeat("x", MaxBodyBytes)+`"}`))
+	WriteError(w, DecodeJSON(w, r, &d), http.StatusBadRequest)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected a large body to give %d got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestAddSpeciesInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDap := mocks.NewMockDataAccessProvider(ctrl)
+	ah := NewAppHandlers(mockDap, das.DefaultPlacement())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/v1/species/add", strings.NewReader(`{"name":"<rex>","diet":"V","danger_level":9}`))
+	ah.AddSpecies(w, r)
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("TestAddSpeciesInvalid did not return %v but gave %v %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var resp ValidationResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Fields) != 3 {
+		t.Errorf("TestAddSpeciesInvalid expected three field problems got %s", w.Body.String())
+	}
+	// the mocked provider fails the test if the invalid species is saved
+}
+
+func TestLimitBody(t *testing.T) {
+	appHandlers := NewAppHandlers(mocks.NewMockDataAccessProvider(gomock.NewController(t)), das.DefaultPlacement())
+	appHandlers.idempotent = NewIdempotencyStore(time.Minute)
+	r, err := NewRouter(appHandlers)
+	if err != nil {
+		t.Fatalf("unable to create router %v", err)
+	}
+	big := `{"name":"` + strings.Repeat("a", MaxBodyBytes) + `","diet":"H"}`
+	req := httptest.NewRequest("POST", "/v1/species/add", strings.NewReader(big))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyHeader, "abc")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body gave %d %s", w.Code, w.Body.String())
+	}
+	if len(appHandlers.idempotent.entries) != 0 {
+		t.Errorf("oversized body was recorded against its idempotency key")
+	}
+
+	for path, limit := range map[string]int64{
+		"/v1/import":      MaxImportBytes,
+		"/v1/restore":     MaxImportBytes,
+		"/v1/species/add": MaxBodyBytes,
+		"/v2/dinosaurs":   MaxBodyBytes,
+	} {
+		if got := bodyLimit(path); got != limit {
+			t.Errorf("%s limited to %d expected %d", path, got, limit)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dinocage/das"
+)
+
+const (
+	SignatureHeader       = "X-Dinocage-Signature"
+	EventTypeHeader       = "X-Dinocage-Event"
+	DeliveryHeader        = "X-Dinocage-Delivery"
+	DefaultMaxAttempts    = 6
+	DefaultRetryBackoff   = time.Second
+	DefaultReloadInterval = time.Minute
+	DefaultClaimLease     = time.Minute
+	webhookQueueSize      = 1024
+)
+
+// sign a webhook payload with the subscription secret
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// a recorded delivery waiting to be sent and the webhook it goes to
+type queuedDelivery struct {
+	wh das.Webhook
+	d  das.Delivery
+}
+
+// asynchronous delivery of events to webhook subscriptions
+// every delivery is recorded as pending before its event is accepted so a
+// delivery is never lost, and pending deliveries are reloaded when the
+// dispatcher starts or once the queue has had to leave some behind
+// failed deliveries are retried with exponential backoff and marked dead
+// once the attempts are exhausted
+// each attempt first claims the delivery while it is still pending so a
+// delivery is not sent again once it has settled, whether queued twice or
+// reloaded by several instances, and a claim left by an instance that
+// stopped mid attempt lapses after ClaimLease
+type WebhookDispatcher struct {
+	store          das.WebhookStore
+	client         *http.Client
+	queue          chan queuedDelivery
+	MaxAttempts    int
+	Backoff        time.Duration
+	ReloadInterval time.Duration
+	ClaimLease     time.Duration
+	// set when a recorded delivery did not fit in the queue
+	behind   atomic.Bool
+	mu       sync.Mutex
+	inFlight map[int]bool
+	wg       sync.WaitGroup
+}
+
+func NewWebhookDispatcher(store das.WebhookStore) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:          store,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		queue:          make(chan queuedDelivery, webhookQueueSize),
+		MaxAttempts:    DefaultMaxAttempts,
+		Backoff:        DefaultRetryBackoff,
+		ReloadInterval: DefaultReloadInterval,
+		ClaimLease:     DefaultClaimLease,
+		inFlight:       make(map[int]bool),
+	}
+}
+
+// record a pending delivery to each subscribed webhook and queue it without
+// blocking the outbox, the event is only accepted once every delivery is
+// recorded so a failure returns an error and the event is delivered again
+func (wd *WebhookDispatcher) Deliver(ctx context.Context, e Event) error {
+	hooks, err := wd.store.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list webhooks for event %d : %w", e.ID, err)
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event %d : %w", e.ID, err)
+	}
+	for _, wh := range hooks {
+		if !wh.Wants(e.Type) {
+			continue
+		}
+		d := das.Delivery{Webhook: wh.ID, EventID: e.ID, EventType: e.Type, Payload: string(payload), Status: das.DeliveryPending}
+		d.ID, err = wd.store.AddDelivery(ctx, d)
+		if err != nil {
+			return fmt.Errorf("unable to record delivery of event %d to webhook %d : %w", e.ID, wh.ID, err)
+		}
+		select {
+		case wd.queue <- queuedDelivery{wh: wh, d: d}:
+		default:
+			wd.behind.Store(true)
+		}
+	}
+	return nil
+}
+
+// send pending deliveries left by an earlier run then queued deliveries
+// until the context is done, then wait for in flight deliveries to finish
+// a delivery cut short stays pending for the next run
+func (wd *WebhookDispatcher) Run(ctx context.Context) {
+	defer wd.wg.Wait()
+	wd.reload(ctx)
+	ticker := time.NewTicker(wd.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-wd.queue:
+			wd.start(ctx, q.wh, q.d)
+		case <-ticker.C:
+			if wd.behind.Swap(false) {
+				wd.reload(ctx)
+			}
+		}
+	}
+}
+
+// start every pending delivery oldest first
+func (wd *WebhookDispatcher) reload(ctx context.Context) {
+	pending, err := wd.store.ListDeliveries(ctx, 0, das.DeliveryPending)
+	if err != nil {
+		log.Printf("unable to list pending deliveries : %v", err)
+		wd.behind.Store(true)
+		return
+	}
+	hooks, err := wd.store.ListWebhooks(ctx)
+	if err != nil {
+		log.Printf("unable to list webhooks for pending deliveries : %v", err)
+		wd.behind.Store(true)
+		return
+	}
+	byID := make(map[int]das.Webhook, len(hooks))
+	for _, wh := range hooks {
+		byID[wh.ID] = wh
+	}
+	slices.SortFunc(pending, func(a, b das.Delivery) int {
+		return a.ID - b.ID
+	})
+	for _, d := range pending {
+		// deliveries are removed along with their webhook
+		if wh, ok := byID[d.Webhook]; ok {
+			wd.start(ctx, wh, d)
+		}
+	}
+}
+
+// attempt a delivery in the background unless it is already in flight
+func (wd *WebhookDispatcher) start(ctx context.Context, wh das.Webhook, d das.Delivery) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if wd.inFlight[d.ID] {
+		return
+	}
+	wd.inFlight[d.ID] = true
+	wd.wg.Add(1)
+	go func() {
+		defer wd.wg.Done()
+		wd.attempt(ctx, wh, d)
+		wd.mu.Lock()
+		delete(wd.inFlight, d.ID)
+		wd.mu.Unlock()
+	}()
+}
+
+// try a delivery until it succeeds, runs out of attempts or is taken by
+// another attempt, the stored delivery is claimed before every attempt
+func (wd *WebhookDispatcher) attempt(ctx context.Context, wh das.Webhook, d das.Delivery) {
+	delay := wd.Backoff
+	for {
+		claimed, ok, err := wd.store.ClaimDelivery(ctx, d.ID, wd.ClaimLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("unable to claim delivery %d : %v", d.ID, err)
+				wd.behind.Store(true)
+			}
+			return
+		}
+		if !ok {
+			return
+		}
+		d = claimed
+		d.Attempts++
+		d.ResponseCode, d.LastError = wd.send(ctx, wh, d)
+		switch {
+		case len(d.LastError) == 0:
+			d.Status = das.DeliveryDelivered
+		case d.Attempts >= wd.MaxAttempts:
+			d.Status = das.DeliveryDead
+		}
+		err = wd.store.UpdateDelivery(context.Background(), d)
+		if err != nil {
+			log.Printf("unable to update delivery %d : %v", d.ID, err)
+		}
+		if d.Status != das.DeliveryPending {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post a delivery returning the response code and an error message on failure
+func (wd *WebhookDispatcher) send(ctx context.Context, wh das.Webhook, d das.Delivery) (int, string) {
+	body := []byte(d.Payload)
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(EventTypeHeader, d.EventType)
+	r.Header.Set(DeliveryHeader, strconv.Itoa(d.ID))
+	r.Header.Set(SignatureHeader, SignPayload(wh.Secret, body))
+	resp, err := wd.client.Do(r)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("receiver returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, ""
+}
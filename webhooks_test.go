@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dinocage/das"
+)
+
+// in memory webhook store for dispatcher tests
+type memWebhookStore struct {
+	mu         sync.Mutex
+	hooks      []das.Webhook
+	deliveries map[int]das.Delivery
+	claimed    map[int]bool
+	addErr     error
+}
+
+func (ms *memWebhookStore) AddWebhook(ctx context.Context, wh das.Webhook) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	wh.ID = len(ms.hooks) + 1
+	ms.hooks = append(ms.hooks, wh)
+	return wh.ID, nil
+}
+
+func (ms *memWebhookStore) ListWebhooks(ctx context.Context) ([]das.Webhook, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return append([]das.Webhook(nil), ms.hooks...), nil
+}
+
+func (ms *memWebhookStore) DeleteWebhook(ctx context.Context, id int) error {
+	return nil
+}
+
+func (ms *memWebhookStore) AddDelivery(ctx context.Context, d das.Delivery) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.addErr != nil {
+		return 0, ms.addErr
+	}
+	if ms.deliveries == nil {
+		ms.deliveries = make(map[int]das.Delivery)
+	}
+	d.ID = len(ms.deliveries) + 1
+	ms.deliveries[d.ID] = d
+	return d.ID, nil
+}
+
+// claims never lapse so a delivery claimed and left is never retried
+func (ms *memWebhookStore) ClaimDelivery(ctx context.Context, id int, lease time.Duration) (das.Delivery, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	d, ok := ms.deliveries[id]
+	if !ok || d.Status != das.DeliveryPending || ms.claimed[id] {
+		return das.Delivery{}, false, nil
+	}
+	if ms.claimed == nil {
+		ms.claimed = make(map[int]bool)
+	}
+	ms.claimed[id] = true
+	return d, true, nil
+}
+
+func (ms *memWebhookStore) UpdateDelivery(ctx context.Context, d das.Delivery) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deliveries[d.ID] = d
+	delete(ms.claimed, d.ID)
+	return nil
+}
+
+func (ms *memWebhookStore) ListDeliveries(ctx context.Context, webhookID int, status string) ([]das.Delivery, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var out []das.Delivery
+	for _, d := range ms.deliveries {
+		if (webhookID == 0 || d.Webhook == webhookID) && (status == "" || d.Status == status) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// run a dispatcher delivering the events and wait for every delivery to settle
+func runDispatcher(t *testing.T, store *memWebhookStore, wd *WebhookDispatcher, events ...Event) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		wd.Run(ctx)
+		close(done)
+	}()
+	for _, e := range events {
+		if err := wd.Deliver(ctx, e); err != nil {
+			t.Errorf("deliver of event %d failed %v", e.ID, err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		pending, _ := store.ListDeliveries(ctx, 0, das.DeliveryPending)
+		all, _ := store.ListDeliveries(ctx, 0, "")
+		if len(all) != 0 && len(pending) == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestWebhookDeliveryRetry(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != SignPayload("s3cret", body) {
+			t.Errorf("bad signature %s", r.Header.Get(SignatureHeader))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := &memWebhookStore{}
+	store.AddWebhook(context.Background(), das.Webhook{URL: receiver.URL, Secret: "s3cret", Events: []string{das.EventCageStatusChanged}})
+	wd := NewWebhookDispatcher(store)
+	wd.Backoff = time.Millisecond
+
+	runDispatcher(t, store, wd, Event{ID: 1, Type: das.EventCageStatusChanged, Cage: 2})
+
+	delivered, _ := store.ListDeliveries(context.Background(), 1, das.DeliveryDelivered)
+	if len(delivered) != 1 || delivered[0].Attempts != 2 || delivered[0].ResponseCode != http.StatusNoContent {
+		t.Errorf("expected one delivery after 2 attempts got %+v", delivered)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := &memWebhookStore{}
+	store.AddWebhook(context.Background(), das.Webhook{URL: receiver.URL, Secret: "s3cret"})
+	store.AddWebhook(context.Background(), das.Webhook{URL: receiver.URL, Secret: "s3cret", Events: []string{das.EventSpeciesAdded}})
+	wd := NewWebhookDispatcher(store)
+	wd.Backoff = time.Millisecond
+	wd.MaxAttempts = 2
+
+	runDispatcher(t, store, wd, Event{ID: 1, Type: das.EventDinoAdded})
+
+	dead, _ := store.ListDeliveries(context.Background(), 0, das.DeliveryDead)
+	if len(dead) != 1 || dead[0].Webhook != 1 || dead[0].Attempts != 2 {
+		t.Errorf("expected one dead delivery to webhook 1 got %+v", dead)
+	}
+}
+
+func TestWebhookDeliverRecordsFirst(t *testing.T) {
+	ctx := context.Background()
+	store := &memWebhookStore{}
+	store.AddWebhook(ctx, das.Webhook{URL: "http://localhost", Secret: "s3cret"})
+	store.AddWebhook(ctx, das.Webhook{URL: "http://localhost", Secret: "s3cret", Events: []string{das.EventSpeciesAdded}})
+	wd := NewWebhookDispatcher(store)
+
+	// the delivery is stored as pending before the event is accepted
+	if err := wd.Deliver(ctx, Event{ID: 7, Type: das.EventCageFull, Cage: 2}); err != nil {
+		t.Fatalf("deliver failed %v", err)
+	}
+	pending, _ := store.ListDeliveries(ctx, 0, das.DeliveryPending)
+	if len(pending) != 1 || pending[0].Webhook != 1 || pending[0].EventID != 7 {
+		t.Errorf("expected one pending delivery to webhook 1 got %+v", pending)
+	}
+
+	// the event is refused when a delivery cannot be recorded
+	store.addErr = errors.New("disk full")
+	if err := wd.Deliver(ctx, Event{ID: 8, Type: das.EventCageFull, Cage: 2}); err == nil {
+		t.Errorf("expected the event to be refused")
+	}
+}
+
+func TestWebhookRunReloadsPending(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// a delivery left pending by a run that stopped after its first attempt
+	ctx := context.Background()
+	store := &memWebhookStore{}
+	store.AddWebhook(ctx, das.Webhook{URL: receiver.URL, Secret: "s3cret"})
+	id, _ := store.AddDelivery(ctx, das.Delivery{Webhook: 1, EventID: 3, EventType: das.EventDinoAdded, Payload: "{}", Status: das.DeliveryPending})
+	store.UpdateDelivery(ctx, das.Delivery{ID: id, Webhook: 1, EventID: 3, EventType: das.EventDinoAdded, Payload: "{}", Status: das.DeliveryPending, Attempts: 1})
+	wd := NewWebhookDispatcher(store)
+
+	runDispatcher(t, store, wd)
+
+	delivered, _ := store.ListDeliveries(ctx, 1, das.DeliveryDelivered)
+	if len(delivered) != 1 || delivered[0].EventID != 3 || delivered[0].Attempts != 2 {
+		t.Errorf("expected the pending delivery sent on start got %+v", delivered)
+	}
+}
+
+func TestWebhookDeliverySentOnce(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// two instances sharing a database both reload the same pending delivery
+	ctx, cancel := context.WithCancel(context.Background())
+	store := sqliteProvider(t)
+	hookID, _ := store.AddWebhook(ctx, das.Webhook{URL: receiver.URL, Secret: "s3cret"})
+	pending := das.Delivery{Webhook: hookID, EventID: 3, EventType: das.EventDinoAdded, Payload: "{}", Status: das.DeliveryPending}
+	pending.ID, _ = store.AddDelivery(ctx, pending)
+	dispatchers := []*WebhookDispatcher{NewWebhookDispatcher(store), NewWebhookDispatcher(store)}
+	var wg sync.WaitGroup
+	for _, wd := range dispatchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wd.Run(ctx)
+		}()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if delivered, _ := store.ListDeliveries(ctx, hookID, das.DeliveryDelivered); len(delivered) != 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// a copy still queued once the delivery has settled is not sent again
+	hooks, _ := store.ListWebhooks(ctx)
+	dispatchers[0].attempt(ctx, hooks[0], pending)
+	cancel()
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the delivery sent once got %d", n)
+	}
+	delivered, _ := store.ListDeliveries(context.Background(), hookID, das.DeliveryDelivered)
+	if len(delivered) != 1 || delivered[0].Attempts != 1 {
+		t.Errorf("expected one delivery after one attempt got %+v", delivered)
+	}
+}